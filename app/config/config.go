@@ -0,0 +1,480 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/PatricioYegros/uala_challenge/app/models"
+	"github.com/PatricioYegros/uala_challenge/app/repository"
+	"github.com/PatricioYegros/uala_challenge/app/service"
+)
+
+// Config is the configuration of the server. It's loaded from defaults, a JSON file, env variables and flags,
+// each one overriding the previous.
+type Config struct {
+	Server    Server    `json:"server"`
+	Storage   Storage   `json:"storage"`
+	Limits    Limits    `json:"limits"`
+	Timelines Timelines `json:"timelines"`
+	Trends    Trends    `json:"trends"`
+}
+
+type Server struct {
+	// Address is the address the http api listens on
+	Address string `json:"address"`
+	// Role is RoleAPI to serve the http api, RoleWorker to run the fan-out workers or RoleAll for both
+	Role string `json:"role"`
+	// AdminToken enables the admin endpoints for the requests sending it. Secret.
+	AdminToken string `json:"adminToken"`
+	// LogPasswordResets writes the password reset tokens to the log, for development without a mail service.
+	// Without it, only the request is logged.
+	LogPasswordResets bool `json:"logPasswordResets"`
+}
+
+type Storage struct {
+	// Backend is StorageBackendRedis, StorageBackendMemory or StorageBackendSQL
+	Backend  string `json:"backend"`
+	CacheURL string `json:"cacheUrl"`
+	// CachePassword is the password of Redis. Secret.
+	CachePassword string `json:"cachePassword"`
+	SQLDriver     string `json:"sqlDriver"`
+	// SQLDSN is the data source name of the sql database, it may have credentials. Secret.
+	SQLDSN string `json:"sqlDsn"`
+}
+
+type Limits struct {
+	TweetTTL         Duration `json:"tweetTtl" swaggertype:"string" example:"24h0m0s"`
+	SessionTTL       Duration `json:"sessionTtl" swaggertype:"string" example:"24h0m0s"`
+	TimelineSize     int      `json:"timelineSize"`
+	TimelinePageSize int      `json:"timelinePageSize"`
+	MaxTweetLength   int      `json:"maxTweetLength"`
+	EditWindow       Duration `json:"editWindow" swaggertype:"string" example:"30m0s"`
+	// HandleGracePeriod is how long the previous handle of a user redirects to it
+	HandleGracePeriod Duration `json:"handleGracePeriod" swaggertype:"string" example:"720h0m0s"`
+}
+
+type Timelines struct {
+	PruneTimelines  bool  `json:"pruneTimelines"`
+	BackfillTweets  int   `json:"backfillTweets"`
+	FanOutThreshold int64 `json:"fanOutThreshold"`
+	FanOutWorkers   int   `json:"fanOutWorkers"`
+}
+
+type Trends struct {
+	// Window is how far back the hashtags of tweets count for trends
+	Window Duration `json:"window" swaggertype:"string" example:"24h0m0s"`
+	// HalfLife is how long it takes for a tweet to count half for trends
+	HalfLife Duration `json:"halfLife" swaggertype:"string" example:"2h0m0s"`
+}
+
+const (
+	ConfigFileEnvVar        = "CONFIG_FILE"
+	ListenAddressEnvVar     = "LISTEN_ADDRESS"
+	RoleEnvVar              = "ROLE"
+	AdminTokenEnvVar        = "ADMIN_TOKEN"
+	LogPasswordResetsEnvVar = "LOG_PASSWORD_RESETS"
+	CacheURLEnvVar          = "CACHE_URL"
+	CachePasswordEnvVar     = "CACHE_PASSWORD"
+	StorageBackendEnvVar    = "STORAGE_BACKEND"
+	SQLDriverEnvVar         = "SQL_DRIVER"
+	SQLDSNEnvVar            = "SQL_DSN"
+	TweetTTLEnvVar          = "TWEET_TTL"
+	SessionTTLEnvVar        = "SESSION_TTL"
+	TimelineSizeEnvVar      = "TIMELINE_SIZE"
+	TimelinePageSizeEnvVar  = "TIMELINE_PAGE_SIZE"
+	MaxTweetLengthEnvVar    = "MAX_TWEET_LENGTH"
+	EditWindowEnvVar        = "EDIT_WINDOW"
+	HandleGracePeriodEnvVar = "HANDLE_GRACE_PERIOD"
+	PruneTimelinesEnvVar    = "PRUNE_TIMELINES"
+	BackfillTweetsEnvVar    = "BACKFILL_TWEETS"
+	FanOutThresholdEnvVar   = "FANOUT_THRESHOLD"
+	FanOutWorkersEnvVar     = "FANOUT_WORKERS"
+	TrendsWindowEnvVar      = "TRENDS_WINDOW"
+	TrendsHalfLifeEnvVar    = "TRENDS_HALF_LIFE"
+)
+
+const (
+	RoleAll    = "all"
+	RoleAPI    = "api"
+	RoleWorker = "worker"
+)
+
+const (
+	StorageBackendRedis  = "redis"
+	StorageBackendMemory = "memory"
+	StorageBackendSQL    = "sql"
+)
+
+// redacted replaces the secrets of a redacted config
+const redacted = "[REDACTED]"
+
+var (
+	ErrInvalidConfig         = errors.New("invalid config")
+	ErrUnknownStorageBackend = errors.New("unknown storage backend")
+	ErrCacheNotConfigured    = errors.New("cache not configured")
+	ErrSQLNotConfigured      = errors.New("sql not configured")
+)
+
+// Default returns the config used when nothing is set
+func Default() Config {
+	return Config{
+		Server: Server{
+			Address: ":8080",
+			Role:    RoleAll,
+		},
+		Storage: Storage{
+			Backend: StorageBackendRedis,
+		},
+		Limits: Limits{
+			TweetTTL:          Duration(repository.DefaultTweetTTL),
+			SessionTTL:        Duration(repository.DefaultSessionTTL),
+			TimelineSize:      repository.DefaultTimelineSize,
+			TimelinePageSize:  service.DefaultTimelinePageSize,
+			MaxTweetLength:    models.MaxLength,
+			EditWindow:        Duration(service.DefaultEditWindow),
+			HandleGracePeriod: Duration(service.DefaultHandleGracePeriod),
+		},
+		Timelines: Timelines{
+			FanOutWorkers: 4,
+		},
+		Trends: Trends{
+			Window:   Duration(service.DefaultTrendsWindow),
+			HalfLife: Duration(service.DefaultTrendsHalfLife),
+		},
+	}
+}
+
+// Load returns the validated config of the command line args, starting from Default and overriding it with
+// the JSON file of --config or CONFIG_FILE, then the env variables found by lookupEnv and then the flags in args.
+// Secrets can't be set with flags.
+func Load(args []string, lookupEnv func(key string) (string, bool)) (Config, error) {
+	//a first parse finds the config file and checks the flags before using them
+	var configFile string
+	parsed := Default()
+
+	err := flags(&parsed, &configFile, os.Stderr).Parse(args)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if configFile == "" {
+		configFile, _ = lookupEnv(ConfigFileEnvVar)
+	}
+
+	config := Default()
+
+	if configFile != "" {
+		err = config.loadFile(configFile)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	err = config.loadEnv(lookupEnv)
+	if err != nil {
+		return Config{}, err
+	}
+
+	//only the flags set in args override the config
+	err = flags(&config, &configFile, io.Discard).Parse(args)
+	if err != nil {
+		return Config{}, err
+	}
+
+	err = config.Validate()
+	if err != nil {
+		return Config{}, err
+	}
+
+	return config, nil
+}
+
+// flags binds the flags to the fields of config, keeping their values as defaults
+func flags(config *Config, configFile *string, output io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet("uala_challenge", flag.ContinueOnError)
+	flags.SetOutput(output)
+
+	flags.StringVar(configFile, "config", "", "JSON config file, or "+ConfigFileEnvVar)
+	flags.StringVar(&config.Server.Address, "address", config.Server.Address, "address of the http api, or "+ListenAddressEnvVar)
+	flags.StringVar(&config.Server.Role, "role", config.Server.Role,
+		"api serves the http api, worker runs the fan-out workers and all does both, or "+RoleEnvVar)
+	flags.BoolVar(&config.Server.LogPasswordResets, "log-password-resets", config.Server.LogPasswordResets,
+		"write password reset tokens to the log, only for development, or "+LogPasswordResetsEnvVar)
+	flags.StringVar(&config.Storage.Backend, "storage", config.Storage.Backend, "redis, memory or sql, or "+StorageBackendEnvVar)
+	flags.StringVar(&config.Storage.CacheURL, "cache-url", config.Storage.CacheURL, "address of Redis, or "+CacheURLEnvVar)
+	flags.StringVar(&config.Storage.SQLDriver, "sql-driver", config.Storage.SQLDriver, "sqlite3 or postgres, or "+SQLDriverEnvVar)
+	flags.Var(&config.Limits.TweetTTL, "tweet-ttl", "how long tweets are kept in Redis and memory, or "+TweetTTLEnvVar)
+	flags.Var(&config.Limits.SessionTTL, "session-ttl", "how long sessions last since their last use, or "+SessionTTLEnvVar)
+	flags.IntVar(&config.Limits.TimelineSize, "timeline-size", config.Limits.TimelineSize,
+		"amount of tweets kept in every timeline, or "+TimelineSizeEnvVar)
+	flags.IntVar(&config.Limits.TimelinePageSize, "timeline-page-size", config.Limits.TimelinePageSize,
+		"default amount of tweets in a timeline page, or "+TimelinePageSizeEnvVar)
+	flags.IntVar(&config.Limits.MaxTweetLength, "max-tweet-length", config.Limits.MaxTweetLength,
+		"max length of the content of a tweet, or "+MaxTweetLengthEnvVar)
+	flags.Var(&config.Limits.EditWindow, "edit-window", "how long after being created a tweet can be edited, or "+EditWindowEnvVar)
+	flags.Var(&config.Limits.HandleGracePeriod, "handle-grace-period",
+		"how long a previous handle redirects to its user, or "+HandleGracePeriodEnvVar)
+	flags.BoolVar(&config.Timelines.PruneTimelines, "prune-timelines", config.Timelines.PruneTimelines,
+		"remove expired tweets from the timelines read, or "+PruneTimelinesEnvVar)
+	flags.IntVar(&config.Timelines.BackfillTweets, "backfill-tweets", config.Timelines.BackfillTweets,
+		"latest tweets of a followed user added to the timeline, or "+BackfillTweetsEnvVar)
+	flags.Int64Var(&config.Timelines.FanOutThreshold, "fanout-threshold", config.Timelines.FanOutThreshold,
+		"followers above which tweets are merged on read, or "+FanOutThresholdEnvVar)
+	flags.IntVar(&config.Timelines.FanOutWorkers, "fanout-workers", config.Timelines.FanOutWorkers,
+		"fan-out workers per process, or "+FanOutWorkersEnvVar)
+	flags.Var(&config.Trends.Window, "trends-window", "how far back hashtags count for trends, or "+TrendsWindowEnvVar)
+	flags.Var(&config.Trends.HalfLife, "trends-half-life", "how long it takes for a tweet to count half for trends, or "+TrendsHalfLifeEnvVar)
+
+	return flags
+}
+
+// loadFile overrides config with the fields set in the JSON file at path
+func (config *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+
+	err = decoder.Decode(config)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+// loadEnv overrides config with the env variables that are set
+func (config *Config) loadEnv(lookupEnv func(key string) (string, bool)) error {
+	stringFields := map[string]*string{
+		ListenAddressEnvVar:  &config.Server.Address,
+		RoleEnvVar:           &config.Server.Role,
+		AdminTokenEnvVar:     &config.Server.AdminToken,
+		StorageBackendEnvVar: &config.Storage.Backend,
+		CacheURLEnvVar:       &config.Storage.CacheURL,
+		CachePasswordEnvVar:  &config.Storage.CachePassword,
+		SQLDriverEnvVar:      &config.Storage.SQLDriver,
+		SQLDSNEnvVar:         &config.Storage.SQLDSN,
+	}
+
+	for key, field := range stringFields {
+		if value, found := lookupEnv(key); found && value != "" {
+			*field = value
+		}
+	}
+
+	parsedFields := map[string]flag.Value{
+		LogPasswordResetsEnvVar: (*boolValue)(&config.Server.LogPasswordResets),
+		TweetTTLEnvVar:          &config.Limits.TweetTTL,
+		SessionTTLEnvVar:        &config.Limits.SessionTTL,
+		TimelineSizeEnvVar:      (*intValue)(&config.Limits.TimelineSize),
+		TimelinePageSizeEnvVar:  (*intValue)(&config.Limits.TimelinePageSize),
+		MaxTweetLengthEnvVar:    (*intValue)(&config.Limits.MaxTweetLength),
+		EditWindowEnvVar:        &config.Limits.EditWindow,
+		HandleGracePeriodEnvVar: &config.Limits.HandleGracePeriod,
+		PruneTimelinesEnvVar:    (*boolValue)(&config.Timelines.PruneTimelines),
+		BackfillTweetsEnvVar:    (*intValue)(&config.Timelines.BackfillTweets),
+		FanOutThresholdEnvVar:   (*int64Value)(&config.Timelines.FanOutThreshold),
+		FanOutWorkersEnvVar:     (*intValue)(&config.Timelines.FanOutWorkers),
+		TrendsWindowEnvVar:      &config.Trends.Window,
+		TrendsHalfLifeEnvVar:    &config.Trends.HalfLife,
+	}
+
+	var errs []error
+	for key, field := range parsedFields {
+		if value, found := lookupEnv(key); found && value != "" {
+			err := field.Set(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate returns every reason why config can't be used, wrapping ErrInvalidConfig
+func (config Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: "+format, append([]any{ErrInvalidConfig}, args...)...))
+	}
+
+	if config.Server.Address == "" {
+		invalid("address can't be empty")
+	}
+
+	if !slices.Contains([]string{RoleAll, RoleAPI, RoleWorker}, config.Server.Role) {
+		invalid("unknown role %s", config.Server.Role)
+	}
+
+	switch config.Storage.Backend {
+	case StorageBackendRedis:
+		if config.Storage.CacheURL == "" || config.Storage.CachePassword == "" {
+			invalid("%w, set %s and %s", ErrCacheNotConfigured, CacheURLEnvVar, CachePasswordEnvVar)
+		}
+	case StorageBackendMemory:
+	case StorageBackendSQL:
+		if config.Storage.SQLDriver == "" || config.Storage.SQLDSN == "" {
+			invalid("%w, set %s and %s", ErrSQLNotConfigured, SQLDriverEnvVar, SQLDSNEnvVar)
+		}
+
+		if config.Storage.CacheURL != "" && config.Storage.CachePassword == "" {
+			invalid("%w, set %s", ErrCacheNotConfigured, CachePasswordEnvVar)
+		}
+	default:
+		invalid("%w %s", ErrUnknownStorageBackend, config.Storage.Backend)
+	}
+
+	if config.Limits.TweetTTL <= 0 {
+		invalid("tweet ttl must be positive")
+	}
+
+	if config.Limits.SessionTTL <= 0 {
+		invalid("session ttl must be positive")
+	}
+
+	if config.Limits.TimelineSize < 1 {
+		invalid("timeline size must be at least 1")
+	}
+
+	if config.Limits.TimelinePageSize < 1 || config.Limits.TimelinePageSize > service.MaxPageSize {
+		invalid("timeline page size must be between 1 and %d", service.MaxPageSize)
+	}
+
+	if config.Limits.MaxTweetLength < 1 {
+		invalid("max tweet length must be at least 1")
+	}
+
+	if config.Limits.EditWindow <= 0 {
+		invalid("edit window must be positive")
+	}
+
+	if config.Limits.HandleGracePeriod <= 0 {
+		invalid("handle grace period must be positive")
+	}
+
+	if config.Timelines.BackfillTweets < 0 || config.Timelines.BackfillTweets > repository.MaxUserTweets {
+		invalid("backfill tweets must be between 0 and %d", repository.MaxUserTweets)
+	}
+
+	if config.Timelines.FanOutThreshold < 0 {
+		invalid("fan-out threshold can't be negative")
+	}
+
+	if config.Timelines.FanOutWorkers < 1 {
+		invalid("fan-out workers must be at least 1")
+	}
+
+	if config.Trends.Window <= 0 {
+		invalid("trends window must be positive")
+	}
+
+	if config.Trends.HalfLife <= 0 {
+		invalid("trends half-life must be positive")
+	}
+
+	return errors.Join(errs...)
+}
+
+// Redacted returns a copy of config with the secrets that are set replaced
+func (config Config) Redacted() Config {
+	for _, secret := range []*string{
+		&config.Server.AdminToken,
+		&config.Storage.CachePassword,
+		&config.Storage.SQLDSN,
+	} {
+		if *secret != "" {
+			*secret = redacted
+		}
+	}
+
+	return config
+}
+
+// Duration is a time.Duration written like "24h" in JSON and flags
+type Duration time.Duration
+
+func (duration Duration) String() string {
+	return time.Duration(duration).String()
+}
+
+// Set implements flag.Value
+func (duration *Duration) Set(value string) error {
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	*duration = Duration(parsed)
+	return nil
+}
+
+func (duration Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(duration.String())
+}
+
+func (duration *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+
+	return duration.Set(value)
+}
+
+// intValue, int64Value and boolValue parse env variables like flags do
+
+type intValue int
+
+func (value *intValue) String() string { return strconv.Itoa(int(*value)) }
+
+func (value *intValue) Set(s string) error {
+	parsed, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+
+	*value = intValue(parsed)
+	return nil
+}
+
+type int64Value int64
+
+func (value *int64Value) String() string { return strconv.FormatInt(int64(*value), 10) }
+
+func (value *int64Value) Set(s string) error {
+	parsed, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	*value = int64Value(parsed)
+	return nil
+}
+
+type boolValue bool
+
+func (value *boolValue) String() string { return strconv.FormatBool(bool(*value)) }
+
+func (value *boolValue) Set(s string) error {
+	parsed, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+
+	*value = boolValue(parsed)
+	return nil
+}
@@ -0,0 +1,152 @@
+package config_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PatricioYegros/uala_challenge/app/config"
+	"github.com/go-playground/assert/v2"
+)
+
+// env returns a lookupEnv of the variables in values
+func env(values map[string]string) func(key string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, found := values[key]
+		return value, found
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.Equal(t, os.WriteFile(path, []byte(content), 0o600), nil)
+
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := config.Load(nil, env(map[string]string{config.StorageBackendEnvVar: config.StorageBackendMemory}))
+	assert.Equal(t, err, nil)
+
+	expected := config.Default()
+	expected.Storage.Backend = config.StorageBackendMemory
+	assert.Equal(t, cfg, expected)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, `{
+		"server": {"address": ":9090", "role": "api"},
+		"storage": {"backend": "memory"},
+		"limits": {"tweetTtl": "1h", "timelineSize": 50, "maxTweetLength": 280}
+	}`)
+
+	cfg, err := config.Load(
+		[]string{"--config", path, "--timeline-size", "70", "--role", "worker", "--trends-half-life", "30m"},
+		env(map[string]string{
+			config.ListenAddressEnvVar:     ":7070",
+			config.TimelineSizeEnvVar:      "60",
+			config.SessionTTLEnvVar:        "2h",
+			config.BackfillTweetsEnvVar:    "5",
+			config.TrendsWindowEnvVar:      "12h",
+			config.HandleGracePeriodEnvVar: "168h",
+			config.LogPasswordResetsEnvVar: "true",
+		}),
+	)
+	assert.Equal(t, err, nil)
+
+	//file over defaults
+	assert.Equal(t, cfg.Storage.Backend, config.StorageBackendMemory)
+	assert.Equal(t, cfg.Limits.TweetTTL, config.Duration(time.Hour))
+	assert.Equal(t, cfg.Limits.MaxTweetLength, 280)
+	//env over file
+	assert.Equal(t, cfg.Server.Address, ":7070")
+	assert.Equal(t, cfg.Limits.SessionTTL, config.Duration(2*time.Hour))
+	assert.Equal(t, cfg.Timelines.BackfillTweets, 5)
+	assert.Equal(t, cfg.Trends.Window, config.Duration(12*time.Hour))
+	assert.Equal(t, cfg.Limits.HandleGracePeriod, config.Duration(7*24*time.Hour))
+	assert.Equal(t, cfg.Server.LogPasswordResets, true)
+	//flags over env
+	assert.Equal(t, cfg.Limits.TimelineSize, 70)
+	assert.Equal(t, cfg.Server.Role, config.RoleWorker)
+	assert.Equal(t, cfg.Trends.HalfLife, config.Duration(30*time.Minute))
+	//untouched
+	assert.Equal(t, cfg.Limits.TimelinePageSize, config.Default().Limits.TimelinePageSize)
+}
+
+func TestLoadConfigFileFromEnv(t *testing.T) {
+	path := writeFile(t, `{"storage": {"backend": "memory"}, "server": {"address": ":9090"}}`)
+
+	cfg, err := config.Load(nil, env(map[string]string{config.ConfigFileEnvVar: path}))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, cfg.Server.Address, ":9090")
+}
+
+func TestLoadUnknownFileField(t *testing.T) {
+	path := writeFile(t, `{"storage": {"backend": "memory"}, "limits": {"timelineSizes": 10}}`)
+
+	_, err := config.Load([]string{"--config", path}, env(nil))
+	assert.NotEqual(t, err, nil)
+}
+
+func TestLoadInvalidEnv(t *testing.T) {
+	_, err := config.Load(nil, env(map[string]string{
+		config.StorageBackendEnvVar: config.StorageBackendMemory,
+		config.TimelineSizeEnvVar:   "many",
+	}))
+	assert.NotEqual(t, err, nil)
+}
+
+func TestLoadUnknownFlag(t *testing.T) {
+	//secrets can't be set with flags
+	_, err := config.Load([]string{"--admin-token", "secret"}, env(map[string]string{
+		config.StorageBackendEnvVar: config.StorageBackendMemory,
+	}))
+	assert.NotEqual(t, err, nil)
+}
+
+func TestValidate(t *testing.T) {
+	cfg := config.Default()
+	assert.Equal(t, errors.Is(cfg.Validate(), config.ErrCacheNotConfigured), true)
+
+	cfg.Storage.Backend = config.StorageBackendSQL
+	assert.Equal(t, errors.Is(cfg.Validate(), config.ErrSQLNotConfigured), true)
+
+	cfg.Storage.Backend = "mongo"
+	assert.Equal(t, errors.Is(cfg.Validate(), config.ErrUnknownStorageBackend), true)
+
+	cfg.Storage.Backend = config.StorageBackendMemory
+	assert.Equal(t, cfg.Validate(), nil)
+
+	cfg.Server.Role = "scheduler"
+	cfg.Limits.TimelineSize = 0
+	cfg.Limits.TimelinePageSize = 1000
+	cfg.Limits.SessionTTL = 0
+	cfg.Timelines.BackfillTweets = -1
+	cfg.Trends.HalfLife = 0
+	cfg.Limits.HandleGracePeriod = 0
+
+	err := cfg.Validate()
+	assert.Equal(t, errors.Is(err, config.ErrInvalidConfig), true)
+	assert.Equal(t, len(err.(interface{ Unwrap() []error }).Unwrap()), 7)
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := config.Default()
+	cfg.Server.AdminToken = "admin"
+	cfg.Storage.CachePassword = "password"
+
+	redacted := cfg.Redacted()
+	assert.Equal(t, redacted.Server.AdminToken, "[REDACTED]")
+	assert.Equal(t, redacted.Storage.CachePassword, "[REDACTED]")
+	//unset secrets stay empty
+	assert.Equal(t, redacted.Storage.SQLDSN, "")
+	//the config itself keeps them
+	assert.Equal(t, cfg.Server.AdminToken, "admin")
+
+	data, err := json.Marshal(redacted)
+	assert.Equal(t, err, nil)
+	assert.NotMatchRegex(t, string(data), "admin\"|password\"")
+}
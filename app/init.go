@@ -2,48 +2,154 @@ package app
 
 import (
 	"context"
-	"errors"
+	"database/sql"
+	"fmt"
 	"os"
+	"time"
 
+	"github.com/PatricioYegros/uala_challenge/app/config"
+	"github.com/PatricioYegros/uala_challenge/app/queue"
 	"github.com/PatricioYegros/uala_challenge/app/repository"
 	"github.com/PatricioYegros/uala_challenge/app/service"
 	"github.com/PatricioYegros/uala_challenge/app/utils"
 
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 	"github.com/redis/go-redis/v9"
 )
 
-const (
-	CacheURLEnvVar      = "CACHE_URL"
-	CachePasswordEnvVar = "CACHE_PASSWORD"
-)
+// NewService builds the TwitterService over the storage backend selected by the config.
+// The sql backend uses Redis as a tweet cache only if the cache URL is set.
+// The returned redis client is nil when the backend doesn't use Redis.
+func NewService(cfg config.Config) (*service.TwitterService, *redis.Client, error) {
+	clock := utils.Clock{}
 
-var ErrCacheNotConfigured = errors.New("cache env variables not configured")
+	repo, redis, err := newRepository(cfg, clock)
+	if err != nil {
+		return nil, nil, err
+	}
 
-func NewService() (*service.TwitterService, *redis.Client, error) {
-	cacheURL := os.Getenv(CacheURLEnvVar)
-	cachePassword := os.Getenv(CachePasswordEnvVar)
+	//return service
+	return &service.TwitterService{
+		Repository:        repo,
+		Clock:             clock,
+		PruneTimelines:    cfg.Timelines.PruneTimelines,
+		BackfillTweets:    cfg.Timelines.BackfillTweets,
+		FanOutThreshold:   cfg.Timelines.FanOutThreshold,
+		TimelinePageSize:  cfg.Limits.TimelinePageSize,
+		MaxTweetLength:    cfg.Limits.MaxTweetLength,
+		EditWindow:        time.Duration(cfg.Limits.EditWindow),
+		Notifier:          service.InboxNotifier{Repository: repo},
+		TrendsWindow:      time.Duration(cfg.Trends.Window),
+		TrendsHalfLife:    time.Duration(cfg.Trends.HalfLife),
+		HandleGracePeriod: time.Duration(cfg.Limits.HandleGracePeriod),
+	}, redis, nil
+}
 
-	if cacheURL == "" || cachePassword == "" {
-		return nil, nil, ErrCacheNotConfigured
+// NewFanOutQueue builds the Redis Stream queue of fan-out jobs, with the configured workers per process
+func NewFanOutQueue(cfg config.Config, redis *redis.Client) (queue.StreamQueue, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return queue.StreamQueue{}, err
 	}
 
+	fanOutQueue := queue.NewStreamQueue(redis, fmt.Sprintf("%s-%d", hostname, os.Getpid()))
+	fanOutQueue.Workers = cfg.Timelines.FanOutWorkers
+
+	return fanOutQueue, nil
+}
+
+func newRepository(cfg config.Config, clock utils.IClock) (repository.IRepository, *redis.Client, error) {
+	limits := repository.Limits{
+		TweetTTL:     time.Duration(cfg.Limits.TweetTTL),
+		SessionTTL:   time.Duration(cfg.Limits.SessionTTL),
+		TimelineSize: cfg.Limits.TimelineSize,
+	}
+
+	switch cfg.Storage.Backend {
+	case config.StorageBackendRedis:
+		redis, err := newRedisClient(cfg.Storage)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return repository.Repository{
+			Redis:  redis,
+			Limits: limits,
+		}, redis, nil
+	case config.StorageBackendMemory:
+		repo := repository.NewMemoryRepository(clock)
+		repo.Limits = limits
+
+		return repo, nil, nil
+	case config.StorageBackendSQL:
+		db, err := newSQLDatabase(cfg.Storage)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var repo repository.IRepository = repository.SQLRepository{
+			DB:     db,
+			Clock:  clock,
+			Limits: limits,
+		}
+
+		if cfg.Storage.CacheURL == "" {
+			return repo, nil, nil
+		}
+
+		redis, err := newRedisClient(cfg.Storage)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return repository.CachedRepository{
+			IRepository: repo,
+			Redis:       redis,
+			Limits:      limits,
+		}, redis, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: %s", config.ErrUnknownStorageBackend, cfg.Storage.Backend)
+	}
+}
+
+func newRedisClient(storage config.Storage) (*redis.Client, error) {
 	//create redis client
 	redis := redis.NewClient(&redis.Options{
-		Addr:     cacheURL,
-		Password: cachePassword,
+		Addr:     storage.CacheURL,
+		Password: storage.CachePassword,
 	})
 
 	//test connection
 	err := redis.Ping(context.Background()).Err()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	//return service
-	return &service.TwitterService{
-		Repository: repository.Repository{
-			Redis: redis,
-		},
-		Clock: utils.Clock{},
-	}, redis, nil
+	return redis, nil
+}
+
+func newSQLDatabase(storage config.Storage) (*sql.DB, error) {
+	db, err := sql.Open(storage.SQLDriver, storage.SQLDSN)
+	if err != nil {
+		return nil, err
+	}
+
+	if storage.SQLDriver == repository.SQLDriverSQLite {
+		//sqlite allows a single writer at a time
+		db.SetMaxOpenConns(1)
+	}
+
+	//test connection
+	err = db.Ping()
+	if err != nil {
+		return nil, err
+	}
+
+	err = repository.Migrate(db, storage.SQLDriver)
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
 }
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// NotificationReply notifies the author of a tweet of a reply to it
+	NotificationReply = "reply"
+	// NotificationRetweet notifies the author of a tweet of a retweet of it
+	NotificationRetweet = "retweet"
+	// NotificationQuote notifies the author of a tweet of a quote of it
+	NotificationQuote = "quote"
+	// NotificationLike notifies the author of a tweet of a like to it
+	NotificationLike = "like"
+	// NotificationMention notifies a user mentioned in a tweet
+	NotificationMention = "mention"
+)
+
+// Notification tells UserID that ActorID did something of Type with TweetID
+type Notification struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	UserID    uint      `json:"userId"`
+	ActorID   uint      `json:"actorId"`
+	TweetID   uuid.UUID `json:"tweetId"`
+	Timestamp time.Time `json:"timestamp"`
+	// Read tells if UserID already read the notification, filled when it's read from the inbox
+	Read bool `json:"read"`
+}
+
+// Implement encoding.BinaryMarshaler for Redis
+func (notification Notification) MarshalBinary() (data []byte, err error) {
+	return json.Marshal(notification)
+}
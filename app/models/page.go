@@ -0,0 +1,25 @@
+package models
+
+// UsersPage is a page of a list of users, like the followers of a user.
+// NextCursor is empty on the last page. Count is the length of the whole list.
+type UsersPage struct {
+	UserIDs    []uint `json:"userIds"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	Count      int64  `json:"count"`
+}
+
+// TimelinePage is a page of a timeline, newest first.
+// NextCursor continues with older tweets and is empty on the last page. PreviousCursor returns the tweets newer than the page.
+type TimelinePage struct {
+	Tweets         []Tweet `json:"tweets"`
+	NextCursor     string  `json:"nextCursor,omitempty"`
+	PreviousCursor string  `json:"previousCursor,omitempty"`
+}
+
+// NotificationsPage is a page of the notifications inbox of a user, newest first.
+// NextCursor continues with older notifications and is empty on the last page. UnreadCount counts the whole inbox.
+type NotificationsPage struct {
+	Notifications []Notification `json:"notifications"`
+	NextCursor    string         `json:"nextCursor,omitempty"`
+	UnreadCount   int64          `json:"unreadCount"`
+}
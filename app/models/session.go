@@ -0,0 +1,20 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Session is an authenticated session of a user.
+// ID is the hash of the bearer token handed to the user, the token itself is never stored.
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     uint      `json:"userId"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+}
+
+// Implement encoding.BinaryMarshaler for Redis
+func (session Session) MarshalBinary() (data []byte, err error) {
+	return json.Marshal(session)
+}
@@ -0,0 +1,8 @@
+package models
+
+// Trend is a hashtag trending in a window of time. Score weights its tweets by how recent they are.
+type Trend struct {
+	Hashtag    string  `json:"hashtag"`
+	Score      float64 `json:"score"`
+	TweetCount int     `json:"tweetCount"`
+}
@@ -3,34 +3,227 @@ package models
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
+	"unicode"
+
+	"github.com/google/uuid"
 )
 
 type Tweet struct {
+	ID        uuid.UUID `json:"id"`
 	UserID    uint      `json:"userId"`
 	Timestamp time.Time `json:"timestamp"`
 	Body      string    `json:"body"`
+	// Mentions are the ids of the users mentioned in the body, sorted
+	Mentions []uint `json:"mentions,omitempty"`
+	// Hashtags are the hashtags of the body, lowercase and in order of appearance
+	Hashtags []string `json:"hashtags,omitempty"`
+	// InReplyTo is the id of the tweet this one replies to, nil if it isn't a reply
+	InReplyTo *uuid.UUID `json:"inReplyTo,omitempty"`
+	// ConversationID is the id of the tweet that started the conversation, the tweet itself if it isn't a reply
+	ConversationID uuid.UUID `json:"conversationId"`
+	// QuotedTweetID is the id of the tweet quoted by this one, nil if it isn't a quote
+	QuotedTweetID *uuid.UUID `json:"quotedTweetId,omitempty"`
+	// QuotedTweet is the tweet quoted by this one, filled when it's read
+	QuotedTweet *Tweet `json:"quotedTweet,omitempty"`
+	// QuoteUnavailable tells that the quoted tweet expired or was deleted, filled when it's read
+	QuoteUnavailable bool `json:"quoteUnavailable,omitempty"`
+	// EditedAt is the time of the last edit, nil if the tweet wasn't edited
+	EditedAt *time.Time `json:"editedAt,omitempty"`
+	// RetweetedBy are the users followed by the reader of a timeline that retweeted the tweet, only set in timelines
+	RetweetedBy []uint `json:"retweetedBy,omitempty"`
+	// LikedByMe tells if the reader of a timeline likes the tweet, only set in timelines
+	LikedByMe bool `json:"likedByMe"`
+	TweetCounts
+}
+
+// TweetCounts are the interactions with a tweet, filled when it's read
+type TweetCounts struct {
+	ReplyCount int64 `json:"replyCount"`
+	LikeCount  int64 `json:"likeCount"`
+}
+
+// Thread is a tweet of a conversation with its replies, oldest first
+type Thread struct {
+	Tweet
+	Replies []Thread `json:"replies"`
+}
+
+// TweetRevision is a version of the body of a tweet, written at Timestamp
+type TweetRevision struct {
+	Body      string    `json:"body"`
+	Timestamp time.Time `json:"timestamp"`
 }
 
+// MaxLength is the default max length of the content of a tweet
 const MaxLength = 150
 
-var ErrMaxLengthExceeded = errors.New("max Length of 150 exceeded")
+// MaxSearchTermLength is the max length of a search term, longer words are cut
+const MaxSearchTermLength = 50
+
+var ErrMaxLengthExceeded = errors.New("max length exceeded")
+
+var ErrInvalidHashtag = errors.New("hashtag must have letters, numbers or underscores and can't be only numbers")
+
+var (
+	mentionPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_@])@([A-Za-z0-9_]+)`)
+	hashtagPattern = regexp.MustCompile(`(?:^|[^\pL\pN_&#])#([\pL\pN_]+)`)
+)
+
+// Creates New Tweet with the hashtags of content
+// Returns ErrMaxLengthExceeded if content length is bigger than maxLength characters, MaxLength if it's 0.
+func NewTweet(userID uint, timestamp time.Time, content string, maxLength int) (*Tweet, error) {
+	if maxLength <= 0 {
+		maxLength = MaxLength
+	}
 
-// Creates New Tweet
-// Returns ErrMaxLengthExceeded if content length is bigger than 150 characters.
-func NewTweet(userID uint, timestamp time.Time, content string) (*Tweet, error) {
-	if len(content) > MaxLength {
-		return nil, ErrMaxLengthExceeded
+	if len(content) > maxLength {
+		return nil, fmt.Errorf("%w: %d characters", ErrMaxLengthExceeded, maxLength)
 	}
 
 	return &Tweet{
 		UserID:    userID,
 		Timestamp: timestamp,
 		Body:      content,
+		Hashtags:  ParseHashtags(content),
 	}, nil
 }
 
+// NewReply creates a new tweet of userID replying to parent, in the conversation of parent
+// Returns ErrMaxLengthExceeded like NewTweet.
+func NewReply(userID uint, timestamp time.Time, content string, maxLength int, parent Tweet) (*Tweet, error) {
+	reply, err := NewTweet(userID, timestamp, content, maxLength)
+	if err != nil {
+		return nil, err
+	}
+
+	reply.InReplyTo = &parent.ID
+	reply.ConversationID = parent.Conversation()
+
+	return reply, nil
+}
+
+// NewQuote creates a new tweet of userID with its own content quoting quoted
+// Returns ErrMaxLengthExceeded like NewTweet.
+func NewQuote(userID uint, timestamp time.Time, content string, maxLength int, quoted Tweet) (*Tweet, error) {
+	quote, err := NewTweet(userID, timestamp, content, maxLength)
+	if err != nil {
+		return nil, err
+	}
+
+	quote.QuotedTweetID = &quoted.ID
+
+	return quote, nil
+}
+
+// ParseMentions returns the handles or ids mentioned with @ in content, in order of appearance and without repeating them.
+// Mentions are only recognized at the start of content or after a character that can't be part of a handle.
+func ParseMentions(content string) []string {
+	var mentions []string
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		mention := match[1]
+		if len(mention) > MaxHandleLength || slices.Contains(mentions, mention) {
+			continue
+		}
+
+		mentions = append(mentions, mention)
+	}
+
+	return mentions
+}
+
+// ParseHashtags returns the hashtags of content in lowercase, in order of appearance and without repeating them.
+// Hashtags are only recognized at the start of content or after a character that can't be part of one, and
+// can't be only numbers.
+func ParseHashtags(content string) []string {
+	var hashtags []string
+
+	for _, match := range hashtagPattern.FindAllStringSubmatch(content, -1) {
+		hashtag := strings.ToLower(match[1])
+		if !isHashtag(hashtag) || slices.Contains(hashtags, hashtag) {
+			continue
+		}
+
+		hashtags = append(hashtags, hashtag)
+	}
+
+	return hashtags
+}
+
+// NormalizeHashtag returns hashtag, with or without #, like ParseHashtags returns it
+// Returns ErrInvalidHashtag if it isn't a hashtag
+func NormalizeHashtag(hashtag string) (string, error) {
+	word := strings.TrimPrefix(hashtag, "#")
+
+	hashtags := ParseHashtags("#" + word)
+	if len(hashtags) != 1 || hashtags[0] != strings.ToLower(word) {
+		return "", ErrInvalidHashtag
+	}
+
+	return hashtags[0], nil
+}
+
+// SearchTerms returns the words of text in lowercase, in order of appearance and without repeating them, cut to
+// MaxSearchTermLength. Words are made of letters and numbers, so mentions and hashtags are searched without @ and #.
+func SearchTerms(text string) []string {
+	var terms []string
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	for _, word := range words {
+		if runes := []rune(word); len(runes) > MaxSearchTermLength {
+			word = string(runes[:MaxSearchTermLength])
+		}
+
+		if !slices.Contains(terms, word) {
+			terms = append(terms, word)
+		}
+	}
+
+	return terms
+}
+
+// isHashtag reports whether the word of a hashtag has something besides numbers
+func isHashtag(word string) bool {
+	return strings.IndexFunc(word, func(r rune) bool { return !unicode.IsDigit(r) }) >= 0
+}
+
+// Conversation returns ConversationID, or the tweet ID for tweets stored before conversations existed
+func (tweet Tweet) Conversation() uuid.UUID {
+	if tweet.ConversationID == uuid.Nil {
+		return tweet.ID
+	}
+
+	return tweet.ConversationID
+}
+
 // Implement encoding.BinaryMarshaler for Redis
 func (tweet Tweet) MarshalBinary() (data []byte, err error) {
 	return json.Marshal(tweet)
 }
+
+// Revision returns the current revision of the tweet
+func (tweet Tweet) Revision() TweetRevision {
+	revision := TweetRevision{
+		Body:      tweet.Body,
+		Timestamp: tweet.Timestamp,
+	}
+
+	if tweet.EditedAt != nil {
+		revision.Timestamp = *tweet.EditedAt
+	}
+
+	return revision
+}
+
+// Implement encoding.BinaryMarshaler for Redis
+func (revision TweetRevision) MarshalBinary() (data []byte, err error) {
+	return json.Marshal(revision)
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type User struct {
+	ID     uint   `json:"id"`
+	Handle string `json:"handle"`
+	Profile
+	PasswordHash []byte    `json:"-"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// Profile is what a user tells about itself to the other users
+type Profile struct {
+	DisplayName string `json:"displayName"`
+	Bio         string `json:"bio"`
+}
+
+const (
+	MaxHandleLength      = 15
+	MinPasswordLength    = 8
+	MaxDisplayNameLength = 50
+	MaxBioLength         = 160
+)
+
+var (
+	ErrInvalidHandle      = errors.New("handle must have between 1 and 15 letters, numbers or underscores")
+	ErrPasswordTooShort   = errors.New("password must have at least 8 characters")
+	ErrDisplayNameTooLong = errors.New("display name can't have more than 50 characters")
+	ErrBioTooLong         = errors.New("bio can't have more than 160 characters")
+)
+
+var handlePattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,15}$`)
+
+// Creates New User with the password hashed
+// Returns ErrInvalidHandle if handle isn't valid or ErrPasswordTooShort if password is shorter than 8 characters.
+func NewUser(handle, password string, createdAt time.Time) (*User, error) {
+	err := CheckHandle(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &User{
+		Handle:    handle,
+		CreatedAt: createdAt,
+	}
+
+	err = user.SetPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// CheckHandle returns ErrInvalidHandle if handle isn't valid
+func CheckHandle(handle string) error {
+	if !handlePattern.MatchString(handle) {
+		return ErrInvalidHandle
+	}
+
+	return nil
+}
+
+// NewProfile creates a profile with displayName and bio without surrounding spaces
+// Returns ErrDisplayNameTooLong if displayName is longer than MaxDisplayNameLength characters or ErrBioTooLong if bio
+// is longer than MaxBioLength characters.
+func NewProfile(displayName, bio string) (Profile, error) {
+	profile := Profile{
+		DisplayName: strings.TrimSpace(displayName),
+		Bio:         strings.TrimSpace(bio),
+	}
+
+	if utf8.RuneCountInString(profile.DisplayName) > MaxDisplayNameLength {
+		return Profile{}, ErrDisplayNameTooLong
+	}
+
+	if utf8.RuneCountInString(profile.Bio) > MaxBioLength {
+		return Profile{}, ErrBioTooLong
+	}
+
+	return profile, nil
+}
+
+// SetPassword replaces the password hash of the user
+// Returns ErrPasswordTooShort if password is shorter than 8 characters.
+func (user *User) SetPassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return ErrPasswordTooShort
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	user.PasswordHash = hash
+
+	return nil
+}
+
+// CheckPassword reports whether password matches the password hash of the user
+func (user User) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(password)) == nil
+}
+
+// Implement encoding.BinaryMarshaler for Redis, keeping the password hash
+func (user User) MarshalBinary() (data []byte, err error) {
+	return json.Marshal(storedUser{User: user, PasswordHash: user.PasswordHash})
+}
+
+// Implement encoding.BinaryUnmarshaler for Redis, restoring the password hash
+func (user *User) UnmarshalBinary(data []byte) error {
+	stored := storedUser{}
+
+	err := json.Unmarshal(data, &stored)
+	if err != nil {
+		return err
+	}
+
+	*user = stored.User
+	user.PasswordHash = stored.PasswordHash
+
+	return nil
+}
+
+// storedUser is the representation of User in the cache, the hash is hidden from API responses only
+type storedUser struct {
+	User
+	PasswordHash []byte `json:"passwordHash"`
+}
@@ -0,0 +1,251 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// StreamQueue is a queue of fan-out jobs over a Redis Stream, consumed by a pool of workers in a consumer group.
+// Jobs are delivered at least once: a job whose worker dies is claimed by another one after ClaimIdle.
+// A failed job is left pending, so it's retried ClaimIdle later, until it's delivered MaxAttempts times, and then
+// moved to DeadLetterStream.
+type StreamQueue struct {
+	Redis            *redis.Client
+	Stream           string
+	DeadLetterStream string
+	Group            string
+	// Consumer prefixes the consumer names of the workers, it must be unique among processes
+	Consumer    string
+	Workers     int
+	MaxAttempts int
+	// ClaimIdle is how long a delivered job stays pending before another worker claims it, which is also the delay
+	// before retrying a failed job
+	ClaimIdle time.Duration
+	// Block is how long a worker waits for new jobs, which also bounds how long it takes to stop
+	Block time.Duration
+}
+
+// Handler fans out tweetID of userID
+type Handler func(tweetID uuid.UUID, userID uint) error
+
+const (
+	DefaultStream           = "fanout"
+	DefaultDeadLetterStream = "fanout-dead-letter"
+	DefaultGroup            = "fanout-workers"
+	DefaultWorkers          = 4
+	DefaultMaxAttempts      = 5
+	DefaultClaimIdle        = time.Minute
+	DefaultBlock            = 5 * time.Second
+
+	// batchSize is the amount of jobs a worker reads at once
+	batchSize = 10
+	// errorDelay is how long a worker waits after failing to read jobs
+	errorDelay = time.Second
+)
+
+const (
+	tweetIDField = "tweet_id"
+	userIDField  = "user_id"
+	attemptField = "attempt"
+	errorField   = "error"
+)
+
+var ErrInvalidJob = errors.New("invalid fan-out job")
+
+// NewStreamQueue creates a StreamQueue with the default settings and the given consumer name prefix
+func NewStreamQueue(redis *redis.Client, consumer string) StreamQueue {
+	return StreamQueue{
+		Redis:            redis,
+		Stream:           DefaultStream,
+		DeadLetterStream: DefaultDeadLetterStream,
+		Group:            DefaultGroup,
+		Consumer:         consumer,
+		Workers:          DefaultWorkers,
+		MaxAttempts:      DefaultMaxAttempts,
+		ClaimIdle:        DefaultClaimIdle,
+		Block:            DefaultBlock,
+	}
+}
+
+// Enqueue adds the fan-out job of tweetID of userID to the stream
+func (queue StreamQueue) Enqueue(tweetID uuid.UUID, userID uint) error {
+	return queue.add(context.Background(), queue.Stream, map[string]any{
+		tweetIDField: tweetID.String(),
+		userIDField:  userID,
+	})
+}
+
+// Consume runs Workers workers handling jobs with handle until ctx is done
+func (queue StreamQueue) Consume(ctx context.Context, handle Handler) error {
+	err := queue.Redis.XGroupCreateMkStream(ctx, queue.Stream, queue.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < queue.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			queue.work(ctx, fmt.Sprintf("%s-%d", queue.Consumer, i), handle)
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (queue StreamQueue) work(ctx context.Context, consumer string, handle Handler) {
+	claimStart := "0-0"
+
+	for ctx.Err() == nil {
+		//jobs of dead workers first
+		claimed, next, err := queue.Redis.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   queue.Stream,
+			Group:    queue.Group,
+			MinIdle:  queue.ClaimIdle,
+			Start:    claimStart,
+			Count:    batchSize,
+			Consumer: consumer,
+		}).Result()
+		if err != nil {
+			queue.wait(ctx, err)
+			continue
+		}
+		claimStart = next
+
+		for _, message := range claimed {
+			queue.process(message, handle)
+		}
+
+		streams, err := queue.Redis.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    queue.Group,
+			Consumer: consumer,
+			Streams:  []string{queue.Stream, ">"},
+			Count:    batchSize,
+			Block:    queue.Block,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		} else if err != nil {
+			queue.wait(ctx, err)
+			continue
+		}
+
+		for _, stream := range streams {
+			for _, message := range stream.Messages {
+				queue.process(message, handle)
+			}
+		}
+	}
+}
+
+// process handles a job and acknowledges it. A failed job is left pending to be claimed again after ClaimIdle, or
+// moved to the dead letter stream once it was delivered MaxAttempts times.
+// It doesn't use the workers context so that a job being handled is always acknowledged.
+func (queue StreamQueue) process(message redis.XMessage, handle Handler) {
+	tweetID, userID, err := parseJob(message)
+	if err == nil {
+		err = handle(tweetID, userID)
+	}
+
+	if err != nil {
+		attempt, attemptErr := queue.deliveries(message.ID)
+		if attemptErr != nil {
+			//left pending, it will be claimed again
+			log.Println(attemptErr.Error())
+			return
+		}
+
+		log.Printf("fan-out job %s attempt %d failed: %s", message.ID, attempt, err.Error())
+
+		if !errors.Is(err, ErrInvalidJob) && attempt < queue.MaxAttempts {
+			return
+		}
+
+		err = queue.add(context.Background(), queue.DeadLetterStream, map[string]any{
+			tweetIDField: message.Values[tweetIDField],
+			userIDField:  message.Values[userIDField],
+			attemptField: attempt,
+			errorField:   err.Error(),
+		})
+		if err != nil {
+			//left pending, it will be claimed again
+			log.Println(err.Error())
+			return
+		}
+	}
+
+	_, err = queue.Redis.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.XAck(context.Background(), queue.Stream, queue.Group, message.ID)
+		pipe.XDel(context.Background(), queue.Stream, message.ID)
+		return nil
+	})
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// deliveries returns how many times the pending job messageID was delivered to a worker
+func (queue StreamQueue) deliveries(messageID string) (int, error) {
+	pending, err := queue.Redis.XPendingExt(context.Background(), &redis.XPendingExtArgs{
+		Stream: queue.Stream,
+		Group:  queue.Group,
+		Start:  messageID,
+		End:    messageID,
+		Count:  1,
+	}).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if len(pending) == 0 {
+		return 0, fmt.Errorf("fan-out job %s isn't pending", messageID)
+	}
+
+	return int(pending[0].RetryCount), nil
+}
+
+func (queue StreamQueue) add(ctx context.Context, stream string, values map[string]any) error {
+	return queue.Redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		Values: values,
+	}).Err()
+}
+
+// wait logs err and waits errorDelay or until ctx is done
+func (queue StreamQueue) wait(ctx context.Context, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	log.Println(err.Error())
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(errorDelay):
+	}
+}
+
+func parseJob(message redis.XMessage) (uuid.UUID, uint, error) {
+	tweetID, err := uuid.Parse(fmt.Sprint(message.Values[tweetIDField]))
+	if err != nil {
+		return uuid.Nil, 0, fmt.Errorf("%w: tweet id", ErrInvalidJob)
+	}
+
+	userID, err := strconv.ParseUint(fmt.Sprint(message.Values[userIDField]), 10, 64)
+	if err != nil {
+		return uuid.Nil, 0, fmt.Errorf("%w: user id", ErrInvalidJob)
+	}
+
+	return tweetID, uint(userID), nil
+}
@@ -0,0 +1,156 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PatricioYegros/uala_challenge/app/queue"
+	"github.com/go-playground/assert/v2"
+	"github.com/google/uuid"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type job struct {
+	tweetID uuid.UUID
+	userID  uint
+}
+
+// recorder is a Handler that records the jobs it handles, failing the first failures ones
+type recorder struct {
+	mutex    sync.Mutex
+	jobs     []job
+	failures int
+}
+
+func (recorder *recorder) handle(tweetID uuid.UUID, userID uint) error {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.jobs = append(recorder.jobs, job{tweetID: tweetID, userID: userID})
+
+	if recorder.failures > 0 {
+		recorder.failures--
+		return errors.New("Error")
+	}
+
+	return nil
+}
+
+func (recorder *recorder) handled() int {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	return len(recorder.jobs)
+}
+
+func newQueue(t *testing.T) (queue.StreamQueue, *redis.Client) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	streamQueue := queue.NewStreamQueue(client, "test")
+	streamQueue.Workers = 2
+	streamQueue.Block = 10 * time.Millisecond
+	streamQueue.ClaimIdle = 50 * time.Millisecond
+
+	return streamQueue, client
+}
+
+// consumeUntil runs the workers until done returns true or a second passes
+func consumeUntil(t *testing.T, streamQueue queue.StreamQueue, handle queue.Handler, done func() bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	go func() {
+		for ctx.Err() == nil && !done() {
+			time.Sleep(5 * time.Millisecond)
+		}
+		cancel()
+	}()
+
+	assert.Equal(t, streamQueue.Consume(ctx, handle), nil)
+	assert.Equal(t, done(), true)
+}
+
+func TestConsumeHandlesAndDeletesJobs(t *testing.T) {
+	streamQueue, client := newQueue(t)
+	recorder := &recorder{}
+
+	tweetID := uuid.New()
+	assert.Equal(t, streamQueue.Enqueue(tweetID, 1), nil)
+	assert.Equal(t, streamQueue.Enqueue(uuid.New(), 2), nil)
+
+	consumeUntil(t, streamQueue, recorder.handle, func() bool { return recorder.handled() == 2 })
+
+	assert.Equal(t, recorder.jobs[0], job{tweetID: tweetID, userID: 1})
+	assert.Equal(t, client.XLen(context.Background(), streamQueue.Stream).Val(), int64(0))
+}
+
+func TestConsumeRetriesFailedJobs(t *testing.T) {
+	streamQueue, client := newQueue(t)
+	recorder := &recorder{failures: 2}
+
+	assert.Equal(t, streamQueue.Enqueue(uuid.New(), 1), nil)
+
+	consumeUntil(t, streamQueue, recorder.handle, func() bool { return recorder.handled() == 3 })
+
+	assert.Equal(t, client.XLen(context.Background(), streamQueue.Stream).Val(), int64(0))
+	assert.Equal(t, client.XLen(context.Background(), streamQueue.DeadLetterStream).Val(), int64(0))
+}
+
+func TestConsumeDelaysRetries(t *testing.T) {
+	streamQueue, client := newQueue(t)
+	streamQueue.ClaimIdle = time.Minute
+	recorder := &recorder{failures: 1}
+
+	assert.Equal(t, streamQueue.Enqueue(uuid.New(), 1), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, streamQueue.Consume(ctx, recorder.handle), nil)
+
+	//the failed job waits in the pending list instead of being retried right away
+	assert.Equal(t, recorder.handled(), 1)
+	assert.Equal(t, client.XPending(context.Background(), streamQueue.Stream, streamQueue.Group).Val().Count, int64(1))
+	assert.Equal(t, client.XLen(context.Background(), streamQueue.DeadLetterStream).Val(), int64(0))
+}
+
+func TestConsumeMovesExhaustedJobsToDeadLetter(t *testing.T) {
+	streamQueue, client := newQueue(t)
+	streamQueue.MaxAttempts = 2
+	recorder := &recorder{failures: 2}
+
+	assert.Equal(t, streamQueue.Enqueue(uuid.New(), 1), nil)
+
+	consumeUntil(t, streamQueue, recorder.handle, func() bool {
+		return client.XLen(context.Background(), streamQueue.DeadLetterStream).Val() == 1
+	})
+
+	assert.Equal(t, recorder.handled(), 2)
+	assert.Equal(t, client.XLen(context.Background(), streamQueue.Stream).Val(), int64(0))
+}
+
+func TestConsumeClaimsJobsOfDeadWorkers(t *testing.T) {
+	streamQueue, client := newQueue(t)
+	recorder := &recorder{}
+	ctx := context.Background()
+
+	assert.Equal(t, streamQueue.Enqueue(uuid.New(), 1), nil)
+
+	//a worker reads the job and dies before acknowledging it
+	assert.Equal(t, client.XGroupCreateMkStream(ctx, streamQueue.Stream, streamQueue.Group, "0").Err(), nil)
+	assert.Equal(t, client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    streamQueue.Group,
+		Consumer: "dead",
+		Streams:  []string{streamQueue.Stream, ">"},
+	}).Err(), nil)
+
+	consumeUntil(t, streamQueue, recorder.handle, func() bool { return recorder.handled() == 1 })
+
+	assert.Equal(t, client.XLen(ctx, streamQueue.Stream).Val(), int64(0))
+}
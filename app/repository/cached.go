@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/PatricioYegros/uala_challenge/app/models"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// CachedRepository puts Redis as a read-through cache of tweets in front of a durable IRepository.
+// Every other operation goes straight to the durable repository.
+type CachedRepository struct {
+	IRepository
+	Redis *redis.Client
+	Limits
+}
+
+// CreateTweet creates a new tweet in the durable repository and caches it for TweetTTL
+func (repository CachedRepository) CreateTweet(tweet models.Tweet) (uuid.UUID, error) {
+	tweetID, err := repository.IRepository.CreateTweet(tweet)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	tweet.ID = tweetID
+	if tweet.ConversationID == uuid.Nil {
+		tweet.ConversationID = tweetID
+	}
+
+	return tweetID, repository.Redis.Set(context.Background(), TweetKey(tweetID), tweet, repository.tweetTTL()).Err()
+}
+
+// GetTweets returns the tweets found by ids with their counts and quoted tweets, in the same order, and the ids that
+// don't exist anymore. Quoted tweets are read through the cache too.
+func (repository CachedRepository) GetTweets(ids []uuid.UUID) ([]models.Tweet, []uuid.UUID, error) {
+	tweets, missing, err := repository.getCountedTweets(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tweets, missing, withQuotes(tweets, repository.getCountedTweets)
+}
+
+// getCountedTweets returns the tweets found by ids with their counts, in the same order, and the ids that don't exist anymore
+// Tweets missing in the cache are loaded from the durable repository and cached again.
+// Counts aren't cached, they always come from the durable repository.
+func (repository CachedRepository) getCountedTweets(ids []uuid.UUID) ([]models.Tweet, []uuid.UUID, error) {
+	cached, notCached, err := Repository{Redis: repository.Redis}.getTweets(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(notCached) == 0 {
+		return cached, nil, withCounts(cached, repository.IRepository.GetTweetCounts)
+	}
+
+	loaded, missing, err := repository.IRepository.GetTweets(notCached)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	found := make(map[uuid.UUID]models.Tweet, len(ids))
+	for _, tweet := range cached {
+		found[tweet.ID] = tweet
+	}
+
+	_, err = repository.Redis.Pipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		for _, tweet := range loaded {
+			found[tweet.ID] = tweet
+			//only the stored tweet is cached
+			tweet.TweetCounts = models.TweetCounts{}
+			tweet.QuotedTweet = nil
+			tweet.QuoteUnavailable = false
+			pipe.Set(context.Background(), TweetKey(tweet.ID), tweet, repository.tweetTTL())
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tweets := make([]models.Tweet, 0, len(found))
+	for _, id := range ids {
+		if tweet, ok := found[id]; ok {
+			tweets = append(tweets, tweet)
+		}
+	}
+
+	return tweets, missing, withCounts(tweets, repository.IRepository.GetTweetCounts)
+}
+
+// DeleteTweet deletes the tweet from the durable repository and then from the cache
+func (repository CachedRepository) DeleteTweet(tweet models.Tweet, timelineUserIDs []uint) (bool, error) {
+	deleted, err := repository.IRepository.DeleteTweet(tweet, timelineUserIDs)
+	if err != nil {
+		return false, err
+	}
+
+	return deleted, repository.Redis.Del(context.Background(), TweetKey(tweet.ID)).Err()
+}
+
+// EditTweet edits the tweet in the durable repository and drops it from the cache, to be loaded again when read
+func (repository CachedRepository) EditTweet(edited models.Tweet) (bool, error) {
+	found, err := repository.IRepository.EditTweet(edited)
+	if err != nil || !found {
+		return found, err
+	}
+
+	return true, repository.Redis.Del(context.Background(), TweetKey(edited.ID)).Err()
+}
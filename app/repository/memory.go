@@ -0,0 +1,1093 @@
+package repository
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PatricioYegros/uala_challenge/app/models"
+	"github.com/PatricioYegros/uala_challenge/app/utils"
+
+	"github.com/google/uuid"
+)
+
+// MemoryRepository implements IRepository in process memory.
+// It is safe for concurrent use and honors the same TTLs as Repository using Clock.
+type MemoryRepository struct {
+	Clock utils.IClock
+	Limits
+
+	mutex     sync.Mutex
+	followers map[uint]map[uint]struct{}
+	following map[uint]map[uint]struct{}
+	tweets    map[uuid.UUID]expiringTweet
+	// tweetHistories keeps the previous revisions of every edited tweet, oldest first
+	tweetHistories map[uuid.UUID][]models.TweetRevision
+	// replyCounts keeps the amount of replies of every tweet with replies
+	replyCounts map[uuid.UUID]int64
+	// retweets keeps the users that retweeted every tweet
+	retweets map[uuid.UUID]map[uint]struct{}
+	// likes keeps the users that like every tweet
+	likes map[uuid.UUID]map[uint]struct{}
+	// userLikes keeps the latest MaxUserLikes tweets ids liked by every user, newest like first
+	userLikes map[uint][]uuid.UUID
+	// conversations keeps the replies ids of every conversation, oldest first
+	conversations map[uuid.UUID][]uuid.UUID
+	// hashtags keeps the latest MaxHashtagTweets tweets ids of every hashtag, newest first
+	hashtags map[string][]uuid.UUID
+	// searchTerms keeps the ids of the tweets with every search term
+	searchTerms map[string]map[uuid.UUID]struct{}
+	// userTweets keeps the latest MaxUserTweets tweets ids of every user, newest first
+	userTweets map[uint][]uuid.UUID
+	timelines  map[uint][]uuid.UUID
+	sessions   map[string]expiringSession
+
+	lastUserID uint
+	users      map[uint]models.User
+	handles    map[string]uint
+	// handleRedirects keeps the users that renamed every handle while it redirects, by lowercase handle
+	handleRedirects map[string]expiringUserID
+	failedLogins    map[uint]expiringCounter
+	passwordResets  map[string]expiringUserID
+	// notifications keeps the latest MaxNotifications notifications of every user, newest first
+	notifications map[uint][]models.Notification
+	// notificationsReadAt keeps the time until which every user read its notifications
+	notificationsReadAt map[uint]time.Time
+}
+
+type expiringTweet struct {
+	tweet     models.Tweet
+	expiresAt time.Time
+}
+
+type expiringSession struct {
+	session   models.Session
+	expiresAt time.Time
+}
+
+type expiringCounter struct {
+	count     int64
+	expiresAt time.Time
+}
+
+type expiringUserID struct {
+	userID    uint
+	expiresAt time.Time
+}
+
+// NewMemoryRepository creates an empty MemoryRepository that expires entries using clock
+func NewMemoryRepository(clock utils.IClock) *MemoryRepository {
+	return &MemoryRepository{
+		Clock:          clock,
+		followers:      make(map[uint]map[uint]struct{}),
+		following:      make(map[uint]map[uint]struct{}),
+		tweets:         make(map[uuid.UUID]expiringTweet),
+		tweetHistories: make(map[uuid.UUID][]models.TweetRevision),
+		replyCounts:    make(map[uuid.UUID]int64),
+		conversations:  make(map[uuid.UUID][]uuid.UUID),
+		retweets:       make(map[uuid.UUID]map[uint]struct{}),
+		likes:          make(map[uuid.UUID]map[uint]struct{}),
+		userLikes:      make(map[uint][]uuid.UUID),
+		hashtags:       make(map[string][]uuid.UUID),
+		searchTerms:    make(map[string]map[uuid.UUID]struct{}),
+		userTweets:     make(map[uint][]uuid.UUID),
+		timelines:      make(map[uint][]uuid.UUID),
+		sessions:       make(map[string]expiringSession),
+
+		users:           make(map[uint]models.User),
+		handles:         make(map[string]uint),
+		handleRedirects: make(map[string]expiringUserID),
+		failedLogins:    make(map[uint]expiringCounter),
+		passwordResets:  make(map[string]expiringUserID),
+
+		notifications:       make(map[uint][]models.Notification),
+		notificationsReadAt: make(map[uint]time.Time),
+	}
+}
+
+// AddFollower adds the newFollowerID to the list of followers of userID
+func (repository *MemoryRepository) AddFollower(userID, newFollowerID uint) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	addToSet(repository.followers, userID, newFollowerID)
+	addToSet(repository.following, newFollowerID, userID)
+
+	return nil
+}
+
+// RemoveFollower removes followerID from the list of followers of userID. Returns false if it wasn't following.
+func (repository *MemoryRepository) RemoveFollower(userID, followerID uint) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	if _, ok := repository.followers[userID][followerID]; !ok {
+		return false, nil
+	}
+	delete(repository.followers[userID], followerID)
+	delete(repository.following[followerID], userID)
+
+	return true, nil
+}
+
+// GetFollowers returns the list of followers of userID
+func (repository *MemoryRepository) GetFollowers(userID uint) ([]uint, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	return sortedSet(repository.followers[userID]), nil
+}
+
+// GetFollowing returns the list of users followed by userID
+func (repository *MemoryRepository) GetFollowing(userID uint) ([]uint, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	return sortedSet(repository.following[userID]), nil
+}
+
+// ScanFollowers returns a page of count followers of userID with ids greater than cursor, and the cursor of the next page
+func (repository *MemoryRepository) ScanFollowers(userID uint, cursor uint64, count int64) ([]uint, uint64, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	ids, next := pageOf(sortedSet(repository.followers[userID]), cursor, count)
+
+	return ids, next, nil
+}
+
+// ScanFollowing returns a page of the users followed by userID like ScanFollowers
+func (repository *MemoryRepository) ScanFollowing(userID uint, cursor uint64, count int64) ([]uint, uint64, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	ids, next := pageOf(sortedSet(repository.following[userID]), cursor, count)
+
+	return ids, next, nil
+}
+
+// CountFollowers returns the amount of followers of userID
+func (repository *MemoryRepository) CountFollowers(userID uint) (int64, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	return int64(len(repository.followers[userID])), nil
+}
+
+// CountFollowing returns the amount of users followed by userID
+func (repository *MemoryRepository) CountFollowing(userID uint) (int64, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	return int64(len(repository.following[userID])), nil
+}
+
+// CountFollowersOf returns the amount of followers of every user in userIDs, in the same order
+func (repository *MemoryRepository) CountFollowersOf(userIDs []uint) ([]int64, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	counts := make([]int64, 0, len(userIDs))
+	for _, userID := range userIDs {
+		counts = append(counts, int64(len(repository.followers[userID])))
+	}
+
+	return counts, nil
+}
+
+// CreateTweet creates a new tweet and returns the uuid
+// Replies are added to their conversation and counted in the tweet they reply to.
+func (repository *MemoryRepository) CreateTweet(tweet models.Tweet) (uuid.UUID, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	tweet.ID = uuid.New()
+	tweet.Mentions = slices.Clone(tweet.Mentions)
+	tweet.Hashtags = slices.Clone(tweet.Hashtags)
+	if tweet.ConversationID == uuid.Nil {
+		tweet.ConversationID = tweet.ID
+	}
+
+	if tweet.InReplyTo != nil {
+		repository.replyCounts[*tweet.InReplyTo]++
+		repository.conversations[tweet.ConversationID] = append(repository.conversations[tweet.ConversationID], tweet.ID)
+	}
+
+	repository.tweets[tweet.ID] = expiringTweet{
+		tweet:     tweet,
+		expiresAt: repository.Clock.Now().Add(repository.tweetTTL()),
+	}
+
+	repository.indexHashtags(tweet)
+	repository.indexSearchTerms(tweet.ID, models.SearchTerms(tweet.Body))
+
+	userTweets := append([]uuid.UUID{tweet.ID}, repository.userTweets[tweet.UserID]...)
+	if len(userTweets) > MaxUserTweets {
+		userTweets = userTweets[:MaxUserTweets]
+	}
+	repository.userTweets[tweet.UserID] = userTweets
+
+	return tweet.ID, nil
+}
+
+// GetUserTweets returns the ids of the latest count tweets of userID, newest first. Ids of expired tweets may be included.
+func (repository *MemoryRepository) GetUserTweets(userID uint, count int64) ([]uuid.UUID, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	userTweets := repository.userTweets[userID]
+	if int64(len(userTweets)) > count {
+		userTweets = userTweets[:count]
+	}
+
+	return slices.Clone(userTweets), nil
+}
+
+// GetTweets returns the tweets found by ids with their counts and quoted tweets, in the same order, and the ids that
+// don't exist anymore
+func (repository *MemoryRepository) GetTweets(ids []uuid.UUID) ([]models.Tweet, []uuid.UUID, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	tweets, missing, err := repository.getCountedTweets(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tweets, missing, withQuotes(tweets, repository.getCountedTweets)
+}
+
+// getCountedTweets returns the tweets found by ids with their counts, in the same order, and the ids that don't exist anymore
+// It must be called holding the mutex.
+func (repository *MemoryRepository) getCountedTweets(ids []uuid.UUID) ([]models.Tweet, []uuid.UUID, error) {
+	now := repository.Clock.Now()
+	tweets := make([]models.Tweet, 0, len(ids))
+	var missing []uuid.UUID
+
+	for _, id := range ids {
+		stored, ok := repository.tweets[id]
+		if ok && !now.Before(stored.expiresAt) {
+			delete(repository.tweets, id)
+			delete(repository.tweetHistories, id)
+			ok = false
+		}
+
+		if !ok {
+			//ttl reached
+			missing = append(missing, id)
+			continue
+		}
+
+		tweet := stored.tweet
+		tweet.TweetCounts = repository.countsOf(id)
+		tweets = append(tweets, tweet)
+	}
+
+	return tweets, missing, nil
+}
+
+// GetTweetCounts returns the counts of every tweet in tweetIDs, in the same order
+func (repository *MemoryRepository) GetTweetCounts(tweetIDs []uuid.UUID) ([]models.TweetCounts, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	counts := make([]models.TweetCounts, 0, len(tweetIDs))
+	for _, tweetID := range tweetIDs {
+		counts = append(counts, repository.countsOf(tweetID))
+	}
+
+	return counts, nil
+}
+
+// countsOf returns the counts of tweetID. It must be called holding the mutex.
+func (repository *MemoryRepository) countsOf(tweetID uuid.UUID) models.TweetCounts {
+	return models.TweetCounts{
+		ReplyCount: repository.replyCounts[tweetID],
+		LikeCount:  int64(len(repository.likes[tweetID])),
+	}
+}
+
+// AddRetweet records that userID retweeted tweetID. Returns false if it was already retweeted.
+func (repository *MemoryRepository) AddRetweet(userID uint, tweetID uuid.UUID) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	return addToSet(repository.retweets, tweetID, userID), nil
+}
+
+// RemoveRetweet removes the retweet of tweetID by userID. Returns false if it wasn't retweeted.
+func (repository *MemoryRepository) RemoveRetweet(userID uint, tweetID uuid.UUID) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	return removeFromSet(repository.retweets, tweetID, userID), nil
+}
+
+// GetRetweeters returns the users that retweeted every tweet in tweetIDs, sorted by id, in the same order
+func (repository *MemoryRepository) GetRetweeters(tweetIDs []uuid.UUID) ([][]uint, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	retweeters := make([][]uint, 0, len(tweetIDs))
+	for _, tweetID := range tweetIDs {
+		retweeters = append(retweeters, sortedSet(repository.retweets[tweetID]))
+	}
+
+	return retweeters, nil
+}
+
+// AddLike records that userID likes tweetID. Returns false if it was already liked.
+func (repository *MemoryRepository) AddLike(userID uint, tweetID uuid.UUID) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	if !addToSet(repository.likes, tweetID, userID) {
+		return false, nil
+	}
+
+	userLikes := append([]uuid.UUID{tweetID}, repository.userLikes[userID]...)
+	if len(userLikes) > MaxUserLikes {
+		userLikes = userLikes[:MaxUserLikes]
+	}
+	repository.userLikes[userID] = userLikes
+
+	return true, nil
+}
+
+// RemoveLike removes the like of userID to tweetID. Returns false if it wasn't liked.
+func (repository *MemoryRepository) RemoveLike(userID uint, tweetID uuid.UUID) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	repository.userLikes[userID] = slices.DeleteFunc(repository.userLikes[userID], func(id uuid.UUID) bool {
+		return id == tweetID
+	})
+
+	return removeFromSet(repository.likes, tweetID, userID), nil
+}
+
+// GetLikedBy tells if userID likes every tweet in tweetIDs, in the same order
+func (repository *MemoryRepository) GetLikedBy(userID uint, tweetIDs []uuid.UUID) ([]bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	liked := make([]bool, 0, len(tweetIDs))
+	for _, tweetID := range tweetIDs {
+		_, ok := repository.likes[tweetID][userID]
+		liked = append(liked, ok)
+	}
+
+	return liked, nil
+}
+
+// GetUserLikes returns the ids of the latest MaxUserLikes tweets liked by userID, newest like first.
+// Ids of expired tweets may be included.
+func (repository *MemoryRepository) GetUserLikes(userID uint) ([]uuid.UUID, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	return slices.Clone(repository.userLikes[userID]), nil
+}
+
+// GetConversation returns the ids of the replies of a conversation, oldest first. Ids of expired tweets may be included.
+func (repository *MemoryRepository) GetConversation(conversationID uuid.UUID) ([]uuid.UUID, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	return slices.Clone(repository.conversations[conversationID]), nil
+}
+
+// DeleteTweet deletes the tweet and removes it from the timelines of timelineUserIDs.
+// Returns false if it didn't exist.
+func (repository *MemoryRepository) DeleteTweet(tweet models.Tweet, timelineUserIDs []uint) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	tweetID := tweet.ID
+	stored, ok := repository.tweets[tweetID]
+	found := ok && stored.tweet.UserID == tweet.UserID && repository.Clock.Now().Before(stored.expiresAt)
+	if ok && stored.tweet.UserID == tweet.UserID {
+		delete(repository.tweets, tweetID)
+		delete(repository.tweetHistories, tweetID)
+		delete(repository.replyCounts, tweetID)
+		delete(repository.retweets, tweetID)
+		delete(repository.likes, tweetID)
+		repository.unindexHashtags(tweetID, stored.tweet.Hashtags)
+		repository.unindexSearchTerms(tweetID, models.SearchTerms(stored.tweet.Body))
+
+		if inReplyTo := stored.tweet.InReplyTo; inReplyTo != nil {
+			repository.replyCounts[*inReplyTo]--
+			if repository.replyCounts[*inReplyTo] <= 0 {
+				delete(repository.replyCounts, *inReplyTo)
+			}
+		}
+	}
+
+	isTweet := func(id uuid.UUID) bool { return id == tweetID }
+
+	repository.conversations[tweet.ConversationID] = slices.DeleteFunc(repository.conversations[tweet.ConversationID], isTweet)
+	if len(repository.conversations[tweet.ConversationID]) == 0 {
+		delete(repository.conversations, tweet.ConversationID)
+	}
+
+	repository.userTweets[tweet.UserID] = slices.DeleteFunc(repository.userTweets[tweet.UserID], isTweet)
+	for _, timelineUserID := range timelineUserIDs {
+		repository.timelines[timelineUserID] = slices.DeleteFunc(repository.timelines[timelineUserID], isTweet)
+	}
+
+	return found, nil
+}
+
+// EditTweet replaces the body, mentions and hashtags of the tweet edited.ID of edited.UserID with the ones of edited,
+// edited at edited.EditedAt, keeping the previous revision in its history. Returns false if it doesn't exist.
+func (repository *MemoryRepository) EditTweet(edited models.Tweet) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	stored, ok := repository.tweets[edited.ID]
+	if !ok || stored.tweet.UserID != edited.UserID || !repository.Clock.Now().Before(stored.expiresAt) {
+		return false, nil
+	}
+
+	repository.tweetHistories[edited.ID] = append(repository.tweetHistories[edited.ID], stored.tweet.Revision())
+	repository.unindexHashtags(edited.ID, stored.tweet.Hashtags)
+	repository.unindexSearchTerms(edited.ID, models.SearchTerms(stored.tweet.Body))
+
+	stored.tweet.Body = edited.Body
+	stored.tweet.Mentions = slices.Clone(edited.Mentions)
+	stored.tweet.Hashtags = slices.Clone(edited.Hashtags)
+	stored.tweet.EditedAt = edited.EditedAt
+	repository.tweets[edited.ID] = stored
+
+	repository.indexHashtags(stored.tweet)
+	repository.indexSearchTerms(edited.ID, models.SearchTerms(stored.tweet.Body))
+
+	return true, nil
+}
+
+// indexHashtags adds tweet to the tweets of its hashtags, newest first, keeping the latest MaxHashtagTweets.
+// It must be called holding the mutex.
+func (repository *MemoryRepository) indexHashtags(tweet models.Tweet) {
+	for _, hashtag := range tweet.Hashtags {
+		tweetIDs := repository.hashtags[hashtag]
+
+		//tweets are usually the newest, but edits and clocks may disagree
+		i, _ := slices.BinarySearchFunc(tweetIDs, tweet.Timestamp, func(tweetID uuid.UUID, timestamp time.Time) int {
+			return timestamp.Compare(repository.tweets[tweetID].tweet.Timestamp)
+		})
+
+		tweetIDs = slices.Insert(tweetIDs, i, tweet.ID)
+		if len(tweetIDs) > MaxHashtagTweets {
+			tweetIDs = tweetIDs[:MaxHashtagTweets]
+		}
+		repository.hashtags[hashtag] = tweetIDs
+	}
+}
+
+// unindexHashtags removes tweetID from the tweets of hashtags. It must be called holding the mutex.
+func (repository *MemoryRepository) unindexHashtags(tweetID uuid.UUID, hashtags []string) {
+	for _, hashtag := range hashtags {
+		repository.hashtags[hashtag] = slices.DeleteFunc(repository.hashtags[hashtag], func(id uuid.UUID) bool {
+			return id == tweetID
+		})
+
+		if len(repository.hashtags[hashtag]) == 0 {
+			delete(repository.hashtags, hashtag)
+		}
+	}
+}
+
+// GetHashtagTweets returns the ids of the latest MaxHashtagTweets tweets with hashtag, newest first.
+// Ids of expired tweets may be included.
+func (repository *MemoryRepository) GetHashtagTweets(hashtag string) ([]uuid.UUID, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	return slices.Clone(repository.hashtags[hashtag]), nil
+}
+
+// GetHashtagUses returns the timestamps of the tweets of every hashtag used since since, by hashtag.
+// Expired tweets aren't used anymore.
+func (repository *MemoryRepository) GetHashtagUses(since time.Time) (map[string][]time.Time, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	now := repository.Clock.Now()
+	uses := make(map[string][]time.Time)
+
+	for hashtag, tweetIDs := range repository.hashtags {
+		for _, tweetID := range tweetIDs {
+			stored, ok := repository.tweets[tweetID]
+			if !ok || !now.Before(stored.expiresAt) {
+				continue
+			}
+
+			if stored.tweet.Timestamp.Before(since) {
+				//the rest are older
+				break
+			}
+
+			uses[hashtag] = append(uses[hashtag], stored.tweet.Timestamp)
+		}
+	}
+
+	return uses, nil
+}
+
+// indexSearchTerms adds tweetID to the tweets of every term in terms. It must be called holding the mutex.
+func (repository *MemoryRepository) indexSearchTerms(tweetID uuid.UUID, terms []string) {
+	for _, term := range terms {
+		if repository.searchTerms[term] == nil {
+			repository.searchTerms[term] = make(map[uuid.UUID]struct{})
+		}
+
+		repository.searchTerms[term][tweetID] = struct{}{}
+	}
+}
+
+// unindexSearchTerms removes tweetID from the tweets of every term in terms. It must be called holding the mutex.
+func (repository *MemoryRepository) unindexSearchTerms(tweetID uuid.UUID, terms []string) {
+	for _, term := range terms {
+		delete(repository.searchTerms[term], tweetID)
+
+		if len(repository.searchTerms[term]) == 0 {
+			delete(repository.searchTerms, term)
+		}
+	}
+}
+
+// SearchTweets returns the ids of the latest MaxSearchResults tweets with every term in terms, of userID unless it's 0,
+// created since since, newest first. Expired tweets found are removed from the index.
+func (repository *MemoryRepository) SearchTweets(terms []string, userID uint, since time.Time) ([]uuid.UUID, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	if len(terms) == 0 && userID == 0 {
+		return nil, nil
+	}
+
+	//the tweets of the rarest term are the fewest candidates
+	var candidates []uuid.UUID
+	if len(terms) == 0 {
+		candidates = make([]uuid.UUID, 0, len(repository.tweets))
+		for tweetID := range repository.tweets {
+			candidates = append(candidates, tweetID)
+		}
+	} else {
+		rarest := slices.MinFunc(terms, func(a, b string) int {
+			return len(repository.searchTerms[a]) - len(repository.searchTerms[b])
+		})
+
+		candidates = make([]uuid.UUID, 0, len(repository.searchTerms[rarest]))
+		for tweetID := range repository.searchTerms[rarest] {
+			candidates = append(candidates, tweetID)
+		}
+	}
+
+	now := repository.Clock.Now()
+	found := make([]models.Tweet, 0, len(candidates))
+
+	for _, tweetID := range candidates {
+		stored, ok := repository.tweets[tweetID]
+		if ok && !now.Before(stored.expiresAt) {
+			repository.unindexSearchTerms(tweetID, models.SearchTerms(stored.tweet.Body))
+			continue
+		}
+
+		if !ok || (userID != 0 && stored.tweet.UserID != userID) || stored.tweet.Timestamp.Before(since) {
+			continue
+		}
+
+		hasTerms := !slices.ContainsFunc(terms, func(term string) bool {
+			_, ok := repository.searchTerms[term][tweetID]
+			return !ok
+		})
+		if hasTerms {
+			found = append(found, stored.tweet)
+		}
+	}
+
+	slices.SortFunc(found, func(a, b models.Tweet) int {
+		if order := b.Timestamp.Compare(a.Timestamp); order != 0 {
+			return order
+		}
+
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+
+	tweetIDs := make([]uuid.UUID, 0, min(len(found), MaxSearchResults))
+	for _, tweet := range found[:min(len(found), MaxSearchResults)] {
+		tweetIDs = append(tweetIDs, tweet.ID)
+	}
+
+	return tweetIDs, nil
+}
+
+// GetTweetHistory returns the previous revisions of a tweet, oldest first
+func (repository *MemoryRepository) GetTweetHistory(tweetID uuid.UUID) ([]models.TweetRevision, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	return slices.Clone(repository.tweetHistories[tweetID]), nil
+}
+
+// AddTweetToTimeline adds a tweetID to the user's timeline if it isn't there yet, keeping the latest TimelineSize.
+func (repository *MemoryRepository) AddTweetToTimeline(tweetID uuid.UUID, userID uint) error {
+	return repository.AddTweetToTimelines(tweetID, []uint{userID})
+}
+
+// AddTweetToTimelines adds a tweetID to the timeline of every user in userIDs that doesn't have it yet, at once.
+// Each keeps the latest TimelineSize.
+func (repository *MemoryRepository) AddTweetToTimelines(tweetID uuid.UUID, userIDs []uint) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	for _, userID := range userIDs {
+		if slices.Contains(repository.timelines[userID], tweetID) {
+			continue
+		}
+
+		timeline := append([]uuid.UUID{tweetID}, repository.timelines[userID]...)
+		repository.timelines[userID] = repository.capTimeline(timeline)
+	}
+
+	return nil
+}
+
+// GetTimeLine returns the list of tweets ids in a user timeline
+func (repository *MemoryRepository) GetTimeLine(userID uint) ([]uuid.UUID, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	ids := make([]uuid.UUID, len(repository.timelines[userID]))
+	copy(ids, repository.timelines[userID])
+
+	return ids, nil
+}
+
+// RemoveFromTimeline removes the tweetIDs from the user's timeline
+func (repository *MemoryRepository) RemoveFromTimeline(userID uint, tweetIDs []uuid.UUID) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	repository.timelines[userID] = slices.DeleteFunc(repository.timelines[userID], func(id uuid.UUID) bool {
+		return slices.Contains(tweetIDs, id)
+	})
+
+	return nil
+}
+
+// RemoveTweetFromTimelines removes the tweetID from the timeline of every user in userIDs at once
+func (repository *MemoryRepository) RemoveTweetFromTimelines(tweetID uuid.UUID, userIDs []uint) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	isTweet := func(id uuid.UUID) bool { return id == tweetID }
+
+	for _, userID := range userIDs {
+		repository.timelines[userID] = slices.DeleteFunc(repository.timelines[userID], isTweet)
+	}
+
+	return nil
+}
+
+// ReplaceTimeline replaces the user's timeline with the first TimelineSize tweetIDs if it still is current.
+// Returns false if it changed meanwhile.
+func (repository *MemoryRepository) ReplaceTimeline(userID uint, current, tweetIDs []uuid.UUID) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	if !slices.Equal(repository.timelines[userID], current) {
+		return false, nil
+	}
+
+	repository.timelines[userID] = slices.Clone(repository.capTimeline(tweetIDs))
+
+	return true, nil
+}
+
+// CreateSession stores a new session for SessionTTL
+func (repository *MemoryRepository) CreateSession(session models.Session) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	repository.sessions[session.ID] = expiringSession{
+		session:   session,
+		expiresAt: repository.Clock.Now().Add(repository.sessionTTL()),
+	}
+
+	return nil
+}
+
+// GetSession returns the session by id, or false if it doesn't exist or expired
+func (repository *MemoryRepository) GetSession(sessionID string) (models.Session, bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	stored, ok := repository.sessions[sessionID]
+	if !ok {
+		return models.Session{}, false, nil
+	}
+
+	if !repository.Clock.Now().Before(stored.expiresAt) {
+		delete(repository.sessions, sessionID)
+		return models.Session{}, false, nil
+	}
+
+	return stored.session, true, nil
+}
+
+// RefreshSession stores the session again and restarts its SessionTTL, if it wasn't deleted
+func (repository *MemoryRepository) RefreshSession(session models.Session) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	if _, ok := repository.sessions[session.ID]; !ok {
+		return nil
+	}
+
+	repository.sessions[session.ID] = expiringSession{
+		session:   session,
+		expiresAt: repository.Clock.Now().Add(repository.sessionTTL()),
+	}
+
+	return nil
+}
+
+// GetSessions returns the active sessions of userID
+func (repository *MemoryRepository) GetSessions(userID uint) ([]models.Session, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	now := repository.Clock.Now()
+	sessions := make([]models.Session, 0)
+
+	for id, stored := range repository.sessions {
+		if !now.Before(stored.expiresAt) {
+			delete(repository.sessions, id)
+			continue
+		}
+
+		if stored.session.UserID == userID {
+			sessions = append(sessions, stored.session)
+		}
+	}
+
+	sortSessions(sessions)
+
+	return sessions, nil
+}
+
+// DeleteSessions deletes the sessions of userID by ids
+func (repository *MemoryRepository) DeleteSessions(userID uint, sessionIDs []string) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	for _, sessionID := range sessionIDs {
+		if stored, ok := repository.sessions[sessionID]; ok && stored.session.UserID == userID {
+			delete(repository.sessions, sessionID)
+		}
+	}
+
+	return nil
+}
+
+// CreateUser stores a new user and returns its id. Returns ErrHandleTaken if the handle is in use, ignoring case.
+func (repository *MemoryRepository) CreateUser(user models.User) (uint, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	handle := strings.ToLower(user.Handle)
+	if _, ok := repository.handles[handle]; ok {
+		return 0, ErrHandleTaken
+	}
+
+	if _, ok := repository.getHandleRedirect(handle); ok {
+		return 0, ErrHandleTaken
+	}
+
+	repository.lastUserID++
+	user.ID = repository.lastUserID
+
+	repository.users[user.ID] = user
+	repository.handles[handle] = user.ID
+
+	return user.ID, nil
+}
+
+// GetUser returns the user by id, or false if it doesn't exist
+func (repository *MemoryRepository) GetUser(userID uint) (models.User, bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	user, ok := repository.users[userID]
+
+	return user, ok, nil
+}
+
+// GetUserByHandle returns the user by handle ignoring case, or false if it doesn't exist
+func (repository *MemoryRepository) GetUserByHandle(handle string) (models.User, bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	userID, ok := repository.handles[strings.ToLower(handle)]
+	if !ok {
+		return models.User{}, false, nil
+	}
+
+	return repository.users[userID], true, nil
+}
+
+// UpdateProfile replaces the profile of userID
+func (repository *MemoryRepository) UpdateProfile(userID uint, profile models.Profile) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	user, ok := repository.users[userID]
+	if ok {
+		user.Profile = profile
+		repository.users[userID] = user
+	}
+
+	return nil
+}
+
+// RenameHandle replaces the handle of userID, keeping the previous one redirecting to userID for redirectFor.
+// Returns ErrHandleTaken if handle is in use by another user, ignoring case, or still redirects to another user.
+func (repository *MemoryRepository) RenameHandle(userID uint, handle string, redirectFor time.Duration) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	user, ok := repository.users[userID]
+	if !ok {
+		return nil
+	}
+
+	lowerHandle := strings.ToLower(handle)
+	if ownerID, ok := repository.handles[lowerHandle]; ok && ownerID != userID {
+		return ErrHandleTaken
+	}
+
+	if ownerID, ok := repository.getHandleRedirect(lowerHandle); ok && ownerID != userID {
+		return ErrHandleTaken
+	}
+
+	previous := strings.ToLower(user.Handle)
+	user.Handle = handle
+	repository.users[userID] = user
+
+	delete(repository.handleRedirects, lowerHandle)
+	repository.handles[lowerHandle] = userID
+
+	if previous != lowerHandle {
+		delete(repository.handles, previous)
+		repository.handleRedirects[previous] = expiringUserID{
+			userID:    userID,
+			expiresAt: repository.Clock.Now().Add(redirectFor),
+		}
+	}
+
+	return nil
+}
+
+// GetHandleRedirect returns the user that renamed handle, ignoring case, while it still redirects, or false
+func (repository *MemoryRepository) GetHandleRedirect(handle string) (uint, bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	userID, ok := repository.getHandleRedirect(strings.ToLower(handle))
+
+	return userID, ok, nil
+}
+
+// getHandleRedirect returns the user that renamed the lowercase handle while it still redirects, or false.
+// It must be called holding the mutex.
+func (repository *MemoryRepository) getHandleRedirect(handle string) (uint, bool) {
+	redirect, ok := repository.handleRedirects[handle]
+	if !ok || !repository.Clock.Now().Before(redirect.expiresAt) {
+		return 0, false
+	}
+
+	return redirect.userID, true
+}
+
+// UpdatePassword replaces the password hash of userID
+func (repository *MemoryRepository) UpdatePassword(userID uint, passwordHash []byte) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	user, ok := repository.users[userID]
+	if !ok {
+		return nil
+	}
+
+	user.PasswordHash = passwordHash
+	repository.users[userID] = user
+
+	return nil
+}
+
+// GetFailedLogins returns the failed login attempts of userID since the first one, up to FailedLoginsTTL ago
+func (repository *MemoryRepository) GetFailedLogins(userID uint) (int64, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	return repository.failedLoginsOf(userID).count, nil
+}
+
+// AddFailedLogin records a failed login attempt of userID and returns the attempts like GetFailedLogins
+func (repository *MemoryRepository) AddFailedLogin(userID uint) (int64, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	counter := repository.failedLoginsOf(userID)
+	if counter.count == 0 {
+		counter.expiresAt = repository.Clock.Now().Add(FailedLoginsTTL)
+	}
+	counter.count++
+
+	repository.failedLogins[userID] = counter
+
+	return counter.count, nil
+}
+
+// ResetFailedLogins forgets the failed login attempts of userID
+func (repository *MemoryRepository) ResetFailedLogins(userID uint) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	delete(repository.failedLogins, userID)
+
+	return nil
+}
+
+// CreatePasswordReset stores the id of a password reset token of userID for PasswordResetTTL
+func (repository *MemoryRepository) CreatePasswordReset(tokenID string, userID uint) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	repository.passwordResets[tokenID] = expiringUserID{
+		userID:    userID,
+		expiresAt: repository.Clock.Now().Add(PasswordResetTTL),
+	}
+
+	return nil
+}
+
+// ConsumePasswordReset deletes a password reset token id and returns its user, or false if it doesn't exist or expired
+func (repository *MemoryRepository) ConsumePasswordReset(tokenID string) (uint, bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	reset, ok := repository.passwordResets[tokenID]
+	if !ok {
+		return 0, false, nil
+	}
+
+	delete(repository.passwordResets, tokenID)
+
+	if !repository.Clock.Now().Before(reset.expiresAt) {
+		return 0, false, nil
+	}
+
+	return reset.userID, true, nil
+}
+
+// AddNotification adds notification to the inbox of its user, keeping the latest MaxNotifications
+func (repository *MemoryRepository) AddNotification(notification models.Notification) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	notifications := append([]models.Notification{notification}, repository.notifications[notification.UserID]...)
+	if len(notifications) > MaxNotifications {
+		notifications = notifications[:MaxNotifications]
+	}
+	repository.notifications[notification.UserID] = notifications
+
+	return nil
+}
+
+// GetNotifications returns the latest MaxNotifications notifications of the inbox of userID, newest first
+func (repository *MemoryRepository) GetNotifications(userID uint) ([]models.Notification, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	return slices.Clone(repository.notifications[userID]), nil
+}
+
+// GetNotificationsReadAt returns the time until which userID read its notifications, zero if it never did
+func (repository *MemoryRepository) GetNotificationsReadAt(userID uint) (time.Time, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	return repository.notificationsReadAt[userID], nil
+}
+
+// SetNotificationsReadAt replaces the time until which userID read its notifications
+func (repository *MemoryRepository) SetNotificationsReadAt(userID uint, readAt time.Time) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	repository.notificationsReadAt[userID] = readAt
+
+	return nil
+}
+
+// failedLoginsOf returns the failed logins counter of userID, dropping it if expired. The mutex must be held.
+func (repository *MemoryRepository) failedLoginsOf(userID uint) expiringCounter {
+	counter, ok := repository.failedLogins[userID]
+	if ok && !repository.Clock.Now().Before(counter.expiresAt) {
+		delete(repository.failedLogins, userID)
+		return expiringCounter{}
+	}
+
+	return counter
+}
+
+// addToSet adds id to the set of key, returning false if it already was there
+func addToSet[K comparable](sets map[K]map[uint]struct{}, key K, id uint) bool {
+	set, ok := sets[key]
+	if !ok {
+		set = make(map[uint]struct{})
+		sets[key] = set
+	}
+
+	if _, ok := set[id]; ok {
+		return false
+	}
+	set[id] = struct{}{}
+
+	return true
+}
+
+// removeFromSet removes id from the set of key, returning false if it wasn't there
+func removeFromSet[K comparable](sets map[K]map[uint]struct{}, key K, id uint) bool {
+	if _, ok := sets[key][id]; !ok {
+		return false
+	}
+
+	delete(sets[key], id)
+	if len(sets[key]) == 0 {
+		delete(sets, key)
+	}
+
+	return true
+}
+
+func sortedSet(set map[uint]struct{}) []uint {
+	ids := make([]uint, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	return ids
+}
+
+// pageOf returns up to count of the sorted ids greater than cursor, and the last id returned if there are more
+func pageOf(ids []uint, cursor uint64, count int64) ([]uint, uint64) {
+	start, _ := slices.BinarySearch(ids, uint(cursor)+1)
+
+	return nextPage(ids[start:], count)
+}
@@ -0,0 +1,33 @@
+CREATE TABLE users (
+    id         BIGINT PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE follows (
+    user_id     BIGINT NOT NULL REFERENCES users (id),
+    follower_id BIGINT NOT NULL REFERENCES users (id),
+    PRIMARY KEY (user_id, follower_id)
+);
+
+CREATE TABLE tweets (
+    id         UUID PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id),
+    created_at TIMESTAMPTZ NOT NULL,
+    body       TEXT NOT NULL
+);
+
+CREATE INDEX tweets_user_id_idx ON tweets (user_id, created_at);
+
+CREATE TABLE timeline_entries (
+    id       BIGSERIAL PRIMARY KEY,
+    user_id  BIGINT NOT NULL,
+    tweet_id UUID NOT NULL REFERENCES tweets (id)
+);
+
+CREATE INDEX timeline_entries_user_id_idx ON timeline_entries (user_id, id);
+
+CREATE TABLE sessions (
+    name       VARCHAR(64) PRIMARY KEY,
+    user_id    BIGINT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,10 @@
+DROP TABLE sessions;
+
+CREATE TABLE sessions (
+    id         VARCHAR(64) PRIMARY KEY,
+    user_id    BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
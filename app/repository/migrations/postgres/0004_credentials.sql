@@ -0,0 +1,23 @@
+ALTER TABLE users ADD COLUMN handle VARCHAR(15);
+
+ALTER TABLE users ADD COLUMN password_hash BYTEA;
+
+CREATE UNIQUE INDEX users_handle_idx ON users (LOWER(handle));
+
+CREATE SEQUENCE users_id_seq OWNED BY users.id;
+
+SELECT setval('users_id_seq', COALESCE((SELECT MAX(id) FROM users), 0) + 1, false);
+
+ALTER TABLE users ALTER COLUMN id SET DEFAULT nextval('users_id_seq');
+
+CREATE TABLE failed_logins (
+    user_id    BIGINT PRIMARY KEY,
+    attempts   BIGINT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE password_resets (
+    id         VARCHAR(64) PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id),
+    expires_at TIMESTAMPTZ NOT NULL
+);
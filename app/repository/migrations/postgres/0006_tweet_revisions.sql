@@ -0,0 +1,10 @@
+ALTER TABLE tweets ADD COLUMN edited_at TIMESTAMPTZ;
+
+CREATE TABLE tweet_revisions (
+    id         BIGSERIAL PRIMARY KEY,
+    tweet_id   UUID NOT NULL REFERENCES tweets (id),
+    body       TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX tweet_revisions_tweet_id_idx ON tweet_revisions (tweet_id, id);
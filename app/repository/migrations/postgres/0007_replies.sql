@@ -0,0 +1,9 @@
+ALTER TABLE tweets ADD COLUMN in_reply_to UUID;
+ALTER TABLE tweets ADD COLUMN conversation_id UUID;
+
+UPDATE tweets SET conversation_id = id;
+
+ALTER TABLE tweets ALTER COLUMN conversation_id SET NOT NULL;
+
+CREATE INDEX tweets_conversation_id_idx ON tweets (conversation_id, created_at);
+CREATE INDEX tweets_in_reply_to_idx ON tweets (in_reply_to);
@@ -0,0 +1,6 @@
+CREATE TABLE retweets (
+    tweet_id   UUID NOT NULL REFERENCES tweets (id),
+    user_id    BIGINT NOT NULL REFERENCES users (id),
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (tweet_id, user_id)
+);
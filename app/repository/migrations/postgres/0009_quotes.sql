@@ -0,0 +1 @@
+ALTER TABLE tweets ADD COLUMN quoted_tweet_id UUID;
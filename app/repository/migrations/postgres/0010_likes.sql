@@ -0,0 +1,8 @@
+CREATE TABLE likes (
+    tweet_id   UUID NOT NULL REFERENCES tweets (id),
+    user_id    BIGINT NOT NULL REFERENCES users (id),
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (tweet_id, user_id)
+);
+
+CREATE INDEX likes_user_id_idx ON likes (user_id, created_at);
@@ -0,0 +1,5 @@
+CREATE TABLE mentions (
+    tweet_id UUID NOT NULL REFERENCES tweets (id),
+    user_id  BIGINT NOT NULL REFERENCES users (id),
+    PRIMARY KEY (tweet_id, user_id)
+);
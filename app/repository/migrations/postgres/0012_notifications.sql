@@ -0,0 +1,15 @@
+CREATE TABLE notifications (
+    id         UUID PRIMARY KEY,
+    user_id    BIGINT NOT NULL,
+    type       VARCHAR(16) NOT NULL,
+    actor_id   BIGINT NOT NULL,
+    tweet_id   UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX notifications_user_id_idx ON notifications (user_id, created_at);
+
+CREATE TABLE notification_reads (
+    user_id BIGINT PRIMARY KEY,
+    read_at TIMESTAMPTZ NOT NULL
+);
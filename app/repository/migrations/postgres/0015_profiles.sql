@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN display_name VARCHAR(50) NOT NULL DEFAULT '';
+
+ALTER TABLE users ADD COLUMN bio VARCHAR(160) NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+CREATE TABLE handle_redirects (
+    handle     VARCHAR(15) PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id),
+    expires_at TIMESTAMPTZ NOT NULL
+);
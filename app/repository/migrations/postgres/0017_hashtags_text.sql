@@ -0,0 +1 @@
+ALTER TABLE hashtags ALTER COLUMN hashtag TYPE TEXT;
@@ -0,0 +1,33 @@
+CREATE TABLE users (
+    id         INTEGER PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE follows (
+    user_id     INTEGER NOT NULL REFERENCES users (id),
+    follower_id INTEGER NOT NULL REFERENCES users (id),
+    PRIMARY KEY (user_id, follower_id)
+);
+
+CREATE TABLE tweets (
+    id         VARCHAR(36) PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users (id),
+    created_at TIMESTAMP NOT NULL,
+    body       TEXT NOT NULL
+);
+
+CREATE INDEX tweets_user_id_idx ON tweets (user_id, created_at);
+
+CREATE TABLE timeline_entries (
+    id       INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id  INTEGER NOT NULL,
+    tweet_id VARCHAR(36) NOT NULL REFERENCES tweets (id)
+);
+
+CREATE INDEX timeline_entries_user_id_idx ON timeline_entries (user_id, id);
+
+CREATE TABLE sessions (
+    name       VARCHAR(64) PRIMARY KEY,
+    user_id    INTEGER NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
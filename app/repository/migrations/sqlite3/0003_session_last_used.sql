@@ -0,0 +1,3 @@
+ALTER TABLE sessions ADD COLUMN last_used_at TIMESTAMP;
+
+UPDATE sessions SET last_used_at = created_at;
@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN handle VARCHAR(15);
+
+ALTER TABLE users ADD COLUMN password_hash BLOB;
+
+CREATE UNIQUE INDEX users_handle_idx ON users (LOWER(handle));
+
+CREATE TABLE failed_logins (
+    user_id    INTEGER PRIMARY KEY,
+    attempts   INTEGER NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE password_resets (
+    id         VARCHAR(64) PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users (id),
+    expires_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1 @@
+CREATE INDEX follows_follower_id_idx ON follows (follower_id, user_id);
@@ -0,0 +1,10 @@
+ALTER TABLE tweets ADD COLUMN edited_at TIMESTAMP;
+
+CREATE TABLE tweet_revisions (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    tweet_id   VARCHAR(36) NOT NULL REFERENCES tweets (id),
+    body       TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX tweet_revisions_tweet_id_idx ON tweet_revisions (tweet_id, id);
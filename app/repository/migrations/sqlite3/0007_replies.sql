@@ -0,0 +1,7 @@
+ALTER TABLE tweets ADD COLUMN in_reply_to VARCHAR(36);
+ALTER TABLE tweets ADD COLUMN conversation_id VARCHAR(36);
+
+UPDATE tweets SET conversation_id = id;
+
+CREATE INDEX tweets_conversation_id_idx ON tweets (conversation_id, created_at);
+CREATE INDEX tweets_in_reply_to_idx ON tweets (in_reply_to);
@@ -0,0 +1,6 @@
+CREATE TABLE retweets (
+    tweet_id   VARCHAR(36) NOT NULL REFERENCES tweets (id),
+    user_id    INTEGER NOT NULL REFERENCES users (id),
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (tweet_id, user_id)
+);
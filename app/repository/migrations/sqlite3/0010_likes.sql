@@ -0,0 +1,8 @@
+CREATE TABLE likes (
+    tweet_id   VARCHAR(36) NOT NULL REFERENCES tweets (id),
+    user_id    INTEGER NOT NULL REFERENCES users (id),
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (tweet_id, user_id)
+);
+
+CREATE INDEX likes_user_id_idx ON likes (user_id, created_at);
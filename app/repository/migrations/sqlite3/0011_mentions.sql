@@ -0,0 +1,5 @@
+CREATE TABLE mentions (
+    tweet_id VARCHAR(36) NOT NULL REFERENCES tweets (id),
+    user_id  INTEGER NOT NULL REFERENCES users (id),
+    PRIMARY KEY (tweet_id, user_id)
+);
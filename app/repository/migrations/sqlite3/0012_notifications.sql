@@ -0,0 +1,15 @@
+CREATE TABLE notifications (
+    id         VARCHAR(36) PRIMARY KEY,
+    user_id    INTEGER NOT NULL,
+    type       VARCHAR(16) NOT NULL,
+    actor_id   INTEGER NOT NULL,
+    tweet_id   VARCHAR(36) NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX notifications_user_id_idx ON notifications (user_id, created_at);
+
+CREATE TABLE notification_reads (
+    user_id INTEGER PRIMARY KEY,
+    read_at TIMESTAMP NOT NULL
+);
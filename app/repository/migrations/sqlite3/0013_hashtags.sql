@@ -0,0 +1,9 @@
+CREATE TABLE hashtags (
+    hashtag    VARCHAR(150) NOT NULL,
+    tweet_id   VARCHAR(36) NOT NULL REFERENCES tweets (id),
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (hashtag, tweet_id)
+);
+
+CREATE INDEX hashtags_hashtag_idx ON hashtags (hashtag, created_at);
+CREATE INDEX hashtags_created_at_idx ON hashtags (created_at);
@@ -0,0 +1,8 @@
+CREATE TABLE search_terms (
+    term     VARCHAR(200) NOT NULL,
+    tweet_id VARCHAR(36) NOT NULL REFERENCES tweets (id),
+    PRIMARY KEY (term, tweet_id)
+);
+
+CREATE INDEX search_terms_tweet_id_idx ON search_terms (tweet_id);
+CREATE INDEX tweets_created_at_idx ON tweets (created_at);
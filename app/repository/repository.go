@@ -3,8 +3,11 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/PatricioYegros/uala_challenge/app/models"
@@ -16,48 +19,407 @@ import (
 type IRepository interface {
 	// AddFollower adds the newFollowerID to the list of followers of userID
 	AddFollower(userID, newFollowerID uint) error
+	//RemoveFollower removes followerID from the list of followers of userID. Returns false if it wasn't following.
+	RemoveFollower(userID, followerID uint) (bool, error)
 	//GetFollowers returns the list of followers of userID
 	GetFollowers(userID uint) ([]uint, error)
+	//GetFollowing returns the list of users followed by userID
+	GetFollowing(userID uint) ([]uint, error)
+	//ScanFollowers returns a page of about count followers of userID starting at cursor, and the cursor of the next page.
+	//The first page is at cursor 0 and a next cursor of 0 means there are no more pages.
+	ScanFollowers(userID uint, cursor uint64, count int64) ([]uint, uint64, error)
+	//ScanFollowing returns a page of the users followed by userID like ScanFollowers
+	ScanFollowing(userID uint, cursor uint64, count int64) ([]uint, uint64, error)
+	//CountFollowers returns the amount of followers of userID
+	CountFollowers(userID uint) (int64, error)
+	//CountFollowing returns the amount of users followed by userID
+	CountFollowing(userID uint) (int64, error)
+	//CountFollowersOf returns the amount of followers of every user in userIDs, in the same order
+	CountFollowersOf(userIDs []uint) ([]int64, error)
 	//CreateTweet creates a new tweet and returns the uuid
 	CreateTweet(tweet models.Tweet) (uuid.UUID, error)
-	//GetTweets returns the list of tweets by ids
-	GetTweets(ids []uuid.UUID) ([]models.Tweet, error)
-	//AddTweetToTimeline adds a tweetID to the user's timeline. Max 10 tweets.
+	//GetUserTweets returns the ids of the latest count tweets of userID, newest first. Ids of expired tweets may be included.
+	GetUserTweets(userID uint, count int64) ([]uuid.UUID, error)
+	//GetTweets returns the tweets found by ids with their counts and quoted tweets, in the same order, and the ids that don't exist anymore
+	GetTweets(ids []uuid.UUID) ([]models.Tweet, []uuid.UUID, error)
+	//GetTweetCounts returns the counts of every tweet in tweetIDs, in the same order
+	GetTweetCounts(tweetIDs []uuid.UUID) ([]models.TweetCounts, error)
+	//AddRetweet records that userID retweeted tweetID. Returns false if it was already retweeted.
+	AddRetweet(userID uint, tweetID uuid.UUID) (bool, error)
+	//RemoveRetweet removes the retweet of tweetID by userID. Returns false if it wasn't retweeted.
+	RemoveRetweet(userID uint, tweetID uuid.UUID) (bool, error)
+	//GetRetweeters returns the users that retweeted every tweet in tweetIDs, sorted by id, in the same order
+	GetRetweeters(tweetIDs []uuid.UUID) ([][]uint, error)
+	//AddLike records that userID likes tweetID. Returns false if it was already liked.
+	AddLike(userID uint, tweetID uuid.UUID) (bool, error)
+	//RemoveLike removes the like of userID to tweetID. Returns false if it wasn't liked.
+	RemoveLike(userID uint, tweetID uuid.UUID) (bool, error)
+	//GetLikedBy tells if userID likes every tweet in tweetIDs, in the same order
+	GetLikedBy(userID uint, tweetIDs []uuid.UUID) ([]bool, error)
+	//GetUserLikes returns the ids of the latest MaxUserLikes tweets liked by userID, newest like first.
+	//Ids of expired tweets may be included.
+	GetUserLikes(userID uint) ([]uuid.UUID, error)
+	//GetConversation returns the ids of the replies of a conversation, oldest first. Ids of expired tweets may be included.
+	GetConversation(conversationID uuid.UUID) ([]uuid.UUID, error)
+	//DeleteTweet deletes the tweet and removes it from the timelines of timelineUserIDs, from the tweets of its hashtags
+	//and from the search index.
+	//Returns false if it didn't exist.
+	DeleteTweet(tweet models.Tweet, timelineUserIDs []uint) (bool, error)
+	//EditTweet replaces the body, mentions and hashtags of the tweet edited.ID of edited.UserID with the ones of edited,
+	//edited at edited.EditedAt, keeping the previous revision in its history. Returns false if it doesn't exist.
+	EditTweet(edited models.Tweet) (bool, error)
+	//GetHashtagTweets returns the ids of the latest MaxHashtagTweets tweets with hashtag, newest first.
+	//Ids of expired tweets may be included.
+	GetHashtagTweets(hashtag string) ([]uuid.UUID, error)
+	//GetHashtagUses returns the timestamps of the tweets of every hashtag used since since, by hashtag
+	GetHashtagUses(since time.Time) (map[string][]time.Time, error)
+	//SearchTweets returns the ids of the latest MaxSearchResults tweets with every term in terms, of userID unless it's 0,
+	//created since since, newest first. Terms are the ones of models.SearchTerms. Ids of expired tweets may be included.
+	SearchTweets(terms []string, userID uint, since time.Time) ([]uuid.UUID, error)
+	//GetTweetHistory returns the previous revisions of a tweet, oldest first
+	GetTweetHistory(tweetID uuid.UUID) ([]models.TweetRevision, error)
+	//AddTweetToTimeline adds a tweetID to the user's timeline if it isn't there yet, keeping the latest TimelineSize.
 	AddTweetToTimeline(tweetID uuid.UUID, userID uint) error
+	//AddTweetToTimelines adds a tweetID to the timeline of every user in userIDs that doesn't have it yet, at once.
+	//Each keeps the latest TimelineSize.
+	AddTweetToTimelines(tweetID uuid.UUID, userIDs []uint) error
 	//GetTimeLine returns the list of tweets ids in a user timeline
 	GetTimeLine(userID uint) ([]uuid.UUID, error)
-	//Login logs the user in the app
-	Login(userID uint) error
-	//CheckUserLog checks if the user logged is the user who wants to make the action
-	CheckUserLog(userID uint) (bool, error)
+	//RemoveFromTimeline removes the tweetIDs from the user's timeline
+	RemoveFromTimeline(userID uint, tweetIDs []uuid.UUID) error
+	//RemoveTweetFromTimelines removes the tweetID from the timeline of every user in userIDs at once
+	RemoveTweetFromTimelines(tweetID uuid.UUID, userIDs []uint) error
+	//ReplaceTimeline replaces the user's timeline with the first TimelineSize tweetIDs if it still is current.
+	//Returns false if it changed meanwhile.
+	ReplaceTimeline(userID uint, current, tweetIDs []uuid.UUID) (bool, error)
+	//CreateSession stores a new session for SessionTTL
+	CreateSession(session models.Session) error
+	//GetSession returns the session by id, or false if it doesn't exist or expired
+	GetSession(sessionID string) (models.Session, bool, error)
+	//RefreshSession stores the session again and restarts its SessionTTL, if it wasn't deleted
+	RefreshSession(session models.Session) error
+	//GetSessions returns the active sessions of userID
+	GetSessions(userID uint) ([]models.Session, error)
+	//DeleteSessions deletes the sessions of userID by ids
+	DeleteSessions(userID uint, sessionIDs []string) error
+	//CreateUser stores a new user and returns its id. Returns ErrHandleTaken if the handle is in use, ignoring case,
+	//or still redirects to the user that renamed it.
+	CreateUser(user models.User) (uint, error)
+	//GetUser returns the user by id, or false if it doesn't exist
+	GetUser(userID uint) (models.User, bool, error)
+	//GetUserByHandle returns the user by handle ignoring case, or false if it doesn't exist
+	GetUserByHandle(handle string) (models.User, bool, error)
+	//UpdatePassword replaces the password hash of userID
+	UpdatePassword(userID uint, passwordHash []byte) error
+	//UpdateProfile replaces the profile of userID
+	UpdateProfile(userID uint, profile models.Profile) error
+	//RenameHandle replaces the handle of userID, keeping the previous one redirecting to userID for redirectFor.
+	//Returns ErrHandleTaken if handle is in use by another user, ignoring case, or still redirects to another user.
+	RenameHandle(userID uint, handle string, redirectFor time.Duration) error
+	//GetHandleRedirect returns the user that renamed handle, ignoring case, while it still redirects, or false
+	GetHandleRedirect(handle string) (uint, bool, error)
+	//GetFailedLogins returns the failed login attempts of userID since the first one, up to FailedLoginsTTL ago
+	GetFailedLogins(userID uint) (int64, error)
+	//AddFailedLogin records a failed login attempt of userID and returns the attempts like GetFailedLogins
+	AddFailedLogin(userID uint) (int64, error)
+	//ResetFailedLogins forgets the failed login attempts of userID
+	ResetFailedLogins(userID uint) error
+	//CreatePasswordReset stores the id of a password reset token of userID for PasswordResetTTL
+	CreatePasswordReset(tokenID string, userID uint) error
+	//ConsumePasswordReset deletes a password reset token id and returns its user, or false if it doesn't exist or expired
+	ConsumePasswordReset(tokenID string) (uint, bool, error)
+	//AddNotification adds notification to the inbox of its user, keeping the latest MaxNotifications
+	AddNotification(notification models.Notification) error
+	//GetNotifications returns the latest MaxNotifications notifications of the inbox of userID, newest first
+	GetNotifications(userID uint) ([]models.Notification, error)
+	//GetNotificationsReadAt returns the time until which userID read its notifications, zero if it never did
+	GetNotificationsReadAt(userID uint) (time.Time, error)
+	//SetNotificationsReadAt replaces the time until which userID read its notifications
+	SetNotificationsReadAt(userID uint, readAt time.Time) error
 }
 
 type Repository struct {
 	Redis *redis.Client
+	Limits
+}
+
+// Limits are the sizes and TTLs of the stored data, every zero value uses its default
+type Limits struct {
+	// TweetTTL is how long tweets are kept by the backends where they expire, DefaultTweetTTL if not set
+	TweetTTL time.Duration
+	// SessionTTL is how long a session lasts since its last use, DefaultSessionTTL if not set
+	SessionTTL time.Duration
+	// TimelineSize is the amount of tweets kept in every timeline, DefaultTimelineSize if not set
+	TimelineSize int
 }
 
 const (
-	TweetTTL            = 24 * time.Hour
-	SessionTTL          = 24 * time.Hour
-	MaxTweetsInTimeline = 10
+	DefaultTweetTTL     = 24 * time.Hour
+	DefaultSessionTTL   = 24 * time.Hour
+	FailedLoginsTTL     = 15 * time.Minute
+	PasswordResetTTL    = time.Hour
+	DefaultTimelineSize = 800
+	MaxUserTweets       = 100
+	MaxUserLikes        = 800
+	MaxNotifications    = 800
+	MaxHashtagTweets    = 800
+	MaxSearchResults    = 800
 )
 
-// AddFollower adds the newFollowerID to the list of followers of userID
+// replyCountField is the field of the replies in the hash of counts of a tweet
+const replyCountField = "replies"
+
+// addToTimelinesScript pushes ARGV[1] to the timelines in KEYS that don't have it, trimming them to ARGV[2] tweets
+var addToTimelinesScript = redis.NewScript(`
+for _, key in ipairs(KEYS) do
+	if not redis.call("LPOS", key, ARGV[1]) then
+		redis.call("LPUSH", key, ARGV[1])
+		redis.call("LTRIM", key, 0, tonumber(ARGV[2]) - 1)
+	end
+end
+return 0
+`)
+
+// addLikeScript adds ARGV[1] to the likers in KEYS[1], expiring them after ARGV[3] milliseconds, and pushes ARGV[2]
+// to the likes in KEYS[2], trimming them to ARGV[4] tweets. Returns 0 if ARGV[1] already liked it
+var addLikeScript = redis.NewScript(`
+if redis.call("SADD", KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call("PEXPIRE", KEYS[1], ARGV[3])
+redis.call("LPUSH", KEYS[2], ARGV[2])
+redis.call("LTRIM", KEYS[2], 0, tonumber(ARGV[4]) - 1)
+return 1
+`)
+
+const UserIDSequenceKey = "user-id-sequence"
+
+// watchAttempts bounds the retries of a transaction whose watched keys changed before it ran
+const watchAttempts = 5
+
+var ErrHandleTaken = errors.New("handle already taken")
+
+// AddFollower adds the newFollowerID to the list of followers of userID, and userID to the users followed by newFollowerID
 func (repository Repository) AddFollower(userID, newFollowerID uint) error {
-	followersKey := UserFollowersKey(userID)
+	_, err := repository.Redis.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.SAdd(context.Background(), UserFollowersKey(userID), newFollowerID)
+		pipe.SAdd(context.Background(), UserFollowingKey(newFollowerID), userID)
+		return nil
+	})
 
-	return repository.Redis.SAdd(context.Background(), followersKey, newFollowerID).Err()
+	return err
+}
+
+// RemoveFollower removes followerID from the list of followers of userID. Returns false if it wasn't following.
+func (repository Repository) RemoveFollower(userID, followerID uint) (bool, error) {
+	var removed *redis.IntCmd
+
+	_, err := repository.Redis.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		removed = pipe.SRem(context.Background(), UserFollowersKey(userID), followerID)
+		pipe.SRem(context.Background(), UserFollowingKey(followerID), userID)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return removed.Val() > 0, nil
 }
 
 // GetFollowers returns the list of followers of userID
 func (repository Repository) GetFollowers(userID uint) ([]uint, error) {
-	followersKey := UserFollowersKey(userID)
+	return repository.getUserIDs(UserFollowersKey(userID))
+}
+
+// GetFollowing returns the list of users followed by userID
+func (repository Repository) GetFollowing(userID uint) ([]uint, error) {
+	return repository.getUserIDs(UserFollowingKey(userID))
+}
+
+// ScanFollowers returns a page of about count followers of userID starting at cursor, and the cursor of the next page.
+// Pages come from SSCAN, so count is only a hint and small sets are returned at once.
+func (repository Repository) ScanFollowers(userID uint, cursor uint64, count int64) ([]uint, uint64, error) {
+	return repository.scanUserIDs(UserFollowersKey(userID), cursor, count)
+}
+
+// ScanFollowing returns a page of the users followed by userID like ScanFollowers
+func (repository Repository) ScanFollowing(userID uint, cursor uint64, count int64) ([]uint, uint64, error) {
+	return repository.scanUserIDs(UserFollowingKey(userID), cursor, count)
+}
+
+// CountFollowers returns the amount of followers of userID
+func (repository Repository) CountFollowers(userID uint) (int64, error) {
+	return repository.Redis.SCard(context.Background(), UserFollowersKey(userID)).Result()
+}
+
+// CountFollowing returns the amount of users followed by userID
+func (repository Repository) CountFollowing(userID uint) (int64, error) {
+	return repository.Redis.SCard(context.Background(), UserFollowingKey(userID)).Result()
+}
+
+// CountFollowersOf returns the amount of followers of every user in userIDs, in the same order, in a single round trip
+func (repository Repository) CountFollowersOf(userIDs []uint) ([]int64, error) {
+	counts := make([]int64, 0, len(userIDs))
+
+	if len(userIDs) == 0 {
+		return counts, nil
+	}
+
+	cmds, err := repository.Redis.Pipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		for _, userID := range userIDs {
+			pipe.SCard(context.Background(), UserFollowersKey(userID))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cmd := range cmds {
+		counts = append(counts, cmd.(*redis.IntCmd).Val())
+	}
 
-	idsString, err := repository.Redis.SMembers(context.Background(), followersKey).Result()
+	return counts, nil
+}
+
+func (repository Repository) getUserIDs(key string) ([]uint, error) {
+	idsString, err := repository.Redis.SMembers(context.Background(), key).Result()
 	if err != nil {
 		return nil, err
 	}
 
+	return parseUserIDs(idsString)
+}
+
+func (repository Repository) scanUserIDs(key string, cursor uint64, count int64) ([]uint, uint64, error) {
+	idsString, next, err := repository.Redis.SScan(context.Background(), key, cursor, "", count).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids, err := parseUserIDs(idsString)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return ids, next, nil
+}
+
+// withCounts sets the counts of tweets returned by getCounts
+func withCounts(tweets []models.Tweet, getCounts func(tweetIDs []uuid.UUID) ([]models.TweetCounts, error)) error {
+	if len(tweets) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(tweets))
+	for _, tweet := range tweets {
+		ids = append(ids, tweet.ID)
+	}
+
+	counts, err := getCounts(ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range tweets {
+		tweets[i].TweetCounts = counts[i]
+	}
+
+	return nil
+}
+
+// withQuotes sets the quoted tweet of every quote in tweets, as returned by getTweets, or marks it unavailable if it
+// doesn't exist anymore
+func withQuotes(tweets []models.Tweet, getTweets func(ids []uuid.UUID) ([]models.Tweet, []uuid.UUID, error)) error {
+	var ids []uuid.UUID
+	for _, tweet := range tweets {
+		if tweet.QuotedTweetID != nil && !slices.Contains(ids, *tweet.QuotedTweetID) {
+			ids = append(ids, *tweet.QuotedTweetID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	quoted, _, err := getTweets(ids)
+	if err != nil {
+		return err
+	}
+
+	found := make(map[uuid.UUID]models.Tweet, len(quoted))
+	for _, tweet := range quoted {
+		found[tweet.ID] = tweet
+	}
+
+	for i := range tweets {
+		if tweets[i].QuotedTweetID == nil {
+			continue
+		}
+
+		quotedTweet, ok := found[*tweets[i].QuotedTweetID]
+		tweets[i].QuotedTweet = nil
+		tweets[i].QuoteUnavailable = !ok
+		if ok {
+			tweets[i].QuotedTweet = &quotedTweet
+		}
+	}
+
+	return nil
+}
+
+// tweetTTL returns TweetTTL, or DefaultTweetTTL if it isn't set
+func (limits Limits) tweetTTL() time.Duration {
+	if limits.TweetTTL <= 0 {
+		return DefaultTweetTTL
+	}
+
+	return limits.TweetTTL
+}
+
+// sessionTTL returns SessionTTL, or DefaultSessionTTL if it isn't set
+func (limits Limits) sessionTTL() time.Duration {
+	if limits.SessionTTL <= 0 {
+		return DefaultSessionTTL
+	}
+
+	return limits.SessionTTL
+}
+
+// timelineSize returns TimelineSize, or DefaultTimelineSize if it isn't set
+func (limits Limits) timelineSize() int {
+	if limits.TimelineSize <= 0 {
+		return DefaultTimelineSize
+	}
+
+	return limits.TimelineSize
+}
+
+// capTimeline returns the first timelineSize() tweetIDs
+func (limits Limits) capTimeline(tweetIDs []uuid.UUID) []uuid.UUID {
+	if len(tweetIDs) > limits.timelineSize() {
+		return tweetIDs[:limits.timelineSize()]
+	}
+
+	return tweetIDs
+}
+
+func parseTweetIDs(idsString []string) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(idsString))
+
+	for _, idStr := range idsString {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func parseUserIDs(idsString []string) ([]uint, error) {
 	ids := make([]uint, 0, len(idsString))
 
 	for _, idStr := range idsString {
@@ -72,123 +434,1214 @@ func (repository Repository) GetFollowers(userID uint) ([]uint, error) {
 }
 
 // CreateTweet creates a new tweet and returns the uuid
+// The id is also pushed to the list of tweets of the user, that keeps the latest MaxUserTweets.
+// Replies are added to their conversation and counted in the tweet they reply to.
 func (repository Repository) CreateTweet(tweet models.Tweet) (uuid.UUID, error) {
-	tweetID := uuid.New()
-	tweetKey := TweetKey(tweetID)
+	tweet.ID = uuid.New()
+	if tweet.ConversationID == uuid.Nil {
+		tweet.ConversationID = tweet.ID
+	}
+
+	tweetKey := TweetKey(tweet.ID)
+	userTweetsKey := UserTweetsKey(tweet.UserID)
+
+	_, err := repository.Redis.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.Set(context.Background(), tweetKey, tweet, repository.tweetTTL())
+		pipe.LPush(context.Background(), userTweetsKey, tweet.ID.String())
+		pipe.LTrim(context.Background(), userTweetsKey, 0, MaxUserTweets-1)
+
+		if tweet.InReplyTo != nil {
+			conversationKey := ConversationKey(tweet.ConversationID)
+			countsKey := TweetCountsKey(*tweet.InReplyTo)
+
+			pipe.ZAdd(context.Background(), conversationKey, redis.Z{
+				Score:  float64(tweet.Timestamp.UnixMilli()),
+				Member: tweet.ID.String(),
+			})
+			pipe.Expire(context.Background(), conversationKey, repository.tweetTTL())
+			pipe.HIncrBy(context.Background(), countsKey, replyCountField, 1)
+			pipe.Expire(context.Background(), countsKey, repository.tweetTTL())
+		}
+
+		repository.indexHashtags(pipe, tweet, tweet.Hashtags)
+		repository.indexSearchTerms(pipe, tweet, models.SearchTerms(tweet.Body))
+		repository.indexSearch(pipe, UserSearchKey(tweet.UserID), tweet)
+
+		return nil
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return tweet.ID, nil
+}
+
+// indexHashtags adds tweet to the tweets of hashtags, scored by its timestamp, and registers them as used then.
+// The tweets older than TweetTTL are dropped, keeping the latest MaxHashtagTweets of each hashtag.
+func (repository Repository) indexHashtags(pipe redis.Pipeliner, tweet models.Tweet, hashtags []string) {
+	if len(hashtags) == 0 {
+		return
+	}
+
+	score := float64(tweet.Timestamp.UnixMilli())
+	expired := strconv.FormatInt(tweet.Timestamp.Add(-repository.tweetTTL()).UnixMilli(), 10)
+
+	for _, hashtag := range hashtags {
+		hashtagKey := HashtagKey(hashtag)
+
+		pipe.ZAdd(context.Background(), hashtagKey, redis.Z{Score: score, Member: tweet.ID.String()})
+		pipe.ZRemRangeByScore(context.Background(), hashtagKey, "-inf", "("+expired)
+		pipe.ZRemRangeByRank(context.Background(), hashtagKey, 0, -MaxHashtagTweets-1)
+		pipe.Expire(context.Background(), hashtagKey, repository.tweetTTL())
+		pipe.ZAddGT(context.Background(), HashtagsKey, redis.Z{Score: score, Member: hashtag})
+	}
+
+	pipe.ZRemRangeByScore(context.Background(), HashtagsKey, "-inf", "("+expired)
+}
+
+// unindexHashtags removes tweetID from the tweets of hashtags
+func unindexHashtags(pipe redis.Pipeliner, tweetID uuid.UUID, hashtags []string) {
+	for _, hashtag := range hashtags {
+		pipe.ZRem(context.Background(), HashtagKey(hashtag), tweetID.String())
+	}
+}
+
+// GetHashtagTweets returns the ids of the latest MaxHashtagTweets tweets with hashtag, newest first.
+// Ids of expired tweets may be included.
+func (repository Repository) GetHashtagTweets(hashtag string) ([]uuid.UUID, error) {
+	idsString, err := repository.Redis.ZRevRange(context.Background(), HashtagKey(hashtag), 0, MaxHashtagTweets-1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseTweetIDs(idsString)
+}
+
+// GetHashtagUses returns the timestamps, in milliseconds, of the tweets of every hashtag used since since, by hashtag
+func (repository Repository) GetHashtagUses(since time.Time) (map[string][]time.Time, error) {
+	min := strconv.FormatInt(since.UnixMilli(), 10)
+
+	hashtags, err := repository.Redis.ZRangeByScore(context.Background(), HashtagsKey, &redis.ZRangeBy{Min: min, Max: "+inf"}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	cmds := make([]*redis.ZSliceCmd, 0, len(hashtags))
+
+	_, err = repository.Redis.Pipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		for _, hashtag := range hashtags {
+			cmds = append(cmds, pipe.ZRangeByScoreWithScores(context.Background(), HashtagKey(hashtag), &redis.ZRangeBy{Min: min, Max: "+inf"}))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uses := make(map[string][]time.Time, len(hashtags))
+	for i, cmd := range cmds {
+		for _, use := range cmd.Val() {
+			uses[hashtags[i]] = append(uses[hashtags[i]], time.UnixMilli(int64(use.Score)))
+		}
+	}
+
+	return uses, nil
+}
+
+// indexSearchTerms adds tweet to the search index of every term in terms
+func (repository Repository) indexSearchTerms(pipe redis.Pipeliner, tweet models.Tweet, terms []string) {
+	for _, term := range terms {
+		repository.indexSearch(pipe, SearchTermKey(term), tweet)
+	}
+}
+
+// indexSearch adds tweet to the search index at key, scored by its timestamp. The tweets older than TweetTTL are
+// dropped and the whole index expires TweetTTL after its last tweet.
+func (repository Repository) indexSearch(pipe redis.Pipeliner, key string, tweet models.Tweet) {
+	expired := strconv.FormatInt(tweet.Timestamp.Add(-repository.tweetTTL()).UnixMilli(), 10)
+
+	pipe.ZAdd(context.Background(), key, redis.Z{Score: float64(tweet.Timestamp.UnixMilli()), Member: tweet.ID.String()})
+	pipe.ZRemRangeByScore(context.Background(), key, "-inf", "("+expired)
+	pipe.Expire(context.Background(), key, repository.tweetTTL())
+}
+
+// unindexSearchTerms removes tweetID from the search index of every term in terms
+func unindexSearchTerms(pipe redis.Pipeliner, tweetID uuid.UUID, terms []string) {
+	for _, term := range terms {
+		pipe.ZRem(context.Background(), SearchTermKey(term), tweetID.String())
+	}
+}
+
+// SearchTweets returns the ids of the latest MaxSearchResults tweets with every term in terms, of userID unless it's 0,
+// created since since, newest first. The indexes are intersected in a temporary key, deleted in the same transaction.
+// Ids of expired tweets may be included.
+func (repository Repository) SearchTweets(terms []string, userID uint, since time.Time) ([]uuid.UUID, error) {
+	keys := make([]string, 0, len(terms)+1)
+	for _, term := range terms {
+		keys = append(keys, SearchTermKey(term))
+	}
+
+	if userID != 0 {
+		keys = append(keys, UserSearchKey(userID))
+	}
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	resultsKey := SearchResultsKey(uuid.New())
+	var results *redis.StringSliceCmd
+
+	_, err := repository.Redis.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		//every index scores a tweet by its timestamp, MAX keeps it
+		pipe.ZInterStore(context.Background(), resultsKey, &redis.ZStore{Keys: keys, Aggregate: "MAX"})
+		results = pipe.ZRevRangeByScore(context.Background(), resultsKey, &redis.ZRangeBy{
+			Min:   strconv.FormatInt(since.UnixMilli(), 10),
+			Max:   "+inf",
+			Count: MaxSearchResults,
+		})
+		pipe.Del(context.Background(), resultsKey)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseTweetIDs(results.Val())
+}
+
+// GetUserTweets returns the ids of the latest count tweets of userID, newest first. Ids of expired tweets may be included.
+func (repository Repository) GetUserTweets(userID uint, count int64) ([]uuid.UUID, error) {
+	idsString, err := repository.Redis.LRange(context.Background(), UserTweetsKey(userID), 0, count-1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseTweetIDs(idsString)
+}
+
+// GetTweets returns the tweets found by ids with their counts and quoted tweets, in the same order, and the ids that
+// don't exist anymore
+func (repository Repository) GetTweets(ids []uuid.UUID) ([]models.Tweet, []uuid.UUID, error) {
+	tweets, missing, err := repository.getCountedTweets(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tweets, missing, withQuotes(tweets, repository.getCountedTweets)
+}
+
+// getCountedTweets returns the tweets found by ids with their counts, in the same order, and the ids that don't exist anymore
+func (repository Repository) getCountedTweets(ids []uuid.UUID) ([]models.Tweet, []uuid.UUID, error) {
+	tweets, missing, err := repository.getTweets(ids)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	return tweetID, repository.Redis.Set(context.Background(), tweetKey, tweet, TweetTTL).Err()
+	return tweets, missing, withCounts(tweets, repository.GetTweetCounts)
 }
 
-// GetTweets returns the list of tweets by ids
-func (repository Repository) GetTweets(ids []uuid.UUID) ([]models.Tweet, error) {
+// getTweets returns the tweets found by ids without their counts, in the same order, and the ids that don't exist anymore
+// All the tweets are fetched in a single MGET.
+func (repository Repository) getTweets(ids []uuid.UUID) ([]models.Tweet, []uuid.UUID, error) {
 	tweets := make([]models.Tweet, 0, len(ids))
+	var missing []uuid.UUID
 
+	if len(ids) == 0 {
+		return tweets, missing, nil
+	}
+
+	keys := make([]string, 0, len(ids))
 	for _, id := range ids {
-		tweetKey := TweetKey(id)
+		keys = append(keys, TweetKey(id))
+	}
+
+	values, err := repository.Redis.MGet(context.Background(), keys...).Result()
+	if err != nil {
+		return nil, nil, err
+	}
 
-		tweetString, err := repository.Redis.Get(context.Background(), tweetKey).Result()
-		if err == redis.Nil {
+	for i, value := range values {
+		tweetString, ok := value.(string)
+		if !ok {
 			//ttl reached
-			break
-		} else if err != nil {
-			return nil, err
+			missing = append(missing, ids[i])
+			continue
 		}
 
 		tweet := models.Tweet{}
 		err = json.Unmarshal([]byte(tweetString), &tweet)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
+		tweet.ID = ids[i]
 
 		tweets = append(tweets, tweet)
 	}
 
-	return tweets, nil
+	return tweets, missing, nil
 }
 
-// AddTweetToTimeline adds a tweetID to the user's timeline. Max 10 tweets.
-func (repository Repository) AddTweetToTimeline(tweetID uuid.UUID, userID uint) error {
-	timelineKey := TimelineKey(userID)
+// GetTweetCounts returns the counts of every tweet in tweetIDs, in the same order
+// The counts of all the tweets are fetched in a single round trip.
+func (repository Repository) GetTweetCounts(tweetIDs []uuid.UUID) ([]models.TweetCounts, error) {
+	cmds := make([]*redis.MapStringStringCmd, 0, len(tweetIDs))
+	likeCmds := make([]*redis.IntCmd, 0, len(tweetIDs))
+
+	_, err := repository.Redis.Pipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		for _, tweetID := range tweetIDs {
+			cmds = append(cmds, pipe.HGetAll(context.Background(), TweetCountsKey(tweetID)))
+			likeCmds = append(likeCmds, pipe.SCard(context.Background(), LikesKey(tweetID)))
+		}
 
-	timelineLen, err := repository.Redis.LLen(context.Background(), timelineKey).Result()
+		return nil
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if timelineLen == MaxTweetsInTimeline {
-		err := repository.Redis.RPop(context.Background(), timelineKey).Err()
-		if err != nil {
-			return err
+	counts := make([]models.TweetCounts, 0, len(tweetIDs))
+	for i, cmd := range cmds {
+		tweetCounts := models.TweetCounts{LikeCount: likeCmds[i].Val()}
+
+		if value, ok := cmd.Val()[replyCountField]; ok {
+			tweetCounts.ReplyCount, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, err
+			}
 		}
+
+		counts = append(counts, tweetCounts)
 	}
-	return repository.Redis.LPush(context.Background(), timelineKey, tweetID.String()).Err()
+
+	return counts, nil
 }
 
-// GetTimeLine returns the list of tweets ids in a user timeline
-func (repository Repository) GetTimeLine(userID uint) ([]uuid.UUID, error) {
-	timelineKey := TimelineKey(userID)
+// AddRetweet records that userID retweeted tweetID. Returns false if it was already retweeted.
+// The retweets of a tweet expire TweetTTL after the last one.
+func (repository Repository) AddRetweet(userID uint, tweetID uuid.UUID) (bool, error) {
+	var added *redis.IntCmd
+	retweetsKey := RetweetsKey(tweetID)
 
-	idsString, err := repository.Redis.LRange(context.Background(), timelineKey, 0, -1).Result()
+	_, err := repository.Redis.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		added = pipe.SAdd(context.Background(), retweetsKey, userID)
+		pipe.Expire(context.Background(), retweetsKey, repository.tweetTTL())
+
+		return nil
+	})
 	if err != nil {
-		return nil, err
+		return false, err
 	}
 
-	ids := make([]uuid.UUID, 0, len(idsString))
+	return added.Val() > 0, nil
+}
 
-	for _, idStr := range idsString {
-		id, err := uuid.Parse(idStr)
+// RemoveRetweet removes the retweet of tweetID by userID. Returns false if it wasn't retweeted.
+func (repository Repository) RemoveRetweet(userID uint, tweetID uuid.UUID) (bool, error) {
+	removed, err := repository.Redis.SRem(context.Background(), RetweetsKey(tweetID), userID).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return removed > 0, nil
+}
+
+// GetRetweeters returns the users that retweeted every tweet in tweetIDs, sorted by id, in the same order
+// The retweeters of all the tweets are fetched in a single round trip.
+func (repository Repository) GetRetweeters(tweetIDs []uuid.UUID) ([][]uint, error) {
+	cmds := make([]*redis.StringSliceCmd, 0, len(tweetIDs))
+
+	_, err := repository.Redis.Pipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		for _, tweetID := range tweetIDs {
+			cmds = append(cmds, pipe.SMembers(context.Background(), RetweetsKey(tweetID)))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	retweeters := make([][]uint, 0, len(tweetIDs))
+	for _, cmd := range cmds {
+		userIDs, err := parseUserIDs(cmd.Val())
 		if err != nil {
 			return nil, err
 		}
 
-		ids = append(ids, id)
+		slices.Sort(userIDs)
+		retweeters = append(retweeters, userIDs)
 	}
 
-	return ids, nil
+	return retweeters, nil
 }
 
-// Login logs the user in the app
-func (repository Repository) Login(userID uint) error {
-	loginKey := "login"
+// AddLike records that userID likes tweetID. Returns false if it was already liked.
+// The likers of a tweet expire TweetTTL after the last like. The like is added by a script, so the tweet is pushed
+// to the likes of the user only once.
+func (repository Repository) AddLike(userID uint, tweetID uuid.UUID) (bool, error) {
+	keys := []string{LikesKey(tweetID), UserLikesKey(userID)}
+
+	added, err := addLikeScript.Run(context.Background(), repository.Redis, keys,
+		userID, tweetID.String(), repository.tweetTTL().Milliseconds(), MaxUserLikes).Int()
+	if err != nil {
+		return false, err
+	}
 
-	return repository.Redis.Set(context.Background(), loginKey, userID, SessionTTL).Err()
+	return added == 1, nil
 }
 
-// CheckUserLog checks if the user logged is the user who wants to make the action
-func (repository Repository) CheckUserLog(userID uint) (bool, error) {
-	loginKey := "login"
+// RemoveLike removes the like of userID to tweetID. Returns false if it wasn't liked.
+func (repository Repository) RemoveLike(userID uint, tweetID uuid.UUID) (bool, error) {
+	var removed *redis.IntCmd
 
-	value, err := repository.Redis.Get(context.Background(), loginKey).Result()
-	if err == redis.Nil {
-		return false, nil
-	} else if err != nil {
+	_, err := repository.Redis.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		removed = pipe.SRem(context.Background(), LikesKey(tweetID), userID)
+		pipe.LRem(context.Background(), UserLikesKey(userID), 0, tweetID.String())
+
+		return nil
+	})
+	if err != nil {
 		return false, err
 	}
 
-	valueInt, err := strconv.Atoi(value)
+	return removed.Val() > 0, nil
+}
+
+// GetLikedBy tells if userID likes every tweet in tweetIDs, in the same order
+func (repository Repository) GetLikedBy(userID uint, tweetIDs []uuid.UUID) ([]bool, error) {
+	cmds := make([]*redis.BoolCmd, 0, len(tweetIDs))
+
+	_, err := repository.Redis.Pipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		for _, tweetID := range tweetIDs {
+			cmds = append(cmds, pipe.SIsMember(context.Background(), LikesKey(tweetID), userID))
+		}
+
+		return nil
+	})
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	if uint(valueInt) == userID {
-		return true, nil
-	} else {
-		return false, nil
+	liked := make([]bool, 0, len(tweetIDs))
+	for _, cmd := range cmds {
+		liked = append(liked, cmd.Val())
 	}
 
+	return liked, nil
 }
 
-// UserFollowersKey returns the key that stores the list of followers of userID in the cache
+// GetUserLikes returns the ids of the latest MaxUserLikes tweets liked by userID, newest like first.
+// Ids of expired tweets may be included.
+func (repository Repository) GetUserLikes(userID uint) ([]uuid.UUID, error) {
+	idsString, err := repository.Redis.LRange(context.Background(), UserLikesKey(userID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseTweetIDs(idsString)
+}
+
+// GetConversation returns the ids of the replies of a conversation, oldest first. Ids of expired tweets may be included.
+func (repository Repository) GetConversation(conversationID uuid.UUID) ([]uuid.UUID, error) {
+	idsString, err := repository.Redis.ZRange(context.Background(), ConversationKey(conversationID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseTweetIDs(idsString)
+}
+
+// DeleteTweet deletes the tweet and removes it from the timelines of timelineUserIDs.
+// Returns false if it didn't exist. Everything is removed in a single MULTI/EXEC round trip.
+// The tweet key is watched, so a reply is discounted from its parent only once.
+func (repository Repository) DeleteTweet(tweet models.Tweet, timelineUserIDs []uint) (bool, error) {
+	tweetKey := TweetKey(tweet.ID)
+	deleted := false
+
+	err := repository.Redis.Watch(context.Background(), func(tx *redis.Tx) error {
+		exists, err := tx.Exists(context.Background(), tweetKey).Result()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+			pipe.Del(context.Background(), tweetKey, TweetHistoryKey(tweet.ID), TweetCountsKey(tweet.ID), RetweetsKey(tweet.ID), LikesKey(tweet.ID))
+			pipe.LRem(context.Background(), UserTweetsKey(tweet.UserID), 0, tweet.ID.String())
+			unindexHashtags(pipe, tweet.ID, tweet.Hashtags)
+			unindexSearchTerms(pipe, tweet.ID, models.SearchTerms(tweet.Body))
+			pipe.ZRem(context.Background(), UserSearchKey(tweet.UserID), tweet.ID.String())
+
+			if tweet.InReplyTo != nil {
+				pipe.ZRem(context.Background(), ConversationKey(tweet.ConversationID), tweet.ID.String())
+				if exists > 0 {
+					pipe.HIncrBy(context.Background(), TweetCountsKey(*tweet.InReplyTo), replyCountField, -1)
+				}
+			}
+
+			for _, timelineUserID := range timelineUserIDs {
+				pipe.LRem(context.Background(), TimelineKey(timelineUserID), 0, tweet.ID.String())
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		deleted = exists > 0
+
+		return nil
+	}, tweetKey)
+
+	return deleted, err
+}
+
+// EditTweet replaces the body, mentions and hashtags of the tweet edited.ID of edited.UserID with the ones of edited,
+// edited at edited.EditedAt, keeping the previous revision in its history. Returns false if it doesn't exist.
+// The tweet key is watched, so concurrent edits fail instead of losing a revision. The history expires with the tweet.
+func (repository Repository) EditTweet(edited models.Tweet) (bool, error) {
+	tweetID := edited.ID
+	tweetKey := TweetKey(tweetID)
+	historyKey := TweetHistoryKey(tweetID)
+	found := false
+
+	err := repository.Redis.Watch(context.Background(), func(tx *redis.Tx) error {
+		tweetString, err := tx.Get(context.Background(), tweetKey).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		tweet := models.Tweet{}
+		err = json.Unmarshal([]byte(tweetString), &tweet)
+		if err != nil {
+			return err
+		}
+
+		if tweet.UserID != edited.UserID {
+			return nil
+		}
+
+		ttl, err := tx.PTTL(context.Background(), tweetKey).Result()
+		if err != nil {
+			return err
+		}
+
+		revision := tweet.Revision()
+		previousHashtags := tweet.Hashtags
+		previousTerms := models.SearchTerms(tweet.Body)
+		tweet.ID = tweetID
+		tweet.Body = edited.Body
+		tweet.Mentions = edited.Mentions
+		tweet.Hashtags = edited.Hashtags
+		tweet.EditedAt = edited.EditedAt
+
+		_, err = tx.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+			pipe.SetArgs(context.Background(), tweetKey, tweet, redis.SetArgs{KeepTTL: true})
+			unindexHashtags(pipe, tweetID, previousHashtags)
+			repository.indexHashtags(pipe, tweet, tweet.Hashtags)
+			unindexSearchTerms(pipe, tweetID, previousTerms)
+			repository.indexSearchTerms(pipe, tweet, models.SearchTerms(tweet.Body))
+			pipe.RPush(context.Background(), historyKey, revision)
+			if ttl > 0 {
+				pipe.PExpire(context.Background(), historyKey, ttl)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		found = true
+
+		return nil
+	}, tweetKey)
+
+	return found, err
+}
+
+// GetTweetHistory returns the previous revisions of a tweet, oldest first
+func (repository Repository) GetTweetHistory(tweetID uuid.UUID) ([]models.TweetRevision, error) {
+	revisionsString, err := repository.Redis.LRange(context.Background(), TweetHistoryKey(tweetID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]models.TweetRevision, 0, len(revisionsString))
+	for _, revisionString := range revisionsString {
+		revision := models.TweetRevision{}
+		err = json.Unmarshal([]byte(revisionString), &revision)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, nil
+}
+
+// AddTweetToTimeline adds a tweetID to the user's timeline if it isn't there yet, keeping the latest TimelineSize.
+func (repository Repository) AddTweetToTimeline(tweetID uuid.UUID, userID uint) error {
+	return repository.AddTweetToTimelines(tweetID, []uint{userID})
+}
+
+// AddTweetToTimelines adds a tweetID to the timeline of every user in userIDs that doesn't have it yet, at once.
+// Each keeps the latest TimelineSize. The check, push and trim of every timeline run atomically in a single script.
+func (repository Repository) AddTweetToTimelines(tweetID uuid.UUID, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	timelineKeys := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		timelineKeys = append(timelineKeys, TimelineKey(userID))
+	}
+
+	return addToTimelinesScript.Run(context.Background(), repository.Redis, timelineKeys, tweetID.String(), repository.timelineSize()).Err()
+}
+
+// GetTimeLine returns the list of tweets ids in a user timeline
+func (repository Repository) GetTimeLine(userID uint) ([]uuid.UUID, error) {
+	timelineKey := TimelineKey(userID)
+
+	idsString, err := repository.Redis.LRange(context.Background(), timelineKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseTweetIDs(idsString)
+}
+
+// RemoveFromTimeline removes the tweetIDs from the user's timeline
+func (repository Repository) RemoveFromTimeline(userID uint, tweetIDs []uuid.UUID) error {
+	if len(tweetIDs) == 0 {
+		return nil
+	}
+
+	timelineKey := TimelineKey(userID)
+
+	_, err := repository.Redis.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		for _, tweetID := range tweetIDs {
+			pipe.LRem(context.Background(), timelineKey, 0, tweetID.String())
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+// RemoveTweetFromTimelines removes the tweetID from the timeline of every user in userIDs at once
+func (repository Repository) RemoveTweetFromTimelines(tweetID uuid.UUID, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	_, err := repository.Redis.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		for _, userID := range userIDs {
+			pipe.LRem(context.Background(), TimelineKey(userID), 0, tweetID.String())
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+// ReplaceTimeline replaces the user's timeline with the first TimelineSize tweetIDs if it still is current.
+// Returns false if it changed meanwhile. The timeline key is watched, so the replacement fails if a tweet is pushed concurrently.
+func (repository Repository) ReplaceTimeline(userID uint, current, tweetIDs []uuid.UUID) (bool, error) {
+	timelineKey := TimelineKey(userID)
+
+	err := repository.Redis.Watch(context.Background(), func(tx *redis.Tx) error {
+		idsString, err := tx.LRange(context.Background(), timelineKey, 0, -1).Result()
+		if err != nil {
+			return err
+		}
+
+		stored, err := parseTweetIDs(idsString)
+		if err != nil {
+			return err
+		}
+
+		if !slices.Equal(stored, current) {
+			return redis.TxFailedErr
+		}
+
+		_, err = tx.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+			pipe.Del(context.Background(), timelineKey)
+			for _, tweetID := range repository.capTimeline(tweetIDs) {
+				pipe.RPush(context.Background(), timelineKey, tweetID.String())
+			}
+			return nil
+		})
+		return err
+	}, timelineKey)
+	if errors.Is(err, redis.TxFailedErr) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// CreateSession stores a new session for SessionTTL
+func (repository Repository) CreateSession(session models.Session) error {
+	sessionKey := SessionKey(session.ID)
+	userSessionsKey := UserSessionsKey(session.UserID)
+
+	_, err := repository.Redis.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.Set(context.Background(), sessionKey, session, repository.sessionTTL())
+		pipe.SAdd(context.Background(), userSessionsKey, session.ID)
+
+		return nil
+	})
+
+	return err
+}
+
+// GetSession returns the session by id, or false if it doesn't exist or expired
+func (repository Repository) GetSession(sessionID string) (models.Session, bool, error) {
+	sessionKey := SessionKey(sessionID)
+
+	sessionString, err := repository.Redis.Get(context.Background(), sessionKey).Result()
+	if err == redis.Nil {
+		return models.Session{}, false, nil
+	} else if err != nil {
+		return models.Session{}, false, err
+	}
+
+	session := models.Session{}
+	err = json.Unmarshal([]byte(sessionString), &session)
+	if err != nil {
+		return models.Session{}, false, err
+	}
+
+	return session, true, nil
+}
+
+// RefreshSession stores the session again and restarts its SessionTTL, if it wasn't deleted
+func (repository Repository) RefreshSession(session models.Session) error {
+	sessionKey := SessionKey(session.ID)
+
+	return repository.Redis.SetXX(context.Background(), sessionKey, session, repository.sessionTTL()).Err()
+}
+
+// GetSessions returns the active sessions of userID
+// Ids of sessions that already expired are removed from the user's index.
+func (repository Repository) GetSessions(userID uint) ([]models.Session, error) {
+	userSessionsKey := UserSessionsKey(userID)
+
+	ids, err := repository.Redis.SMembers(context.Background(), userSessionsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]models.Session, 0, len(ids))
+
+	if len(ids) == 0 {
+		return sessions, nil
+	}
+
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, SessionKey(id))
+	}
+
+	values, err := repository.Redis.MGet(context.Background(), keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	expired := make([]any, 0)
+
+	for i, value := range values {
+		sessionString, ok := value.(string)
+		if !ok {
+			//ttl reached
+			expired = append(expired, ids[i])
+			continue
+		}
+
+		session := models.Session{}
+		err = json.Unmarshal([]byte(sessionString), &session)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	if len(expired) > 0 {
+		err = repository.Redis.SRem(context.Background(), userSessionsKey, expired...).Err()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sortSessions(sessions)
+
+	return sessions, nil
+}
+
+// DeleteSessions deletes the sessions of userID by ids
+func (repository Repository) DeleteSessions(userID uint, sessionIDs []string) error {
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+
+	userSessionsKey := UserSessionsKey(userID)
+
+	_, err := repository.Redis.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		for _, sessionID := range sessionIDs {
+			pipe.Del(context.Background(), SessionKey(sessionID))
+			pipe.SRem(context.Background(), userSessionsKey, sessionID)
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+// CreateUser stores a new user and returns its id. Returns ErrHandleTaken if the handle is in use, ignoring case.
+// The id is only taken once the handle is known to be free.
+func (repository Repository) CreateUser(user models.User) (uint, error) {
+	handleKey := HandleKey(user.Handle)
+	redirectKey := HandleRedirectKey(user.Handle)
+	var id int64
+
+	err := repository.watch(func(tx *redis.Tx) error {
+		//a handle is kept by its redirect until it expires
+		taken, err := tx.Exists(context.Background(), handleKey, redirectKey).Result()
+		if err != nil {
+			return err
+		}
+
+		if taken > 0 {
+			return ErrHandleTaken
+		}
+
+		//a retry keeps the id taken by the previous attempt
+		if id == 0 {
+			id, err = tx.Incr(context.Background(), UserIDSequenceKey).Result()
+			if err != nil {
+				return err
+			}
+		}
+
+		user.ID = uint(id)
+
+		_, err = tx.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+			pipe.Set(context.Background(), handleKey, user.ID, 0)
+			pipe.Set(context.Background(), UserKey(user.ID), user, 0)
+			return nil
+		})
+
+		return err
+	}, handleKey, redirectKey)
+	if errors.Is(err, redis.TxFailedErr) {
+		//the handle kept changing, so another user is taking it
+		return 0, ErrHandleTaken
+	} else if err != nil {
+		return 0, err
+	}
+
+	return user.ID, nil
+}
+
+// watch runs fn in a transaction watching keys, retrying up to watchAttempts times if they changed before it ran.
+// Returns redis.TxFailedErr if they changed every time
+func (repository Repository) watch(fn func(tx *redis.Tx) error, keys ...string) error {
+	var err error
+
+	for attempt := 0; attempt < watchAttempts; attempt++ {
+		err = repository.Redis.Watch(context.Background(), fn, keys...)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// GetUser returns the user by id, or false if it doesn't exist
+func (repository Repository) GetUser(userID uint) (models.User, bool, error) {
+	userString, err := repository.Redis.Get(context.Background(), UserKey(userID)).Result()
+	if err == redis.Nil {
+		return models.User{}, false, nil
+	} else if err != nil {
+		return models.User{}, false, err
+	}
+
+	user := models.User{}
+	err = user.UnmarshalBinary([]byte(userString))
+	if err != nil {
+		return models.User{}, false, err
+	}
+
+	return user, true, nil
+}
+
+// GetUserByHandle returns the user by handle ignoring case, or false if it doesn't exist
+func (repository Repository) GetUserByHandle(handle string) (models.User, bool, error) {
+	id, err := repository.Redis.Get(context.Background(), HandleKey(handle)).Uint64()
+	if err == redis.Nil {
+		return models.User{}, false, nil
+	} else if err != nil {
+		return models.User{}, false, err
+	}
+
+	return repository.GetUser(uint(id))
+}
+
+// UpdatePassword replaces the password hash of userID. The user key is watched, so a concurrent profile change or
+// rename isn't lost.
+func (repository Repository) UpdatePassword(userID uint, passwordHash []byte) error {
+	return repository.updateUser(userID, func(user *models.User) {
+		user.PasswordHash = passwordHash
+	})
+}
+
+// UpdateProfile replaces the profile of userID. The user key is watched, so a concurrent password change isn't lost.
+func (repository Repository) UpdateProfile(userID uint, profile models.Profile) error {
+	return repository.updateUser(userID, func(user *models.User) {
+		user.Profile = profile
+	})
+}
+
+// updateUser stores the changes made by update to userID, if it exists, watching its key so that concurrent updates
+// of other fields aren't overwritten. It's retried like watch if one of them happened meanwhile
+func (repository Repository) updateUser(userID uint, update func(user *models.User)) error {
+	userKey := UserKey(userID)
+
+	return repository.watch(func(tx *redis.Tx) error {
+		userString, err := tx.Get(context.Background(), userKey).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		user := models.User{}
+		err = user.UnmarshalBinary([]byte(userString))
+		if err != nil {
+			return err
+		}
+
+		update(&user)
+
+		_, err = tx.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+			pipe.Set(context.Background(), userKey, user, 0)
+
+			return nil
+		})
+
+		return err
+	}, userKey)
+}
+
+// RenameHandle replaces the handle of userID, keeping the previous one redirecting to userID for redirectFor.
+// Returns ErrHandleTaken if handle is in use by another user, ignoring case, or still redirects to another user.
+// The user and both handles are watched, so concurrent renames can't take the same handle, and it's retried like
+// watch if they changed meanwhile.
+func (repository Repository) RenameHandle(userID uint, handle string, redirectFor time.Duration) error {
+	userKey := UserKey(userID)
+	handleKey := HandleKey(handle)
+	redirectKey := HandleRedirectKey(handle)
+
+	return repository.watch(func(tx *redis.Tx) error {
+		userString, err := tx.Get(context.Background(), userKey).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		user := models.User{}
+		err = user.UnmarshalBinary([]byte(userString))
+		if err != nil {
+			return err
+		}
+
+		for _, key := range []string{handleKey, redirectKey} {
+			ownerID, err := tx.Get(context.Background(), key).Uint64()
+			if err == nil && uint(ownerID) != userID {
+				return ErrHandleTaken
+			} else if err != nil && !errors.Is(err, redis.Nil) {
+				return err
+			}
+		}
+
+		previousKey := HandleKey(user.Handle)
+		previousHandle := user.Handle
+		user.Handle = handle
+
+		_, err = tx.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+			pipe.Set(context.Background(), userKey, user, 0)
+			pipe.Set(context.Background(), handleKey, userID, 0)
+			pipe.Del(context.Background(), redirectKey)
+
+			//a change of case keeps the same key
+			if previousKey != handleKey {
+				pipe.Del(context.Background(), previousKey)
+				pipe.Set(context.Background(), HandleRedirectKey(previousHandle), userID, redirectFor)
+			}
+
+			return nil
+		})
+
+		return err
+	}, userKey, handleKey, redirectKey)
+}
+
+// GetHandleRedirect returns the user that renamed handle, ignoring case, while it still redirects, or false
+func (repository Repository) GetHandleRedirect(handle string) (uint, bool, error) {
+	userID, err := repository.Redis.Get(context.Background(), HandleRedirectKey(handle)).Uint64()
+	if errors.Is(err, redis.Nil) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, err
+	}
+
+	return uint(userID), true, nil
+}
+
+// GetFailedLogins returns the failed login attempts of userID since the first one, up to FailedLoginsTTL ago
+func (repository Repository) GetFailedLogins(userID uint) (int64, error) {
+	attempts, err := repository.Redis.Get(context.Background(), FailedLoginsKey(userID)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+
+	return attempts, err
+}
+
+// AddFailedLogin records a failed login attempt of userID and returns the attempts like GetFailedLogins
+func (repository Repository) AddFailedLogin(userID uint) (int64, error) {
+	failedLoginsKey := FailedLoginsKey(userID)
+
+	// The TTL is only set by the first attempt, in the same transaction so the counter never lives without one
+	var attempts *redis.IntCmd
+	_, err := repository.Redis.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		attempts = pipe.Incr(context.Background(), failedLoginsKey)
+		pipe.ExpireNX(context.Background(), failedLoginsKey, FailedLoginsTTL)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return attempts.Val(), nil
+}
+
+// ResetFailedLogins forgets the failed login attempts of userID
+func (repository Repository) ResetFailedLogins(userID uint) error {
+	return repository.Redis.Del(context.Background(), FailedLoginsKey(userID)).Err()
+}
+
+// CreatePasswordReset stores the id of a password reset token of userID for PasswordResetTTL
+func (repository Repository) CreatePasswordReset(tokenID string, userID uint) error {
+	return repository.Redis.Set(context.Background(), PasswordResetKey(tokenID), userID, PasswordResetTTL).Err()
+}
+
+// ConsumePasswordReset deletes a password reset token id and returns its user, or false if it doesn't exist or expired
+func (repository Repository) ConsumePasswordReset(tokenID string) (uint, bool, error) {
+	userID, err := repository.Redis.GetDel(context.Background(), PasswordResetKey(tokenID)).Uint64()
+	if err == redis.Nil {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, err
+	}
+
+	return uint(userID), true, nil
+}
+
+// AddNotification adds notification to the inbox of its user, keeping the latest MaxNotifications
+func (repository Repository) AddNotification(notification models.Notification) error {
+	notificationsKey := NotificationsKey(notification.UserID)
+
+	_, err := repository.Redis.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.LPush(context.Background(), notificationsKey, notification)
+		pipe.LTrim(context.Background(), notificationsKey, 0, MaxNotifications-1)
+
+		return nil
+	})
+
+	return err
+}
+
+// GetNotifications returns the latest MaxNotifications notifications of the inbox of userID, newest first
+func (repository Repository) GetNotifications(userID uint) ([]models.Notification, error) {
+	notificationsString, err := repository.Redis.LRange(context.Background(), NotificationsKey(userID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	notifications := make([]models.Notification, 0, len(notificationsString))
+	for _, notificationString := range notificationsString {
+		notification := models.Notification{}
+		err := json.Unmarshal([]byte(notificationString), &notification)
+		if err != nil {
+			return nil, err
+		}
+
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, nil
+}
+
+// GetNotificationsReadAt returns the time until which userID read its notifications, zero if it never did
+func (repository Repository) GetNotificationsReadAt(userID uint) (time.Time, error) {
+	nanos, err := repository.Redis.Get(context.Background(), NotificationsReadAtKey(userID)).Int64()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(0, nanos), nil
+}
+
+// SetNotificationsReadAt replaces the time until which userID read its notifications
+func (repository Repository) SetNotificationsReadAt(userID uint, readAt time.Time) error {
+	return repository.Redis.Set(context.Background(), NotificationsReadAtKey(userID), readAt.UnixNano(), 0).Err()
+}
+
+// UserFollowersKey returns the key that stores the list of followers of userID in the cache
 func UserFollowersKey(userID uint) string {
 	return fmt.Sprintf("%d-followers", userID)
 }
 
+// UserFollowingKey returns the key of the set of users followed by userID
+func UserFollowingKey(userID uint) string {
+	return fmt.Sprintf("%d-following", userID)
+}
+
+// UserTweetsKey returns the key of the list of the latest tweets ids of userID
+func UserTweetsKey(userID uint) string {
+	return fmt.Sprintf("%d-tweets", userID)
+}
+
 // TweetKey returns the key that stores a tweet by id
 func TweetKey(tweetID uuid.UUID) string {
 	return fmt.Sprintf("tweet-%s", tweetID)
 }
 
+// TweetHistoryKey returns the key of the list of previous revisions of a tweet
+func TweetHistoryKey(tweetID uuid.UUID) string {
+	return fmt.Sprintf("tweet-%s-history", tweetID)
+}
+
+// TweetCountsKey returns the key of the hash of counts of a tweet
+func TweetCountsKey(tweetID uuid.UUID) string {
+	return fmt.Sprintf("tweet-%s-counts", tweetID)
+}
+
+// RetweetsKey returns the key of the set of users that retweeted a tweet
+func RetweetsKey(tweetID uuid.UUID) string {
+	return fmt.Sprintf("tweet-%s-retweets", tweetID)
+}
+
+// LikesKey returns the key of the set of users that like a tweet
+func LikesKey(tweetID uuid.UUID) string {
+	return fmt.Sprintf("tweet-%s-likes", tweetID)
+}
+
+// UserLikesKey returns the key of the list of the latest tweets ids liked by userID, newest like first
+func UserLikesKey(userID uint) string {
+	return fmt.Sprintf("%d-likes", userID)
+}
+
+// NotificationsKey returns the key of the list of the latest notifications of userID, newest first
+func NotificationsKey(userID uint) string {
+	return fmt.Sprintf("%d-notifications", userID)
+}
+
+// NotificationsReadAtKey returns the key of the time until which userID read its notifications, in nanoseconds
+func NotificationsReadAtKey(userID uint) string {
+	return fmt.Sprintf("%d-notifications-read-at", userID)
+}
+
+// HashtagsKey is the key of the sorted set of hashtags, by the timestamp of their latest tweet in milliseconds
+const HashtagsKey = "hashtags"
+
+// HashtagKey returns the key of the sorted set of the tweets with hashtag, by timestamp in milliseconds
+func HashtagKey(hashtag string) string {
+	return fmt.Sprintf("hashtag-%s", hashtag)
+}
+
+// SearchTermKey returns the key of the sorted set of the tweets ids with term, scored by their timestamp
+func SearchTermKey(term string) string {
+	return fmt.Sprintf("search-%s", term)
+}
+
+// UserSearchKey returns the key of the sorted set of the tweets ids of userID searched by user, scored by their timestamp
+func UserSearchKey(userID uint) string {
+	return fmt.Sprintf("%d-search", userID)
+}
+
+// SearchResultsKey returns the temporary key of the results of searchID
+func SearchResultsKey(searchID uuid.UUID) string {
+	return fmt.Sprintf("search-results-%s", searchID)
+}
+
+// ConversationKey returns the key of the sorted set of replies of a conversation, by timestamp
+func ConversationKey(conversationID uuid.UUID) string {
+	return fmt.Sprintf("conversation-%s", conversationID)
+}
+
 // TimelineKey returns the key that stores an user's timeline
 func TimelineKey(userID uint) string {
 	return fmt.Sprintf("tl-%d", userID)
 }
+
+// SessionKey returns the key that stores a session by id
+func SessionKey(sessionID string) string {
+	return fmt.Sprintf("session-%s", sessionID)
+}
+
+// UserSessionsKey returns the key that stores the ids of the sessions of userID
+func UserSessionsKey(userID uint) string {
+	return fmt.Sprintf("%d-sessions", userID)
+}
+
+// UserKey returns the key that stores a user by id
+func UserKey(userID uint) string {
+	return fmt.Sprintf("user-%d", userID)
+}
+
+// HandleKey returns the key that stores the id of the user with handle, ignoring case
+func HandleKey(handle string) string {
+	return fmt.Sprintf("handle-%s", strings.ToLower(handle))
+}
+
+// HandleRedirectKey returns the key of the id of the user that renamed handle, ignoring case, while it redirects
+func HandleRedirectKey(handle string) string {
+	return fmt.Sprintf("handle-redirect-%s", strings.ToLower(handle))
+}
+
+// FailedLoginsKey returns the key that counts the failed login attempts of userID
+func FailedLoginsKey(userID uint) string {
+	return fmt.Sprintf("%d-failed-logins", userID)
+}
+
+// PasswordResetKey returns the key that stores the user of a password reset token id
+func PasswordResetKey(tokenID string) string {
+	return fmt.Sprintf("password-reset-%s", tokenID)
+}
+
+// sortSessions sorts sessions from the oldest to the newest
+func sortSessions(sessions []models.Session) {
+	slices.SortFunc(sessions, func(a, b models.Session) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+}
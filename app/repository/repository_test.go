@@ -0,0 +1,1421 @@
+package repository_test
+
+import (
+	"database/sql"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PatricioYegros/uala_challenge/app/models"
+	"github.com/PatricioYegros/uala_challenge/app/repository"
+	"github.com/go-playground/assert/v2"
+	"github.com/google/uuid"
+
+	"github.com/alicebob/miniredis/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/redis/go-redis/v9"
+)
+
+type fakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+	// onAdvance lets backends with their own notion of time follow the clock
+	onAdvance []func(time.Duration)
+}
+
+func (clock *fakeClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	return clock.now
+}
+
+func (clock *fakeClock) Advance(duration time.Duration) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	clock.now = clock.now.Add(duration)
+	for _, advance := range clock.onAdvance {
+		advance(duration)
+	}
+}
+
+// postgresDSNEnvVar points the tests at a PostgreSQL database, the postgres backend is skipped without it
+const postgresDSNEnvVar = "TEST_POSTGRES_DSN"
+
+// testTimelineSize keeps timelines short enough to overflow them
+const testTimelineSize = 10
+
+// testLimits are shorter than the defaults to check that the backends honor them
+var testLimits = repository.Limits{
+	TweetTTL:     2 * time.Hour,
+	SessionTTL:   3 * time.Hour,
+	TimelineSize: testTimelineSize,
+}
+
+type backend struct {
+	name string
+	// durable backends keep tweets beyond TweetTTL
+	durable bool
+	new     func(t *testing.T, clock *fakeClock) repository.IRepository
+}
+
+var backends = []backend{
+	{
+		name: "memory",
+		new: func(t *testing.T, clock *fakeClock) repository.IRepository {
+			repo := repository.NewMemoryRepository(clock)
+			repo.Limits = testLimits
+
+			return repo
+		},
+	},
+	{
+		name:    "sqlite",
+		durable: true,
+		new: func(t *testing.T, clock *fakeClock) repository.IRepository {
+			return repository.SQLRepository{DB: newSQLiteDatabase(t), Clock: clock, Limits: testLimits}
+		},
+	},
+	{
+		name:    "postgres",
+		durable: true,
+		new: func(t *testing.T, clock *fakeClock) repository.IRepository {
+			return repository.SQLRepository{DB: newPostgresDatabase(t), Clock: clock, Limits: testLimits}
+		},
+	},
+	{
+		name: "redis",
+		new: func(t *testing.T, clock *fakeClock) repository.IRepository {
+			return repository.Repository{Redis: newRedisClient(t, clock), Limits: testLimits}
+		},
+	},
+	{
+		name:    "sqlite+redis",
+		durable: true,
+		new: func(t *testing.T, clock *fakeClock) repository.IRepository {
+			return repository.CachedRepository{
+				IRepository: repository.SQLRepository{DB: newSQLiteDatabase(t), Clock: clock, Limits: testLimits},
+				Redis:       newRedisClient(t, clock),
+				Limits:      testLimits,
+			}
+		},
+	},
+}
+
+func newRedisClient(t *testing.T, clock *fakeClock) *redis.Client {
+	server := miniredis.RunT(t)
+	clock.onAdvance = append(clock.onAdvance, server.FastForward)
+
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func newSQLiteDatabase(t *testing.T) *sql.DB {
+	db, err := sql.Open(repository.SQLDriverSQLite, filepath.Join(t.TempDir(), "uala.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if err := repository.Migrate(db, repository.SQLDriverSQLite); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// newPostgresDatabase migrates a new schema of the database at postgresDSNEnvVar, dropped when the test ends
+func newPostgresDatabase(t *testing.T) *sql.DB {
+	dsn := os.Getenv(postgresDSNEnvVar)
+	if dsn == "" {
+		t.Skipf("%s isn't set", postgresDSNEnvVar)
+	}
+
+	admin, err := sql.Open(repository.SQLDriverPostgres, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	//unknown parameters are sent to the server as settings of the session
+	if strings.Contains(dsn, "://") {
+		location, err := url.Parse(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		query := location.Query()
+		query.Set("search_path", schema)
+		location.RawQuery = query.Encode()
+		dsn = location.String()
+	} else {
+		dsn += " search_path=" + schema
+	}
+
+	db, err := sql.Open(repository.SQLDriverPostgres, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := repository.Migrate(db, repository.SQLDriverPostgres); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func forEachBackend(t *testing.T, test func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock)) {
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)}
+			test(t, backend, backend.new(t, clock), clock)
+		})
+	}
+}
+
+func TestFollowers(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		assert.Equal(t, repo.AddFollower(1, 3), nil)
+		assert.Equal(t, repo.AddFollower(1, 2), nil)
+		assert.Equal(t, repo.AddFollower(1, 2), nil)
+
+		followers, err := repo.GetFollowers(1)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(followers), 2)
+
+		followers, err = repo.GetFollowers(2)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(followers), 0)
+	})
+}
+
+func TestRemoveFollower(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		assert.Equal(t, repo.AddFollower(1, 2), nil)
+		assert.Equal(t, repo.AddFollower(1, 3), nil)
+
+		removed, err := repo.RemoveFollower(1, 2)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, removed, true)
+
+		removed, err = repo.RemoveFollower(1, 2)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, removed, false)
+
+		followers, err := repo.GetFollowers(1)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, followers, []uint{3})
+	})
+}
+
+func TestFollowingIndex(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		assert.Equal(t, repo.AddFollower(1, 3), nil)
+		assert.Equal(t, repo.AddFollower(2, 3), nil)
+		assert.Equal(t, repo.AddFollower(2, 4), nil)
+
+		following, err := repo.GetFollowing(3)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(following), 2)
+
+		_, err = repo.RemoveFollower(1, 3)
+		assert.Equal(t, err, nil)
+
+		following, err = repo.GetFollowing(3)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, following, []uint{2})
+
+		count, err := repo.CountFollowing(3)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, count, int64(1))
+
+		count, err = repo.CountFollowers(2)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, count, int64(2))
+
+		counts, err := repo.CountFollowersOf([]uint{2, 1, 5})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, counts, []int64{2, 0, 0})
+	})
+}
+
+func TestScanFollowersAndFollowing(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		for follower := uint(2); follower <= 26; follower++ {
+			assert.Equal(t, repo.AddFollower(1, follower), nil)
+			assert.Equal(t, repo.AddFollower(follower, 1), nil)
+		}
+
+		for _, scan := range []func(uint, uint64, int64) ([]uint, uint64, error){repo.ScanFollowers, repo.ScanFollowing} {
+			seen := make(map[uint]struct{})
+			cursor := uint64(0)
+
+			for {
+				ids, next, err := scan(1, cursor, 10)
+				assert.Equal(t, err, nil)
+
+				for _, id := range ids {
+					seen[id] = struct{}{}
+				}
+
+				if next == 0 {
+					break
+				}
+				cursor = next
+			}
+
+			assert.Equal(t, len(seen), 25)
+		}
+	})
+}
+
+func TestTweetsAfterTTL(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		tweet := models.Tweet{UserID: 1, Timestamp: clock.Now(), Body: "uala_challenge"}
+
+		id, err := repo.CreateTweet(tweet)
+		assert.Equal(t, err, nil)
+
+		tweets, missing, err := repo.GetTweets([]uuid.UUID{id})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(missing), 0)
+		assert.Equal(t, len(tweets), 1)
+		assert.Equal(t, tweets[0].ID, id)
+		assert.Equal(t, tweets[0].Body, tweet.Body)
+
+		clock.Advance(testLimits.TweetTTL)
+
+		tweets, missing, err = repo.GetTweets([]uuid.UUID{id})
+		assert.Equal(t, err, nil)
+		if backend.durable {
+			assert.Equal(t, len(tweets), 1)
+			assert.Equal(t, len(missing), 0)
+		} else {
+			assert.Equal(t, len(tweets), 0)
+			assert.Equal(t, missing, []uuid.UUID{id})
+		}
+	})
+}
+
+func TestGetTweetsSkipsMissingAndKeepsOrder(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		first, err := repo.CreateTweet(models.Tweet{UserID: 1, Timestamp: clock.Now(), Body: "first"})
+		assert.Equal(t, err, nil)
+		second, err := repo.CreateTweet(models.Tweet{UserID: 1, Timestamp: clock.Now(), Body: "second"})
+		assert.Equal(t, err, nil)
+		unknown := uuid.New()
+
+		tweets, missing, err := repo.GetTweets([]uuid.UUID{second, unknown, first})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, missing, []uuid.UUID{unknown})
+		assert.Equal(t, len(tweets), 2)
+		assert.Equal(t, tweets[0].Body, "second")
+		assert.Equal(t, tweets[1].Body, "first")
+	})
+}
+
+func TestDeleteTweet(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		kept, err := repo.CreateTweet(models.Tweet{UserID: 1, Timestamp: clock.Now(), Body: "kept"})
+		assert.Equal(t, err, nil)
+		deleted, err := repo.CreateTweet(models.Tweet{UserID: 1, Timestamp: clock.Now(), Body: "deleted"})
+		assert.Equal(t, err, nil)
+
+		assert.Equal(t, repo.AddTweetToTimelines(kept, []uint{2, 3}), nil)
+		assert.Equal(t, repo.AddTweetToTimelines(deleted, []uint{2, 3}), nil)
+
+		//the tweets are cached when read
+		_, _, err = repo.GetTweets([]uuid.UUID{kept, deleted})
+		assert.Equal(t, err, nil)
+
+		found, err := repo.DeleteTweet(models.Tweet{ID: deleted, UserID: 1}, []uint{2, 3})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, true)
+
+		tweets, missing, err := repo.GetTweets([]uuid.UUID{kept, deleted})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, missing, []uuid.UUID{deleted})
+		assert.Equal(t, len(tweets), 1)
+
+		for _, userID := range []uint{2, 3} {
+			timeline, err := repo.GetTimeLine(userID)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, timeline, []uuid.UUID{kept})
+		}
+
+		userTweets, err := repo.GetUserTweets(1, 10)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, userTweets, []uuid.UUID{kept})
+
+		found, err = repo.DeleteTweet(models.Tweet{ID: deleted, UserID: 1}, []uint{2, 3})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, false)
+	})
+}
+
+func TestEditTweet(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		createdAt := clock.Now()
+		id, err := repo.CreateTweet(models.Tweet{UserID: 1, Timestamp: createdAt, Body: "frist @3", Mentions: []uint{3}})
+		assert.Equal(t, err, nil)
+
+		//the tweet is cached when read
+		tweets, _, err := repo.GetTweets([]uuid.UUID{id})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, tweets[0].EditedAt == nil, true)
+		assert.Equal(t, tweets[0].Mentions, []uint{3})
+
+		clock.Advance(time.Minute)
+		firstEdit := clock.Now()
+		edited, err := repo.EditTweet(models.Tweet{ID: id, UserID: 1, Body: "first @3", Mentions: []uint{3}, EditedAt: &firstEdit})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, edited, true)
+
+		clock.Advance(time.Minute)
+		secondEdit := clock.Now()
+		edited, err = repo.EditTweet(models.Tweet{ID: id, UserID: 1, Body: "first @2 @4", Mentions: []uint{2, 4}, EditedAt: &secondEdit})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, edited, true)
+
+		tweets, _, err = repo.GetTweets([]uuid.UUID{id})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(tweets), 1)
+		assert.Equal(t, tweets[0].Body, "first @2 @4")
+		assert.Equal(t, tweets[0].Mentions, []uint{2, 4})
+		assert.Equal(t, tweets[0].Timestamp.Equal(createdAt), true)
+		assert.Equal(t, tweets[0].EditedAt.Equal(secondEdit), true)
+
+		history, err := repo.GetTweetHistory(id)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(history), 2)
+		assert.Equal(t, history[0].Body, "frist @3")
+		assert.Equal(t, history[0].Timestamp.Equal(createdAt), true)
+		assert.Equal(t, history[1].Body, "first @3")
+		assert.Equal(t, history[1].Timestamp.Equal(firstEdit), true)
+
+		//only the author edits
+		edited, err = repo.EditTweet(models.Tweet{ID: id, UserID: 2, Body: "second", EditedAt: &secondEdit})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, edited, false)
+
+		edited, err = repo.EditTweet(models.Tweet{ID: uuid.New(), UserID: 1, Body: "unknown", EditedAt: &secondEdit})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, edited, false)
+
+		_, err = repo.DeleteTweet(models.Tweet{ID: id, UserID: 1}, nil)
+		assert.Equal(t, err, nil)
+
+		history, err = repo.GetTweetHistory(id)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(history), 0)
+	})
+}
+
+func TestReplies(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		rootID, err := repo.CreateTweet(models.Tweet{UserID: 1, Timestamp: clock.Now(), Body: "root"})
+		assert.Equal(t, err, nil)
+
+		tweets, _, err := repo.GetTweets([]uuid.UUID{rootID})
+		assert.Equal(t, err, nil)
+		root := tweets[0]
+		assert.Equal(t, root.InReplyTo == nil, true)
+		assert.Equal(t, root.Conversation(), rootID)
+		assert.Equal(t, root.ConversationID, rootID)
+
+		clock.Advance(time.Second)
+		reply, err := models.NewReply(2, clock.Now(), "reply", models.MaxLength, root)
+		assert.Equal(t, err, nil)
+		replyID, err := repo.CreateTweet(*reply)
+		assert.Equal(t, err, nil)
+
+		tweets, _, err = repo.GetTweets([]uuid.UUID{replyID})
+		assert.Equal(t, err, nil)
+
+		clock.Advance(time.Second)
+		nested, err := models.NewReply(1, clock.Now(), "nested", models.MaxLength, tweets[0])
+		assert.Equal(t, err, nil)
+		nestedID, err := repo.CreateTweet(*nested)
+		assert.Equal(t, err, nil)
+
+		clock.Advance(time.Second)
+		second, err := models.NewReply(3, clock.Now(), "second", models.MaxLength, root)
+		assert.Equal(t, err, nil)
+		secondID, err := repo.CreateTweet(*second)
+		assert.Equal(t, err, nil)
+
+		tweets, _, err = repo.GetTweets([]uuid.UUID{rootID, replyID, nestedID})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, tweets[0].ReplyCount, int64(2))
+		assert.Equal(t, tweets[1].ReplyCount, int64(1))
+		assert.Equal(t, *tweets[1].InReplyTo, rootID)
+		assert.Equal(t, tweets[2].ReplyCount, int64(0))
+		assert.Equal(t, *tweets[2].InReplyTo, replyID)
+		assert.Equal(t, tweets[2].ConversationID, rootID)
+
+		conversation, err := repo.GetConversation(rootID)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, conversation, []uuid.UUID{replyID, nestedID, secondID})
+
+		counts, err := repo.GetTweetCounts([]uuid.UUID{replyID, uuid.New(), rootID})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, counts, []models.TweetCounts{{ReplyCount: 1}, {}, {ReplyCount: 2}})
+
+		tweets, _, err = repo.GetTweets([]uuid.UUID{secondID})
+		assert.Equal(t, err, nil)
+
+		for range 2 {
+			_, err = repo.DeleteTweet(tweets[0], nil)
+			assert.Equal(t, err, nil)
+		}
+
+		counts, err = repo.GetTweetCounts([]uuid.UUID{rootID})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, counts, []models.TweetCounts{{ReplyCount: 1}})
+
+		conversation, err = repo.GetConversation(rootID)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, conversation, []uuid.UUID{replyID, nestedID})
+	})
+}
+
+func TestQuotes(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		quotedID, err := repo.CreateTweet(models.Tweet{UserID: 1, Timestamp: clock.Now(), Body: "quoted"})
+		assert.Equal(t, err, nil)
+		expiringID, err := repo.CreateTweet(models.Tweet{UserID: 1, Timestamp: clock.Now(), Body: "expiring"})
+		assert.Equal(t, err, nil)
+
+		tweets, _, err := repo.GetTweets([]uuid.UUID{quotedID, expiringID})
+		assert.Equal(t, err, nil)
+
+		clock.Advance(time.Hour)
+		quote, err := models.NewQuote(2, clock.Now(), "look", models.MaxLength, tweets[0])
+		assert.Equal(t, err, nil)
+		quoteID, err := repo.CreateTweet(*quote)
+		assert.Equal(t, err, nil)
+		quote, err = models.NewQuote(2, clock.Now(), "look again", models.MaxLength, tweets[1])
+		assert.Equal(t, err, nil)
+		expiringQuoteID, err := repo.CreateTweet(*quote)
+		assert.Equal(t, err, nil)
+
+		reply, err := models.NewReply(3, clock.Now(), "reply", models.MaxLength, tweets[0])
+		assert.Equal(t, err, nil)
+		_, err = repo.CreateTweet(*reply)
+		assert.Equal(t, err, nil)
+
+		tweets, _, err = repo.GetTweets([]uuid.UUID{quoteID, expiringQuoteID})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(tweets), 2)
+		assert.Equal(t, *tweets[0].QuotedTweetID, quotedID)
+		assert.Equal(t, tweets[0].QuoteUnavailable, false)
+		assert.Equal(t, tweets[0].QuotedTweet.ID, quotedID)
+		assert.Equal(t, tweets[0].QuotedTweet.Body, "quoted")
+		assert.Equal(t, tweets[0].QuotedTweet.ReplyCount, int64(1))
+		assert.Equal(t, tweets[1].QuotedTweet.ID, expiringID)
+
+		_, err = repo.DeleteTweet(models.Tweet{ID: quotedID, UserID: 1}, nil)
+		assert.Equal(t, err, nil)
+		clock.Advance(90 * time.Minute)
+
+		tweets, _, err = repo.GetTweets([]uuid.UUID{quoteID, expiringQuoteID})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, tweets[0].QuoteUnavailable, true)
+		assert.Equal(t, tweets[0].QuotedTweet == nil, true)
+		assert.Equal(t, *tweets[0].QuotedTweetID, quotedID)
+		//durable backends keep tweets beyond TweetTTL
+		assert.Equal(t, tweets[1].QuoteUnavailable, !backend.durable)
+	})
+}
+
+func TestRemoveFromTimeline(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+		for _, id := range ids {
+			assert.Equal(t, repo.AddTweetToTimeline(id, 1), nil)
+		}
+
+		assert.Equal(t, repo.RemoveFromTimeline(1, []uuid.UUID{ids[1]}), nil)
+
+		timeline, err := repo.GetTimeLine(1)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, timeline, []uuid.UUID{ids[2], ids[0]})
+	})
+}
+
+func TestAddTweetToTimelinesSkipsDuplicates(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		first, second := uuid.New(), uuid.New()
+
+		assert.Equal(t, repo.AddTweetToTimeline(first, 1), nil)
+		assert.Equal(t, repo.AddTweetToTimeline(second, 1), nil)
+		assert.Equal(t, repo.AddTweetToTimelines(first, []uint{1, 2}), nil)
+
+		timeline, err := repo.GetTimeLine(1)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, timeline, []uuid.UUID{second, first})
+
+		timeline, err = repo.GetTimeLine(2)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, timeline, []uuid.UUID{first})
+	})
+}
+
+func TestRemoveTweetFromTimelines(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		kept, removed := uuid.New(), uuid.New()
+
+		assert.Equal(t, repo.AddTweetToTimelines(kept, []uint{1, 2, 3}), nil)
+		assert.Equal(t, repo.AddTweetToTimelines(removed, []uint{1, 2, 3}), nil)
+		assert.Equal(t, repo.RemoveTweetFromTimelines(removed, []uint{1, 2}), nil)
+
+		for userID, expected := range map[uint][]uuid.UUID{1: {kept}, 2: {kept}, 3: {removed, kept}} {
+			timeline, err := repo.GetTimeLine(userID)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, timeline, expected)
+		}
+	})
+}
+
+func TestRetweets(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		tweetID, err := repo.CreateTweet(models.Tweet{UserID: 1, Timestamp: clock.Now(), Body: "retweet me"})
+		assert.Equal(t, err, nil)
+		otherID, err := repo.CreateTweet(models.Tweet{UserID: 1, Timestamp: clock.Now(), Body: "or me"})
+		assert.Equal(t, err, nil)
+
+		for _, userID := range []uint{3, 2} {
+			added, err := repo.AddRetweet(userID, tweetID)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, added, true)
+		}
+
+		added, err := repo.AddRetweet(2, tweetID)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, added, false)
+
+		retweeters, err := repo.GetRetweeters([]uuid.UUID{otherID, tweetID})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, retweeters, [][]uint{{}, {2, 3}})
+
+		removed, err := repo.RemoveRetweet(3, tweetID)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, removed, true)
+
+		removed, err = repo.RemoveRetweet(3, tweetID)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, removed, false)
+
+		retweeters, err = repo.GetRetweeters([]uuid.UUID{tweetID})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, retweeters, [][]uint{{2}})
+
+		_, err = repo.DeleteTweet(models.Tweet{ID: tweetID, UserID: 1}, nil)
+		assert.Equal(t, err, nil)
+
+		retweeters, err = repo.GetRetweeters([]uuid.UUID{tweetID})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, retweeters, [][]uint{{}})
+	})
+}
+
+func TestLikes(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		tweetID, err := repo.CreateTweet(models.Tweet{UserID: 1, Timestamp: clock.Now(), Body: "like me"})
+		assert.Equal(t, err, nil)
+		otherID, err := repo.CreateTweet(models.Tweet{UserID: 1, Timestamp: clock.Now(), Body: "or me"})
+		assert.Equal(t, err, nil)
+
+		for _, userID := range []uint{2, 3} {
+			added, err := repo.AddLike(userID, tweetID)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, added, true)
+		}
+
+		clock.Advance(time.Second)
+		added, err := repo.AddLike(2, otherID)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, added, true)
+
+		added, err = repo.AddLike(2, tweetID)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, added, false)
+
+		counts, err := repo.GetTweetCounts([]uuid.UUID{tweetID, otherID})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, counts[0].LikeCount, int64(2))
+		assert.Equal(t, counts[1].LikeCount, int64(1))
+
+		//newest like first
+		likes, err := repo.GetUserLikes(2)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, likes, []uuid.UUID{otherID, tweetID})
+
+		likedBy, err := repo.GetLikedBy(3, []uuid.UUID{otherID, tweetID})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, likedBy, []bool{false, true})
+
+		removed, err := repo.RemoveLike(3, tweetID)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, removed, true)
+
+		removed, err = repo.RemoveLike(3, tweetID)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, removed, false)
+
+		likes, err = repo.GetUserLikes(3)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(likes), 0)
+
+		tweets, _, err := repo.GetTweets([]uuid.UUID{tweetID})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, tweets[0].LikeCount, int64(1))
+
+		_, err = repo.DeleteTweet(models.Tweet{ID: tweetID, UserID: 1}, nil)
+		assert.Equal(t, err, nil)
+
+		likedBy, err = repo.GetLikedBy(2, []uuid.UUID{tweetID})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, likedBy, []bool{false})
+	})
+}
+
+func TestNotifications(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		readAt, err := repo.GetNotificationsReadAt(1)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, readAt.IsZero(), true)
+
+		var notifications []models.Notification
+		for _, notificationType := range []string{models.NotificationMention, models.NotificationLike} {
+			clock.Advance(time.Second)
+			notification := models.Notification{
+				ID:        uuid.New(),
+				Type:      notificationType,
+				UserID:    1,
+				ActorID:   2,
+				TweetID:   uuid.New(),
+				Timestamp: clock.Now(),
+			}
+			assert.Equal(t, repo.AddNotification(notification), nil)
+			notifications = append(notifications, notification)
+		}
+
+		assert.Equal(t, repo.AddNotification(models.Notification{ID: uuid.New(), UserID: 3, Timestamp: clock.Now()}), nil)
+
+		//newest first
+		inbox, err := repo.GetNotifications(1)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(inbox), 2)
+		for i, notification := range inbox {
+			expected := notifications[1-i]
+			assert.Equal(t, notification.ID, expected.ID)
+			assert.Equal(t, notification.Type, expected.Type)
+			assert.Equal(t, notification.ActorID, expected.ActorID)
+			assert.Equal(t, notification.TweetID, expected.TweetID)
+			assert.Equal(t, notification.Timestamp.Equal(expected.Timestamp), true)
+		}
+
+		assert.Equal(t, repo.SetNotificationsReadAt(1, notifications[0].Timestamp), nil)
+
+		readAt, err = repo.GetNotificationsReadAt(1)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, readAt.Equal(notifications[0].Timestamp), true)
+
+		readAt, err = repo.GetNotificationsReadAt(3)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, readAt.IsZero(), true)
+	})
+}
+
+func TestHashtags(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		start := clock.Now()
+
+		var ids []uuid.UUID
+		for _, body := range []string{"#go", "#go #uala", "#uala"} {
+			clock.Advance(time.Minute)
+			tweet, err := models.NewTweet(1, clock.Now(), body, 0)
+			assert.Equal(t, err, nil)
+
+			id, err := repo.CreateTweet(*tweet)
+			assert.Equal(t, err, nil)
+			ids = append(ids, id)
+		}
+
+		tweets, err := repo.GetHashtagTweets("go")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, tweets, []uuid.UUID{ids[1], ids[0]})
+
+		tweets, err = repo.GetHashtagTweets("none")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(tweets), 0)
+
+		found, _, err := repo.GetTweets([]uuid.UUID{ids[1]})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found[0].Hashtags, []string{"go", "uala"})
+
+		//tweets can be longer than models.MaxLength, and so their hashtags
+		long := strings.Repeat("a", 200)
+		tweet, err := models.NewTweet(2, start, "#"+long, 300)
+		assert.Equal(t, err, nil)
+
+		longID, err := repo.CreateTweet(*tweet)
+		assert.Equal(t, err, nil)
+
+		tweets, err = repo.GetHashtagTweets(long)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, tweets, []uuid.UUID{longID})
+
+		uses, err := repo.GetHashtagUses(start.Add(90 * time.Second))
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(uses), 2)
+		assert.Equal(t, len(uses["go"]), 1)
+		assert.Equal(t, uses["go"][0].Equal(start.Add(2*time.Minute)), true)
+		assert.Equal(t, len(uses["uala"]), 2)
+
+		//edits reindex the tweet at its timestamp
+		editedAt := clock.Now()
+		edited, err := repo.EditTweet(models.Tweet{ID: ids[0], UserID: 1, Body: "#uala", Hashtags: []string{"uala"}, EditedAt: &editedAt})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, edited, true)
+
+		tweets, err = repo.GetHashtagTweets("go")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, tweets, []uuid.UUID{ids[1]})
+
+		tweets, err = repo.GetHashtagTweets("uala")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, tweets, []uuid.UUID{ids[2], ids[1], ids[0]})
+
+		_, err = repo.DeleteTweet(models.Tweet{ID: ids[2], UserID: 1, Hashtags: []string{"uala"}}, nil)
+		assert.Equal(t, err, nil)
+
+		uses, err = repo.GetHashtagUses(start)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(uses["uala"]), 2)
+		assert.Equal(t, len(uses["go"]), 1)
+
+		if !backend.durable {
+			//expired tweets aren't used anymore
+			clock.Advance(testLimits.TweetTTL)
+
+			uses, err = repo.GetHashtagUses(start)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, len(uses), 0)
+		}
+	})
+}
+
+func TestSearchTweets(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		start := clock.Now()
+
+		var ids []uuid.UUID
+		for _, tweet := range []models.Tweet{
+			{UserID: 1, Body: "Hola mundo"},
+			{UserID: 2, Body: "hola @ana, qué tal el mundo?"},
+			{UserID: 1, Body: "chau"},
+		} {
+			clock.Advance(time.Minute)
+			tweet.Timestamp = clock.Now()
+
+			id, err := repo.CreateTweet(tweet)
+			assert.Equal(t, err, nil)
+			ids = append(ids, id)
+		}
+
+		found, err := repo.SearchTweets([]string{"hola", "mundo"}, 0, time.Time{})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, []uuid.UUID{ids[1], ids[0]})
+
+		found, err = repo.SearchTweets([]string{"hola", "chau"}, 0, time.Time{})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(found), 0)
+
+		found, err = repo.SearchTweets([]string{"qué", "ana"}, 0, time.Time{})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, []uuid.UUID{ids[1]})
+
+		found, err = repo.SearchTweets([]string{"hola"}, 1, time.Time{})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, []uuid.UUID{ids[0]})
+
+		found, err = repo.SearchTweets(nil, 1, time.Time{})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, []uuid.UUID{ids[2], ids[0]})
+
+		found, err = repo.SearchTweets([]string{"hola"}, 0, start.Add(90*time.Second))
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, []uuid.UUID{ids[1]})
+
+		//since is compared as an instant, whatever its time zone
+		found, err = repo.SearchTweets([]string{"hola"}, 0, start.Add(90*time.Second).In(time.FixedZone("ART", -3*60*60)))
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, []uuid.UUID{ids[1]})
+
+		//edits reindex the new body
+		editedAt := clock.Now()
+		edited, err := repo.EditTweet(models.Tweet{ID: ids[2], UserID: 1, Body: "hola otra vez", EditedAt: &editedAt})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, edited, true)
+
+		found, err = repo.SearchTweets([]string{"chau"}, 0, time.Time{})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(found), 0)
+
+		found, err = repo.SearchTweets([]string{"hola"}, 0, time.Time{})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, []uuid.UUID{ids[2], ids[1], ids[0]})
+
+		_, err = repo.DeleteTweet(models.Tweet{ID: ids[1], UserID: 2, Body: "hola @ana, qué tal el mundo?"}, nil)
+		assert.Equal(t, err, nil)
+
+		found, err = repo.SearchTweets([]string{"mundo"}, 0, time.Time{})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, []uuid.UUID{ids[0]})
+
+		if !backend.durable {
+			//expired tweets are removed from the index
+			clock.Advance(testLimits.TweetTTL)
+
+			found, err = repo.SearchTweets([]string{"hola"}, 0, time.Time{})
+			assert.Equal(t, err, nil)
+			assert.Equal(t, len(found), 0)
+		}
+	})
+}
+
+func TestGetUserTweets(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		var ids []uuid.UUID
+		for i := 0; i < 3; i++ {
+			clock.Advance(time.Second)
+			id, err := repo.CreateTweet(models.Tweet{UserID: 1, Timestamp: clock.Now(), Body: "tweet"})
+			assert.Equal(t, err, nil)
+			ids = append(ids, id)
+		}
+
+		_, err := repo.CreateTweet(models.Tweet{UserID: 2, Timestamp: clock.Now(), Body: "other"})
+		assert.Equal(t, err, nil)
+
+		latest, err := repo.GetUserTweets(1, 2)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, latest, []uuid.UUID{ids[2], ids[1]})
+
+		latest, err = repo.GetUserTweets(3, 2)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(latest), 0)
+	})
+}
+
+func TestGetUserTweetsAcrossTimeZones(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		//the newer tweet has an earlier local time
+		art := time.FixedZone("ART", -3*60*60)
+		pst := time.FixedZone("PST", -8*60*60)
+
+		older, err := repo.CreateTweet(models.Tweet{UserID: 1, Timestamp: clock.Now().In(art), Body: "older"})
+		assert.Equal(t, err, nil)
+
+		clock.Advance(time.Hour)
+		newer, err := repo.CreateTweet(models.Tweet{UserID: 1, Timestamp: clock.Now().In(pst), Body: "newer"})
+		assert.Equal(t, err, nil)
+
+		latest, err := repo.GetUserTweets(1, 2)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, latest, []uuid.UUID{newer, older})
+	})
+}
+
+func TestReplaceTimeline(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		first, second, third := uuid.New(), uuid.New(), uuid.New()
+		assert.Equal(t, repo.AddTweetToTimeline(first, 1), nil)
+
+		replaced, err := repo.ReplaceTimeline(1, []uuid.UUID{first}, []uuid.UUID{second, first})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, replaced, true)
+
+		timeline, err := repo.GetTimeLine(1)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, timeline, []uuid.UUID{second, first})
+
+		//a tweet pushed meanwhile makes the replacement fail
+		assert.Equal(t, repo.AddTweetToTimeline(third, 1), nil)
+
+		replaced, err = repo.ReplaceTimeline(1, []uuid.UUID{second, first}, []uuid.UUID{first})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, replaced, false)
+
+		timeline, err = repo.GetTimeLine(1)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, timeline, []uuid.UUID{third, second, first})
+
+		//replacements are capped like pushes
+		ids := make([]uuid.UUID, 0, testTimelineSize+2)
+		for range testTimelineSize + 2 {
+			ids = append(ids, uuid.New())
+		}
+
+		replaced, err = repo.ReplaceTimeline(1, timeline, ids)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, replaced, true)
+
+		timeline, err = repo.GetTimeLine(1)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, timeline, ids[:testTimelineSize])
+	})
+}
+
+func TestTimelineIsCapped(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		ids := make([]uuid.UUID, 0, testTimelineSize+2)
+		for range testTimelineSize + 2 {
+			id := uuid.New()
+			ids = append(ids, id)
+			assert.Equal(t, repo.AddTweetToTimeline(id, 1), nil)
+		}
+
+		timeline, err := repo.GetTimeLine(1)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(timeline), testTimelineSize)
+		assert.Equal(t, timeline[0], ids[len(ids)-1])
+		assert.Equal(t, timeline[len(timeline)-1], ids[2])
+	})
+}
+
+func TestTimelineCapHoldsUnderConcurrentFanOut(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		followers := []uint{1, 2, 3}
+
+		var wg sync.WaitGroup
+		for range 5 * testTimelineSize {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.Equal(t, repo.AddTweetToTimelines(uuid.New(), followers), nil)
+			}()
+		}
+		wg.Wait()
+
+		for _, follower := range followers {
+			timeline, err := repo.GetTimeLine(follower)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, len(timeline), testTimelineSize)
+		}
+	})
+}
+
+func TestConcurrentLikesAreAllCounted(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		tweetID, err := repo.CreateTweet(models.Tweet{UserID: 1, Body: "hola", Timestamp: clock.Now()})
+		assert.Equal(t, err, nil)
+
+		const likers = 50
+
+		var wg sync.WaitGroup
+		for userID := uint(1); userID <= likers; userID++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				added, err := repo.AddLike(userID, tweetID)
+				assert.Equal(t, err, nil)
+				assert.Equal(t, added, true)
+			}()
+		}
+		wg.Wait()
+
+		counts, err := repo.GetTweetCounts([]uuid.UUID{tweetID})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, counts[0].LikeCount, int64(likers))
+
+		likes, err := repo.GetUserLikes(likers)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, likes, []uuid.UUID{tweetID})
+	})
+}
+
+func TestSessionsArePerUserAndExpire(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		_, found, err := repo.GetSession("unknown")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, false)
+
+		first := models.Session{ID: "first", UserID: 1, CreatedAt: clock.Now()}
+		second := models.Session{ID: "second", UserID: 2, CreatedAt: clock.Now()}
+		assert.Equal(t, repo.CreateSession(first), nil)
+		assert.Equal(t, repo.CreateSession(second), nil)
+
+		session, found, err := repo.GetSession("first")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, true)
+		assert.Equal(t, session.UserID, uint(1))
+
+		session, found, err = repo.GetSession("second")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, true)
+		assert.Equal(t, session.UserID, uint(2))
+
+		clock.Advance(testLimits.SessionTTL)
+
+		_, found, err = repo.GetSession("first")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, false)
+	})
+}
+
+func TestSessionManagement(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		first := models.Session{ID: "first", UserID: 1, CreatedAt: clock.Now(), LastUsedAt: clock.Now()}
+		assert.Equal(t, repo.CreateSession(first), nil)
+
+		second := models.Session{ID: "second", UserID: 1, CreatedAt: clock.Now().Add(time.Second), LastUsedAt: clock.Now()}
+		other := models.Session{ID: "other", UserID: 2, CreatedAt: clock.Now(), LastUsedAt: clock.Now()}
+		assert.Equal(t, repo.CreateSession(second), nil)
+		assert.Equal(t, repo.CreateSession(other), nil)
+
+		sessions, err := repo.GetSessions(1)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(sessions), 2)
+		assert.Equal(t, sessions[0].ID, "first")
+		assert.Equal(t, sessions[1].ID, "second")
+
+		//sliding expiration keeps the used session alive
+		clock.Advance(testLimits.SessionTTL - time.Hour)
+		first.LastUsedAt = clock.Now()
+		assert.Equal(t, repo.RefreshSession(first), nil)
+		clock.Advance(time.Hour + time.Minute)
+
+		sessions, err = repo.GetSessions(1)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(sessions), 1)
+		assert.Equal(t, sessions[0].ID, "first")
+		assert.Equal(t, sessions[0].LastUsedAt.Equal(first.LastUsedAt), true)
+
+		assert.Equal(t, repo.DeleteSessions(1, []string{"first"}), nil)
+
+		_, found, err := repo.GetSession("first")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, false)
+
+		//refreshing a deleted session doesn't bring it back
+		assert.Equal(t, repo.RefreshSession(first), nil)
+
+		_, found, err = repo.GetSession("first")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, false)
+	})
+}
+
+func TestUsers(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		user, err := models.NewUser("Uala", "password123", clock.Now())
+		assert.Equal(t, err, nil)
+		user.Profile = models.Profile{DisplayName: "Ualá", Bio: "challenge"}
+
+		id, err := repo.CreateUser(*user)
+		assert.Equal(t, err, nil)
+		assert.NotEqual(t, id, uint(0))
+
+		taken, err := models.NewUser("uALA", "password123", clock.Now())
+		assert.Equal(t, err, nil)
+
+		_, err = repo.CreateUser(*taken)
+		assert.Equal(t, err, repository.ErrHandleTaken)
+
+		stored, found, err := repo.GetUserByHandle("uala")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, true)
+		assert.Equal(t, stored.ID, id)
+		assert.Equal(t, stored.Handle, "Uala")
+		assert.Equal(t, stored.CheckPassword("password123"), true)
+		assert.Equal(t, stored.Profile, models.Profile{DisplayName: "Ualá", Bio: "challenge"})
+
+		assert.Equal(t, stored.SetPassword("new-password"), nil)
+		assert.Equal(t, repo.UpdatePassword(id, stored.PasswordHash), nil)
+		assert.Equal(t, repo.UpdateProfile(id, models.Profile{DisplayName: "Uala"}), nil)
+
+		stored, found, err = repo.GetUser(id)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, true)
+		assert.Equal(t, stored.CheckPassword("new-password"), true)
+		assert.Equal(t, stored.Profile, models.Profile{DisplayName: "Uala"})
+		assert.Equal(t, stored.CreatedAt.Equal(user.CreatedAt), true)
+
+		_, found, err = repo.GetUser(id + 1)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, false)
+
+		_, found, err = repo.GetUserByHandle("unknown")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, false)
+	})
+}
+
+func TestConcurrentRegistrationsOfAHandle(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		user, err := models.NewUser("uala", "password123", clock.Now())
+		assert.Equal(t, err, nil)
+
+		const registrations = 20
+
+		var mu sync.Mutex
+		created := 0
+
+		var wg sync.WaitGroup
+		for range registrations {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := repo.CreateUser(*user)
+				if err != nil {
+					assert.Equal(t, err, repository.ErrHandleTaken)
+					return
+				}
+
+				mu.Lock()
+				created++
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, created, 1)
+	})
+}
+
+func TestRenameHandle(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		ids := make([]uint, 0, 2)
+		for _, handle := range []string{"uala", "other"} {
+			user, err := models.NewUser(handle, "password123", clock.Now())
+			assert.Equal(t, err, nil)
+
+			id, err := repo.CreateUser(*user)
+			assert.Equal(t, err, nil)
+			ids = append(ids, id)
+		}
+
+		assert.Equal(t, repo.RenameHandle(ids[0], "OTHER", time.Hour), repository.ErrHandleTaken)
+		assert.Equal(t, repo.RenameHandle(ids[0], "Renamed", time.Hour), nil)
+
+		stored, found, err := repo.GetUserByHandle("renamed")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, true)
+		assert.Equal(t, stored.ID, ids[0])
+		assert.Equal(t, stored.Handle, "Renamed")
+
+		_, found, err = repo.GetUserByHandle("uala")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, false)
+
+		userID, found, err := repo.GetHandleRedirect("UALA")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, true)
+		assert.Equal(t, userID, ids[0])
+
+		//the old handle is kept for its owner while it redirects
+		taken, err := models.NewUser("Uala", "password123", clock.Now())
+		assert.Equal(t, err, nil)
+
+		_, err = repo.CreateUser(*taken)
+		assert.Equal(t, err, repository.ErrHandleTaken)
+		assert.Equal(t, repo.RenameHandle(ids[1], "uala", time.Hour), repository.ErrHandleTaken)
+
+		//changing only the case doesn't redirect
+		assert.Equal(t, repo.RenameHandle(ids[0], "renamed", time.Hour), nil)
+
+		_, found, err = repo.GetHandleRedirect("renamed")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, false)
+
+		clock.Advance(time.Hour)
+		_, found, err = repo.GetHandleRedirect("uala")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, false)
+
+		id, err := repo.CreateUser(*taken)
+		assert.Equal(t, err, nil)
+		assert.NotEqual(t, id, ids[0])
+	})
+}
+
+func TestConcurrentRenameAndProfileUpdates(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		user, err := models.NewUser("uala", "password123", clock.Now())
+		assert.Equal(t, err, nil)
+
+		id, err := repo.CreateUser(*user)
+		assert.Equal(t, err, nil)
+
+		profile := models.Profile{DisplayName: "Ualá", Bio: "challenge"}
+
+		var wg sync.WaitGroup
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, repo.RenameHandle(id, "renamed", time.Hour), nil)
+		}()
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, repo.UpdateProfile(id, profile), nil)
+		}()
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, repo.UpdatePassword(id, []byte("hash")), nil)
+		}()
+		wg.Wait()
+
+		stored, found, err := repo.GetUser(id)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, true)
+		assert.Equal(t, stored.Handle, "renamed")
+		assert.Equal(t, stored.Profile, profile)
+		assert.Equal(t, string(stored.PasswordHash), "hash")
+	})
+}
+
+func TestFailedLoginsExpire(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		attempts, err := repo.GetFailedLogins(1)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, attempts, int64(0))
+
+		for i := int64(1); i <= 3; i++ {
+			attempts, err = repo.AddFailedLogin(1)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, attempts, i)
+		}
+
+		//the window starts at the first failure
+		clock.Advance(repository.FailedLoginsTTL - time.Minute)
+		attempts, err = repo.AddFailedLogin(1)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, attempts, int64(4))
+
+		clock.Advance(time.Minute)
+		attempts, err = repo.GetFailedLogins(1)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, attempts, int64(0))
+
+		_, err = repo.AddFailedLogin(1)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, repo.ResetFailedLogins(1), nil)
+
+		attempts, err = repo.GetFailedLogins(1)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, attempts, int64(0))
+	})
+}
+
+func TestConcurrentFailedLoginsAreAllCounted(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		const logins = 20
+
+		var mu sync.Mutex
+		var counted []int64
+
+		var wg sync.WaitGroup
+		for range logins {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				attempts, err := repo.AddFailedLogin(1)
+				assert.Equal(t, err, nil)
+
+				mu.Lock()
+				counted = append(counted, attempts)
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+
+		//every attempt gets its own count
+		slices.Sort(counted)
+		for i, attempts := range counted {
+			assert.Equal(t, attempts, int64(i+1))
+		}
+	})
+}
+
+func TestPasswordResetsAreSingleUseAndExpire(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		user, err := models.NewUser("uala", "password123", clock.Now())
+		assert.Equal(t, err, nil)
+
+		id, err := repo.CreateUser(*user)
+		assert.Equal(t, err, nil)
+
+		assert.Equal(t, repo.CreatePasswordReset("first", id), nil)
+		assert.Equal(t, repo.CreatePasswordReset("second", id), nil)
+
+		userID, found, err := repo.ConsumePasswordReset("first")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, true)
+		assert.Equal(t, userID, id)
+
+		_, found, err = repo.ConsumePasswordReset("first")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, false)
+
+		clock.Advance(repository.PasswordResetTTL)
+
+		_, found, err = repo.ConsumePasswordReset("second")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, false)
+	})
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend backend, repo repository.IRepository, clock *fakeClock) {
+		var wg sync.WaitGroup
+		for follower := uint(2); follower < 50; follower++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.Equal(t, repo.AddFollower(1, follower), nil)
+				assert.Equal(t, repo.AddTweetToTimeline(uuid.New(), 1), nil)
+			}()
+		}
+		wg.Wait()
+
+		followers, err := repo.GetFollowers(1)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(followers), 48)
+
+		timeline, err := repo.GetTimeLine(1)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(timeline), testTimelineSize)
+	})
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	db := newSQLiteDatabase(t)
+
+	assert.Equal(t, repository.Migrate(db, repository.SQLDriverSQLite), nil)
+}
+
+func TestMigrateUnsupportedDriver(t *testing.T) {
+	db := newSQLiteDatabase(t)
+
+	err := repository.Migrate(db, "mysql")
+	assert.Equal(t, errors.Is(err, repository.ErrUnsupportedSQLDriver), true)
+}
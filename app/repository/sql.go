@@ -0,0 +1,1500 @@
+package repository
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/PatricioYegros/uala_challenge/app/models"
+	"github.com/PatricioYegros/uala_challenge/app/utils"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+const (
+	SQLDriverSQLite   = "sqlite3"
+	SQLDriverPostgres = "postgres"
+)
+
+var ErrUnsupportedSQLDriver = errors.New("unsupported sql driver")
+
+//go:embed migrations
+var migrations embed.FS
+
+// SQLRepository implements IRepository over a SQL database (SQLite or PostgreSQL).
+// Tweets are durable: unlike Repository they are never expired.
+// Queries use $N placeholders in increasing order of appearance, since SQLite numbers them that way.
+type SQLRepository struct {
+	DB    *sql.DB
+	Clock utils.IClock
+	Limits
+}
+
+// Migrate applies the pending schema migrations of driver, in order, to db
+// Returns ErrUnsupportedSQLDriver if there are no migrations for driver
+func Migrate(db *sql.DB, driver string) error {
+	dir := path.Join("migrations", driver)
+
+	files, err := fs.ReadDir(migrations, dir)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrUnsupportedSQLDriver, driver)
+	}
+
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (version VARCHAR(255) PRIMARY KEY)")
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		version := strings.TrimSuffix(file.Name(), ".sql")
+
+		var applied int
+		err = db.QueryRow("SELECT COUNT(*) FROM schema_migrations WHERE version = $1", version).Scan(&applied)
+		if err != nil {
+			return err
+		}
+
+		if applied > 0 {
+			continue
+		}
+
+		script, err := fs.ReadFile(migrations, path.Join(dir, file.Name()))
+		if err != nil {
+			return err
+		}
+
+		err = inTransaction(db, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(string(script)); err != nil {
+				return fmt.Errorf("migration %s: %w", version, err)
+			}
+
+			_, err := tx.Exec("INSERT INTO schema_migrations (version) VALUES ($1)", version)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AddFollower adds the newFollowerID to the list of followers of userID
+func (repository SQLRepository) AddFollower(userID, newFollowerID uint) error {
+	return inTransaction(repository.DB, func(tx *sql.Tx) error {
+		err := repository.ensureUsers(tx, userID, newFollowerID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(
+			"INSERT INTO follows (user_id, follower_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+			userID, newFollowerID,
+		)
+		return err
+	})
+}
+
+// RemoveFollower removes followerID from the list of followers of userID. Returns false if it wasn't following.
+func (repository SQLRepository) RemoveFollower(userID, followerID uint) (bool, error) {
+	result, err := repository.DB.Exec("DELETE FROM follows WHERE user_id = $1 AND follower_id = $2", userID, followerID)
+	if err != nil {
+		return false, err
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return removed > 0, nil
+}
+
+// GetFollowers returns the list of followers of userID
+func (repository SQLRepository) GetFollowers(userID uint) ([]uint, error) {
+	return repository.queryUserIDs("SELECT follower_id FROM follows WHERE user_id = $1 ORDER BY follower_id", userID)
+}
+
+// GetFollowing returns the list of users followed by userID
+func (repository SQLRepository) GetFollowing(userID uint) ([]uint, error) {
+	return repository.queryUserIDs("SELECT user_id FROM follows WHERE follower_id = $1 ORDER BY user_id", userID)
+}
+
+// ScanFollowers returns a page of count followers of userID with ids greater than cursor, and the cursor of the next page
+func (repository SQLRepository) ScanFollowers(userID uint, cursor uint64, count int64) ([]uint, uint64, error) {
+	ids, err := repository.queryUserIDs(
+		"SELECT follower_id FROM follows WHERE user_id = $1 AND follower_id > $2 ORDER BY follower_id LIMIT $3",
+		userID, cursor, count+1,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids, next := nextPage(ids, count)
+
+	return ids, next, nil
+}
+
+// ScanFollowing returns a page of the users followed by userID like ScanFollowers
+func (repository SQLRepository) ScanFollowing(userID uint, cursor uint64, count int64) ([]uint, uint64, error) {
+	ids, err := repository.queryUserIDs(
+		"SELECT user_id FROM follows WHERE follower_id = $1 AND user_id > $2 ORDER BY user_id LIMIT $3",
+		userID, cursor, count+1,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids, next := nextPage(ids, count)
+
+	return ids, next, nil
+}
+
+// CountFollowers returns the amount of followers of userID
+func (repository SQLRepository) CountFollowers(userID uint) (int64, error) {
+	var count int64
+
+	err := repository.DB.QueryRow("SELECT COUNT(*) FROM follows WHERE user_id = $1", userID).Scan(&count)
+
+	return count, err
+}
+
+// CountFollowing returns the amount of users followed by userID
+func (repository SQLRepository) CountFollowing(userID uint) (int64, error) {
+	var count int64
+
+	err := repository.DB.QueryRow("SELECT COUNT(*) FROM follows WHERE follower_id = $1", userID).Scan(&count)
+
+	return count, err
+}
+
+// CountFollowersOf returns the amount of followers of every user in userIDs, in the same order
+func (repository SQLRepository) CountFollowersOf(userIDs []uint) ([]int64, error) {
+	counts := make([]int64, len(userIDs))
+
+	if len(userIDs) == 0 {
+		return counts, nil
+	}
+
+	placeholders := make([]string, 0, len(userIDs))
+	args := make([]any, 0, len(userIDs))
+	for i, userID := range userIDs {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+		args = append(args, userID)
+	}
+
+	rows, err := repository.DB.Query(
+		"SELECT user_id, COUNT(*) FROM follows WHERE user_id IN ("+strings.Join(placeholders, ", ")+") GROUP BY user_id",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[uint]int64, len(userIDs))
+
+	for rows.Next() {
+		var userID uint
+		var count int64
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, err
+		}
+		found[userID] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, userID := range userIDs {
+		counts[i] = found[userID]
+	}
+
+	return counts, nil
+}
+
+func (repository SQLRepository) queryUserIDs(query string, args ...any) ([]uint, error) {
+	rows, err := repository.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]uint, 0)
+
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// scanTweetIDs reads and closes rows of a single tweet id column
+func scanTweetIDs(rows *sql.Rows) ([]uuid.UUID, error) {
+	defer rows.Close()
+
+	ids := make([]uuid.UUID, 0)
+
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// nextPage cuts ids queried with a limit of count+1 to count, returning the last id as cursor if there are more
+func nextPage(ids []uint, count int64) ([]uint, uint64) {
+	if int64(len(ids)) <= count {
+		return ids, 0
+	}
+
+	ids = ids[:count]
+
+	return ids, uint64(ids[len(ids)-1])
+}
+
+// CreateTweet creates a new tweet and returns the uuid
+func (repository SQLRepository) CreateTweet(tweet models.Tweet) (uuid.UUID, error) {
+	tweetID := uuid.New()
+	if tweet.ConversationID == uuid.Nil {
+		tweet.ConversationID = tweetID
+	}
+
+	err := inTransaction(repository.DB, func(tx *sql.Tx) error {
+		err := repository.ensureUsers(tx, tweet.UserID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(
+			`INSERT INTO tweets (id, user_id, created_at, body, in_reply_to, conversation_id, quoted_tweet_id)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			tweetID.String(), tweet.UserID, tweet.Timestamp.UTC(), tweet.Body, tweet.InReplyTo, tweet.ConversationID.String(),
+			tweet.QuotedTweetID,
+		)
+		if err != nil {
+			return err
+		}
+
+		err = repository.insertMentions(tx, tweetID, tweet.Mentions)
+		if err != nil {
+			return err
+		}
+
+		err = insertHashtags(tx, tweetID, tweet.Hashtags, tweet.Timestamp)
+		if err != nil {
+			return err
+		}
+
+		return insertSearchTerms(tx, tweetID, models.SearchTerms(tweet.Body))
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return tweetID, nil
+}
+
+// insertMentions stores the users mentioned in tweetID
+func (repository SQLRepository) insertMentions(tx *sql.Tx, tweetID uuid.UUID, mentions []uint) error {
+	err := repository.ensureUsers(tx, mentions...)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range mentions {
+		_, err := tx.Exec("INSERT INTO mentions (tweet_id, user_id) VALUES ($1, $2)", tweetID.String(), userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetUserTweets returns the ids of the latest count tweets of userID, newest first
+func (repository SQLRepository) GetUserTweets(userID uint, count int64) ([]uuid.UUID, error) {
+	rows, err := repository.DB.Query(
+		"SELECT id FROM tweets WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2",
+		userID, count,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanTweetIDs(rows)
+}
+
+// GetTweets returns the tweets found by ids with their counts and quoted tweets, in the same order, and the ids that
+// don't exist anymore
+func (repository SQLRepository) GetTweets(ids []uuid.UUID) ([]models.Tweet, []uuid.UUID, error) {
+	tweets, missing, err := repository.getCountedTweets(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tweets, missing, withQuotes(tweets, repository.getCountedTweets)
+}
+
+// getCountedTweets returns the tweets found by ids with their counts, in the same order, and the ids that don't exist anymore
+func (repository SQLRepository) getCountedTweets(ids []uuid.UUID) ([]models.Tweet, []uuid.UUID, error) {
+	tweets := make([]models.Tweet, 0, len(ids))
+	var missing []uuid.UUID
+
+	if len(ids) == 0 {
+		return tweets, missing, nil
+	}
+
+	placeholders := make([]string, 0, len(ids))
+	args := make([]any, 0, len(ids))
+	for i, id := range ids {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+		args = append(args, id.String())
+	}
+
+	rows, err := repository.DB.Query(
+		"SELECT id, user_id, created_at, body, edited_at, in_reply_to, conversation_id, quoted_tweet_id FROM tweets WHERE id IN ("+strings.Join(placeholders, ", ")+")",
+		args...,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[uuid.UUID]models.Tweet, len(ids))
+
+	for rows.Next() {
+		var tweet models.Tweet
+		err := rows.Scan(
+			&tweet.ID, &tweet.UserID, &tweet.Timestamp, &tweet.Body, &tweet.EditedAt,
+			&tweet.InReplyTo, &tweet.ConversationID, &tweet.QuotedTweetID,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		found[tweet.ID] = tweet
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	mentions, err := repository.getMentions(placeholders, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, id := range ids {
+		tweet, ok := found[id]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+
+		tweet.Mentions = mentions[id]
+		//the index doesn't keep the order of the hashtags, the body does
+		tweet.Hashtags = models.ParseHashtags(tweet.Body)
+		tweets = append(tweets, tweet)
+	}
+
+	return tweets, missing, withCounts(tweets, repository.GetTweetCounts)
+}
+
+// insertHashtags indexes tweetID, created at createdAt, by its hashtags
+func insertHashtags(tx *sql.Tx, tweetID uuid.UUID, hashtags []string, createdAt time.Time) error {
+	for _, hashtag := range hashtags {
+		_, err := tx.Exec(
+			"INSERT INTO hashtags (hashtag, tweet_id, created_at) VALUES ($1, $2, $3)",
+			hashtag, tweetID.String(), createdAt.UTC(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insertSearchTerms indexes tweetID by the search terms of its body
+func insertSearchTerms(tx *sql.Tx, tweetID uuid.UUID, terms []string) error {
+	for _, term := range terms {
+		_, err := tx.Exec("INSERT INTO search_terms (term, tweet_id) VALUES ($1, $2)", term, tweetID.String())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SearchTweets returns the ids of the latest MaxSearchResults tweets with every term in terms, of userID unless it's 0,
+// created since since, newest first
+func (repository SQLRepository) SearchTweets(terms []string, userID uint, since time.Time) ([]uuid.UUID, error) {
+	if len(terms) == 0 && userID == 0 {
+		return nil, nil
+	}
+
+	args := []any{since.UTC()}
+	query := "SELECT id FROM tweets WHERE created_at >= $1"
+
+	if userID != 0 {
+		args = append(args, userID)
+		query += fmt.Sprintf(" AND user_id = $%d", len(args))
+	}
+
+	if len(terms) > 0 {
+		placeholders := make([]string, 0, len(terms))
+		for _, term := range terms {
+			args = append(args, term)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+
+		args = append(args, len(terms))
+		query += " AND id IN (SELECT tweet_id FROM search_terms WHERE term IN (" + strings.Join(placeholders, ", ") +
+			fmt.Sprintf(") GROUP BY tweet_id HAVING COUNT(*) = $%d)", len(args))
+	}
+
+	args = append(args, MaxSearchResults)
+	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d", len(args))
+
+	rows, err := repository.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanTweetIDs(rows)
+}
+
+// GetHashtagTweets returns the ids of the latest MaxHashtagTweets tweets with hashtag, newest first
+func (repository SQLRepository) GetHashtagTweets(hashtag string) ([]uuid.UUID, error) {
+	rows, err := repository.DB.Query(
+		"SELECT tweet_id FROM hashtags WHERE hashtag = $1 ORDER BY created_at DESC LIMIT $2",
+		hashtag, MaxHashtagTweets,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanTweetIDs(rows)
+}
+
+// GetHashtagUses returns the timestamps of the tweets of every hashtag used since since, by hashtag
+func (repository SQLRepository) GetHashtagUses(since time.Time) (map[string][]time.Time, error) {
+	rows, err := repository.DB.Query("SELECT hashtag, created_at FROM hashtags WHERE created_at >= $1", since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	uses := make(map[string][]time.Time)
+
+	for rows.Next() {
+		var hashtag string
+		var createdAt time.Time
+		if err := rows.Scan(&hashtag, &createdAt); err != nil {
+			return nil, err
+		}
+		uses[hashtag] = append(uses[hashtag], createdAt)
+	}
+
+	return uses, rows.Err()
+}
+
+// getMentions returns the sorted ids of the users mentioned in the tweets with ids args, by tweet id
+func (repository SQLRepository) getMentions(placeholders []string, args []any) (map[uuid.UUID][]uint, error) {
+	rows, err := repository.DB.Query(
+		"SELECT tweet_id, user_id FROM mentions WHERE tweet_id IN ("+strings.Join(placeholders, ", ")+") ORDER BY user_id",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentions := make(map[uuid.UUID][]uint)
+
+	for rows.Next() {
+		var tweetID uuid.UUID
+		var userID uint
+		if err := rows.Scan(&tweetID, &userID); err != nil {
+			return nil, err
+		}
+		mentions[tweetID] = append(mentions[tweetID], userID)
+	}
+
+	return mentions, rows.Err()
+}
+
+// GetTweetCounts returns the counts of every tweet in tweetIDs, in the same order
+func (repository SQLRepository) GetTweetCounts(tweetIDs []uuid.UUID) ([]models.TweetCounts, error) {
+	counts := make([]models.TweetCounts, len(tweetIDs))
+
+	if len(tweetIDs) == 0 {
+		return counts, nil
+	}
+
+	placeholders := make([]string, 0, len(tweetIDs))
+	args := make([]any, 0, len(tweetIDs))
+	for i, tweetID := range tweetIDs {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+		args = append(args, tweetID.String())
+	}
+
+	in := "(" + strings.Join(placeholders, ", ") + ")"
+
+	replyCounts, err := repository.countTweets("SELECT in_reply_to, COUNT(*) FROM tweets WHERE in_reply_to IN "+in+" GROUP BY in_reply_to", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	likeCounts, err := repository.countTweets("SELECT tweet_id, COUNT(*) FROM likes WHERE tweet_id IN "+in+" GROUP BY tweet_id", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, tweetID := range tweetIDs {
+		counts[i].ReplyCount = replyCounts[tweetID]
+		counts[i].LikeCount = likeCounts[tweetID]
+	}
+
+	return counts, nil
+}
+
+// countTweets returns the counts of a query of tweet ids and counts, by tweet id
+func (repository SQLRepository) countTweets(query string, args ...any) (map[uuid.UUID]int64, error) {
+	rows, err := repository.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[uuid.UUID]int64)
+
+	for rows.Next() {
+		var tweetID uuid.UUID
+		var count int64
+		if err := rows.Scan(&tweetID, &count); err != nil {
+			return nil, err
+		}
+		counts[tweetID] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// AddRetweet records that userID retweeted tweetID. Returns false if it was already retweeted.
+func (repository SQLRepository) AddRetweet(userID uint, tweetID uuid.UUID) (bool, error) {
+	added := false
+
+	err := inTransaction(repository.DB, func(tx *sql.Tx) error {
+		err := repository.ensureUsers(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(
+			"INSERT INTO retweets (tweet_id, user_id, created_at) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING",
+			tweetID.String(), userID, repository.Clock.Now().UTC(),
+		)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		added = affected > 0
+
+		return nil
+	})
+
+	return added, err
+}
+
+// RemoveRetweet removes the retweet of tweetID by userID. Returns false if it wasn't retweeted.
+func (repository SQLRepository) RemoveRetweet(userID uint, tweetID uuid.UUID) (bool, error) {
+	result, err := repository.DB.Exec("DELETE FROM retweets WHERE tweet_id = $1 AND user_id = $2", tweetID.String(), userID)
+	if err != nil {
+		return false, err
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return removed > 0, nil
+}
+
+// GetRetweeters returns the users that retweeted every tweet in tweetIDs, sorted by id, in the same order
+func (repository SQLRepository) GetRetweeters(tweetIDs []uuid.UUID) ([][]uint, error) {
+	retweeters := make([][]uint, len(tweetIDs))
+
+	if len(tweetIDs) == 0 {
+		return retweeters, nil
+	}
+
+	placeholders := make([]string, 0, len(tweetIDs))
+	args := make([]any, 0, len(tweetIDs))
+	for i, tweetID := range tweetIDs {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+		args = append(args, tweetID.String())
+	}
+
+	rows, err := repository.DB.Query(
+		"SELECT tweet_id, user_id FROM retweets WHERE tweet_id IN ("+strings.Join(placeholders, ", ")+") ORDER BY user_id",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[uuid.UUID][]uint, len(tweetIDs))
+
+	for rows.Next() {
+		var tweetID uuid.UUID
+		var userID uint
+		if err := rows.Scan(&tweetID, &userID); err != nil {
+			return nil, err
+		}
+		found[tweetID] = append(found[tweetID], userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, tweetID := range tweetIDs {
+		retweeters[i] = found[tweetID]
+		if retweeters[i] == nil {
+			retweeters[i] = []uint{}
+		}
+	}
+
+	return retweeters, nil
+}
+
+// AddLike records that userID likes tweetID. Returns false if it was already liked.
+func (repository SQLRepository) AddLike(userID uint, tweetID uuid.UUID) (bool, error) {
+	added := false
+
+	err := inTransaction(repository.DB, func(tx *sql.Tx) error {
+		err := repository.ensureUsers(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(
+			"INSERT INTO likes (tweet_id, user_id, created_at) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING",
+			tweetID.String(), userID, repository.Clock.Now().UTC(),
+		)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		added = affected > 0
+
+		return nil
+	})
+
+	return added, err
+}
+
+// RemoveLike removes the like of userID to tweetID. Returns false if it wasn't liked.
+func (repository SQLRepository) RemoveLike(userID uint, tweetID uuid.UUID) (bool, error) {
+	result, err := repository.DB.Exec("DELETE FROM likes WHERE tweet_id = $1 AND user_id = $2", tweetID.String(), userID)
+	if err != nil {
+		return false, err
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return removed > 0, nil
+}
+
+// GetLikedBy tells if userID likes every tweet in tweetIDs, in the same order
+func (repository SQLRepository) GetLikedBy(userID uint, tweetIDs []uuid.UUID) ([]bool, error) {
+	liked := make([]bool, len(tweetIDs))
+
+	if len(tweetIDs) == 0 {
+		return liked, nil
+	}
+
+	placeholders := make([]string, 0, len(tweetIDs))
+	args := []any{userID}
+	for i, tweetID := range tweetIDs {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+2))
+		args = append(args, tweetID.String())
+	}
+
+	rows, err := repository.DB.Query(
+		"SELECT tweet_id FROM likes WHERE user_id = $1 AND tweet_id IN ("+strings.Join(placeholders, ", ")+")",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	likedIDs, err := scanTweetIDs(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, tweetID := range tweetIDs {
+		liked[i] = slices.Contains(likedIDs, tweetID)
+	}
+
+	return liked, nil
+}
+
+// GetUserLikes returns the ids of the latest MaxUserLikes tweets liked by userID, newest like first
+func (repository SQLRepository) GetUserLikes(userID uint) ([]uuid.UUID, error) {
+	rows, err := repository.DB.Query(
+		"SELECT tweet_id FROM likes WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2",
+		userID, MaxUserLikes,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanTweetIDs(rows)
+}
+
+// GetConversation returns the ids of the replies of a conversation, oldest first
+func (repository SQLRepository) GetConversation(conversationID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := repository.DB.Query(
+		"SELECT id FROM tweets WHERE conversation_id = $1 AND id <> $1 ORDER BY created_at, id",
+		conversationID.String(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanTweetIDs(rows)
+}
+
+// DeleteTweet deletes the tweet and removes it from the timelines of timelineUserIDs.
+// Returns false if it didn't exist. Entries of the tweet are removed from every timeline, as they reference it.
+// Replies of the tweet are kept.
+func (repository SQLRepository) DeleteTweet(tweet models.Tweet, timelineUserIDs []uint) (bool, error) {
+	tweetID, userID := tweet.ID, tweet.UserID
+	deleted := false
+
+	err := inTransaction(repository.DB, func(tx *sql.Tx) error {
+		for _, table := range []string{"timeline_entries", "tweet_revisions", "retweets", "likes", "mentions", "hashtags", "search_terms"} {
+			_, err := tx.Exec(
+				"DELETE FROM "+table+" WHERE tweet_id IN (SELECT id FROM tweets WHERE id = $1 AND user_id = $2)",
+				tweetID.String(), userID,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		result, err := tx.Exec("DELETE FROM tweets WHERE id = $1 AND user_id = $2", tweetID.String(), userID)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		deleted = affected > 0
+
+		return nil
+	})
+
+	return deleted, err
+}
+
+// EditTweet replaces the body, mentions and hashtags of the tweet edited.ID of edited.UserID with the ones of edited,
+// edited at edited.EditedAt, keeping the previous revision in its history. Returns false if it doesn't exist.
+func (repository SQLRepository) EditTweet(edited models.Tweet) (bool, error) {
+	tweetID := edited.ID
+	found := false
+
+	err := inTransaction(repository.DB, func(tx *sql.Tx) error {
+		var tweet models.Tweet
+
+		err := tx.QueryRow(
+			"SELECT body, created_at, edited_at FROM tweets WHERE id = $1 AND user_id = $2",
+			tweetID.String(), edited.UserID,
+		).Scan(&tweet.Body, &tweet.Timestamp, &tweet.EditedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		revision := tweet.Revision()
+
+		_, err = tx.Exec(
+			"INSERT INTO tweet_revisions (tweet_id, body, created_at) VALUES ($1, $2, $3)",
+			tweetID.String(), revision.Body, revision.Timestamp.UTC(),
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("UPDATE tweets SET body = $1, edited_at = $2 WHERE id = $3", edited.Body, edited.EditedAt.UTC(), tweetID.String())
+		if err != nil {
+			return err
+		}
+
+		for _, table := range []string{"mentions", "hashtags", "search_terms"} {
+			_, err = tx.Exec("DELETE FROM "+table+" WHERE tweet_id = $1", tweetID.String())
+			if err != nil {
+				return err
+			}
+		}
+
+		err = repository.insertMentions(tx, tweetID, edited.Mentions)
+		if err != nil {
+			return err
+		}
+
+		err = insertHashtags(tx, tweetID, edited.Hashtags, tweet.Timestamp)
+		if err != nil {
+			return err
+		}
+
+		err = insertSearchTerms(tx, tweetID, models.SearchTerms(edited.Body))
+		if err != nil {
+			return err
+		}
+
+		found = true
+
+		return nil
+	})
+
+	return found, err
+}
+
+// GetTweetHistory returns the previous revisions of a tweet, oldest first
+func (repository SQLRepository) GetTweetHistory(tweetID uuid.UUID) ([]models.TweetRevision, error) {
+	rows, err := repository.DB.Query(
+		"SELECT body, created_at FROM tweet_revisions WHERE tweet_id = $1 ORDER BY id",
+		tweetID.String(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.TweetRevision{}
+
+	for rows.Next() {
+		var revision models.TweetRevision
+		if err := rows.Scan(&revision.Body, &revision.Timestamp); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+// AddTweetToTimeline adds a tweetID to the user's timeline if it isn't there yet, keeping the latest TimelineSize.
+func (repository SQLRepository) AddTweetToTimeline(tweetID uuid.UUID, userID uint) error {
+	return repository.AddTweetToTimelines(tweetID, []uint{userID})
+}
+
+// AddTweetToTimelines adds a tweetID to the timeline of every user in userIDs that doesn't have it yet, in a single transaction.
+// Each keeps the latest TimelineSize.
+func (repository SQLRepository) AddTweetToTimelines(tweetID uuid.UUID, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	return inTransaction(repository.DB, func(tx *sql.Tx) error {
+		for _, userID := range userIDs {
+			var entries int
+			err := tx.QueryRow(
+				"SELECT COUNT(*) FROM timeline_entries WHERE user_id = $1 AND tweet_id = $2", userID, tweetID.String(),
+			).Scan(&entries)
+			if err != nil {
+				return err
+			}
+
+			if entries > 0 {
+				continue
+			}
+
+			_, err = tx.Exec("INSERT INTO timeline_entries (user_id, tweet_id) VALUES ($1, $2)", userID, tweetID.String())
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.Exec(
+				`DELETE FROM timeline_entries WHERE user_id = $1 AND id NOT IN (
+					SELECT id FROM timeline_entries WHERE user_id = $1 ORDER BY id DESC LIMIT $2
+				)`,
+				userID, repository.timelineSize(),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetTimeLine returns the list of tweets ids in a user timeline
+// Concurrent transactions may briefly leave extra entries before trimming, so reads are capped too.
+func (repository SQLRepository) GetTimeLine(userID uint) ([]uuid.UUID, error) {
+	rows, err := repository.DB.Query(
+		"SELECT tweet_id FROM timeline_entries WHERE user_id = $1 ORDER BY id DESC LIMIT $2",
+		userID, repository.timelineSize(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanTweetIDs(rows)
+}
+
+// RemoveFromTimeline removes the tweetIDs from the user's timeline
+func (repository SQLRepository) RemoveFromTimeline(userID uint, tweetIDs []uuid.UUID) error {
+	return inTransaction(repository.DB, func(tx *sql.Tx) error {
+		for _, tweetID := range tweetIDs {
+			_, err := tx.Exec("DELETE FROM timeline_entries WHERE user_id = $1 AND tweet_id = $2", userID, tweetID.String())
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// RemoveTweetFromTimelines removes the tweetID from the timeline of every user in userIDs in a single transaction
+func (repository SQLRepository) RemoveTweetFromTimelines(tweetID uuid.UUID, userIDs []uint) error {
+	return inTransaction(repository.DB, func(tx *sql.Tx) error {
+		for _, userID := range userIDs {
+			_, err := tx.Exec("DELETE FROM timeline_entries WHERE user_id = $1 AND tweet_id = $2", userID, tweetID.String())
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ReplaceTimeline replaces the user's timeline with the first TimelineSize tweetIDs if it still is current.
+// Returns false if it changed meanwhile.
+func (repository SQLRepository) ReplaceTimeline(userID uint, current, tweetIDs []uuid.UUID) (bool, error) {
+	replaced := false
+
+	err := inTransaction(repository.DB, func(tx *sql.Tx) error {
+		rows, err := tx.Query(
+			"SELECT tweet_id FROM timeline_entries WHERE user_id = $1 ORDER BY id DESC LIMIT $2",
+			userID, repository.timelineSize(),
+		)
+		if err != nil {
+			return err
+		}
+
+		stored, err := scanTweetIDs(rows)
+		if err != nil {
+			return err
+		}
+
+		if !slices.Equal(stored, current) {
+			return nil
+		}
+
+		_, err = tx.Exec("DELETE FROM timeline_entries WHERE user_id = $1", userID)
+		if err != nil {
+			return err
+		}
+
+		tweetIDs = repository.capTimeline(tweetIDs)
+
+		//oldest first, so the newest gets the highest id
+		for i := len(tweetIDs) - 1; i >= 0; i-- {
+			_, err = tx.Exec("INSERT INTO timeline_entries (user_id, tweet_id) VALUES ($1, $2)", userID, tweetIDs[i].String())
+			if err != nil {
+				return err
+			}
+		}
+
+		replaced = true
+
+		return nil
+	})
+
+	return replaced, err
+}
+
+// CreateSession stores a new session for SessionTTL
+func (repository SQLRepository) CreateSession(session models.Session) error {
+	_, err := repository.DB.Exec(
+		"INSERT INTO sessions (id, user_id, created_at, last_used_at, expires_at) VALUES ($1, $2, $3, $4, $5)",
+		session.ID, session.UserID, session.CreatedAt.UTC(), session.LastUsedAt.UTC(), repository.Clock.Now().Add(repository.sessionTTL()).UTC(),
+	)
+
+	return err
+}
+
+// GetSession returns the session by id, or false if it doesn't exist or expired
+func (repository SQLRepository) GetSession(sessionID string) (models.Session, bool, error) {
+	var session models.Session
+	var expiresAt time.Time
+
+	err := repository.DB.QueryRow(
+		"SELECT id, user_id, created_at, last_used_at, expires_at FROM sessions WHERE id = $1", sessionID,
+	).Scan(&session.ID, &session.UserID, &session.CreatedAt, &session.LastUsedAt, &expiresAt)
+	if err == sql.ErrNoRows {
+		return models.Session{}, false, nil
+	} else if err != nil {
+		return models.Session{}, false, err
+	}
+
+	if !repository.Clock.Now().Before(expiresAt) {
+		return models.Session{}, false, nil
+	}
+
+	return session, true, nil
+}
+
+// RefreshSession stores the session again and restarts its SessionTTL, if it wasn't deleted
+func (repository SQLRepository) RefreshSession(session models.Session) error {
+	_, err := repository.DB.Exec(
+		"UPDATE sessions SET last_used_at = $1, expires_at = $2 WHERE id = $3",
+		session.LastUsedAt.UTC(), repository.Clock.Now().Add(repository.sessionTTL()).UTC(), session.ID,
+	)
+
+	return err
+}
+
+// GetSessions returns the active sessions of userID
+func (repository SQLRepository) GetSessions(userID uint) ([]models.Session, error) {
+	rows, err := repository.DB.Query(
+		"SELECT id, user_id, created_at, last_used_at, expires_at FROM sessions WHERE user_id = $1 ORDER BY created_at",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := repository.Clock.Now()
+	sessions := make([]models.Session, 0)
+
+	for rows.Next() {
+		var session models.Session
+		var expiresAt time.Time
+		if err := rows.Scan(&session.ID, &session.UserID, &session.CreatedAt, &session.LastUsedAt, &expiresAt); err != nil {
+			return nil, err
+		}
+
+		if now.Before(expiresAt) {
+			sessions = append(sessions, session)
+		}
+	}
+
+	return sessions, rows.Err()
+}
+
+// DeleteSessions deletes the sessions of userID by ids
+func (repository SQLRepository) DeleteSessions(userID uint, sessionIDs []string) error {
+	return inTransaction(repository.DB, func(tx *sql.Tx) error {
+		for _, sessionID := range sessionIDs {
+			_, err := tx.Exec("DELETE FROM sessions WHERE id = $1 AND user_id = $2", sessionID, userID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// CreateUser stores a new user and returns its id. Returns ErrHandleTaken if the handle is in use, ignoring case.
+func (repository SQLRepository) CreateUser(user models.User) (uint, error) {
+	var id uint
+
+	err := inTransaction(repository.DB, func(tx *sql.Tx) error {
+		_, redirected, err := repository.getHandleRedirect(tx, user.Handle)
+		if err != nil {
+			return err
+		}
+
+		if redirected {
+			return ErrHandleTaken
+		}
+
+		err = tx.QueryRow(
+			"INSERT INTO users (handle, display_name, bio, password_hash, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+			user.Handle, user.DisplayName, user.Bio, user.PasswordHash, user.CreatedAt.UTC(),
+		).Scan(&id)
+		if isUniqueViolation(err) {
+			return ErrHandleTaken
+		}
+
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// GetUser returns the user by id, or false if it doesn't exist
+func (repository SQLRepository) GetUser(userID uint) (models.User, bool, error) {
+	return repository.getUser("id = $1", userID)
+}
+
+// GetUserByHandle returns the user by handle ignoring case, or false if it doesn't exist
+func (repository SQLRepository) GetUserByHandle(handle string) (models.User, bool, error) {
+	return repository.getUser("LOWER(handle) = LOWER($1)", handle)
+}
+
+// UpdatePassword replaces the password hash of userID
+func (repository SQLRepository) UpdatePassword(userID uint, passwordHash []byte) error {
+	_, err := repository.DB.Exec("UPDATE users SET password_hash = $1 WHERE id = $2", passwordHash, userID)
+
+	return err
+}
+
+// UpdateProfile replaces the profile of userID
+func (repository SQLRepository) UpdateProfile(userID uint, profile models.Profile) error {
+	_, err := repository.DB.Exec(
+		"UPDATE users SET display_name = $1, bio = $2 WHERE id = $3 AND handle IS NOT NULL",
+		profile.DisplayName, profile.Bio, userID,
+	)
+
+	return err
+}
+
+// RenameHandle replaces the handle of userID, keeping the previous one redirecting to userID for redirectFor.
+// Returns ErrHandleTaken if handle is in use by another user, ignoring case, or still redirects to another user.
+func (repository SQLRepository) RenameHandle(userID uint, handle string, redirectFor time.Duration) error {
+	return inTransaction(repository.DB, func(tx *sql.Tx) error {
+		var previous string
+
+		err := tx.QueryRow("SELECT handle FROM users WHERE id = $1 AND handle IS NOT NULL", userID).Scan(&previous)
+		if err == sql.ErrNoRows {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		ownerID, redirected, err := repository.getHandleRedirect(tx, handle)
+		if err != nil {
+			return err
+		}
+
+		if redirected && ownerID != userID {
+			return ErrHandleTaken
+		}
+
+		_, err = tx.Exec("UPDATE users SET handle = $1 WHERE id = $2", handle, userID)
+		if isUniqueViolation(err) {
+			return ErrHandleTaken
+		} else if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM handle_redirects WHERE handle = $1", strings.ToLower(handle))
+		if err != nil || strings.EqualFold(previous, handle) {
+			return err
+		}
+
+		_, err = tx.Exec(
+			`INSERT INTO handle_redirects (handle, user_id, expires_at) VALUES ($1, $2, $3)
+			ON CONFLICT (handle) DO UPDATE SET user_id = excluded.user_id, expires_at = excluded.expires_at`,
+			strings.ToLower(previous), userID, repository.Clock.Now().Add(redirectFor).UTC(),
+		)
+		return err
+	})
+}
+
+// GetHandleRedirect returns the user that renamed handle, ignoring case, while it still redirects, or false
+func (repository SQLRepository) GetHandleRedirect(handle string) (uint, bool, error) {
+	var userID uint
+	var redirected bool
+
+	err := inTransaction(repository.DB, func(tx *sql.Tx) error {
+		var err error
+		userID, redirected, err = repository.getHandleRedirect(tx, handle)
+		return err
+	})
+
+	return userID, redirected, err
+}
+
+// GetFailedLogins returns the failed login attempts of userID since the first one, up to FailedLoginsTTL ago
+func (repository SQLRepository) GetFailedLogins(userID uint) (int64, error) {
+	var attempts int64
+	var expiresAt time.Time
+
+	err := repository.DB.QueryRow(
+		"SELECT attempts, expires_at FROM failed_logins WHERE user_id = $1", userID,
+	).Scan(&attempts, &expiresAt)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	if !repository.Clock.Now().Before(expiresAt) {
+		return 0, nil
+	}
+
+	return attempts, nil
+}
+
+// AddFailedLogin records a failed login attempt of userID and returns the attempts like GetFailedLogins.
+// The attempts are counted by the database, so concurrent ones aren't lost.
+func (repository SQLRepository) AddFailedLogin(userID uint) (int64, error) {
+	var attempts int64
+
+	now := repository.Clock.Now()
+	err := repository.DB.QueryRow(
+		`INSERT INTO failed_logins (user_id, attempts, expires_at) VALUES ($1, 1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET
+			attempts = CASE WHEN failed_logins.expires_at <= $3 THEN 1 ELSE failed_logins.attempts + 1 END,
+			expires_at = CASE WHEN failed_logins.expires_at <= $3 THEN excluded.expires_at ELSE failed_logins.expires_at END
+		RETURNING attempts`,
+		userID, now.Add(FailedLoginsTTL).UTC(), now.UTC(),
+	).Scan(&attempts)
+	if err != nil {
+		return 0, err
+	}
+
+	return attempts, nil
+}
+
+// ResetFailedLogins forgets the failed login attempts of userID
+func (repository SQLRepository) ResetFailedLogins(userID uint) error {
+	_, err := repository.DB.Exec("DELETE FROM failed_logins WHERE user_id = $1", userID)
+
+	return err
+}
+
+// CreatePasswordReset stores the id of a password reset token of userID for PasswordResetTTL
+func (repository SQLRepository) CreatePasswordReset(tokenID string, userID uint) error {
+	_, err := repository.DB.Exec(
+		"INSERT INTO password_resets (id, user_id, expires_at) VALUES ($1, $2, $3)",
+		tokenID, userID, repository.Clock.Now().Add(PasswordResetTTL).UTC(),
+	)
+
+	return err
+}
+
+// ConsumePasswordReset deletes a password reset token id and returns its user, or false if it doesn't exist or expired
+func (repository SQLRepository) ConsumePasswordReset(tokenID string) (uint, bool, error) {
+	var userID uint
+	var expiresAt time.Time
+	found := false
+
+	err := inTransaction(repository.DB, func(tx *sql.Tx) error {
+		err := tx.QueryRow(
+			"SELECT user_id, expires_at FROM password_resets WHERE id = $1", tokenID,
+		).Scan(&userID, &expiresAt)
+		if err == sql.ErrNoRows {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		result, err := tx.Exec("DELETE FROM password_resets WHERE id = $1", tokenID)
+		if err != nil {
+			return err
+		}
+
+		//a concurrent consumer may have deleted it first
+		deleted, err := result.RowsAffected()
+		found = deleted > 0
+
+		return err
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	if !found || !repository.Clock.Now().Before(expiresAt) {
+		return 0, false, nil
+	}
+
+	return userID, true, nil
+}
+
+// AddNotification adds notification to the inbox of its user. Only the latest MaxNotifications are read.
+func (repository SQLRepository) AddNotification(notification models.Notification) error {
+	_, err := repository.DB.Exec(
+		"INSERT INTO notifications (id, user_id, type, actor_id, tweet_id, created_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		notification.ID.String(), notification.UserID, notification.Type, notification.ActorID, notification.TweetID.String(),
+		notification.Timestamp.UTC(),
+	)
+
+	return err
+}
+
+// GetNotifications returns the latest MaxNotifications notifications of the inbox of userID, newest first
+func (repository SQLRepository) GetNotifications(userID uint) ([]models.Notification, error) {
+	rows, err := repository.DB.Query(
+		"SELECT id, user_id, type, actor_id, tweet_id, created_at FROM notifications WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2",
+		userID, MaxNotifications,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+
+	for rows.Next() {
+		var notification models.Notification
+		err := rows.Scan(
+			&notification.ID, &notification.UserID, &notification.Type, &notification.ActorID, &notification.TweetID,
+			&notification.Timestamp,
+		)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, rows.Err()
+}
+
+// GetNotificationsReadAt returns the time until which userID read its notifications, zero if it never did
+func (repository SQLRepository) GetNotificationsReadAt(userID uint) (time.Time, error) {
+	var readAt time.Time
+
+	err := repository.DB.QueryRow("SELECT read_at FROM notification_reads WHERE user_id = $1", userID).Scan(&readAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+
+	return readAt, err
+}
+
+// SetNotificationsReadAt replaces the time until which userID read its notifications
+func (repository SQLRepository) SetNotificationsReadAt(userID uint, readAt time.Time) error {
+	_, err := repository.DB.Exec(
+		`INSERT INTO notification_reads (user_id, read_at) VALUES ($1, $2)
+			ON CONFLICT (user_id) DO UPDATE SET read_at = excluded.read_at`,
+		userID, readAt.UTC(),
+	)
+
+	return err
+}
+
+// getUser returns the registered user matching condition, or false if there is none
+func (repository SQLRepository) getUser(condition string, arg any) (models.User, bool, error) {
+	var user models.User
+
+	err := repository.DB.QueryRow(
+		"SELECT id, handle, display_name, bio, password_hash, created_at FROM users WHERE handle IS NOT NULL AND "+condition, arg,
+	).Scan(&user.ID, &user.Handle, &user.DisplayName, &user.Bio, &user.PasswordHash, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return models.User{}, false, nil
+	} else if err != nil {
+		return models.User{}, false, err
+	}
+
+	return user, true, nil
+}
+
+// getHandleRedirect returns the user that renamed handle, ignoring case, while it still redirects, or false
+func (repository SQLRepository) getHandleRedirect(tx *sql.Tx, handle string) (uint, bool, error) {
+	var userID uint
+	var expiresAt time.Time
+
+	err := tx.QueryRow(
+		"SELECT user_id, expires_at FROM handle_redirects WHERE handle = $1", strings.ToLower(handle),
+	).Scan(&userID, &expiresAt)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, err
+	}
+
+	if !repository.Clock.Now().Before(expiresAt) {
+		return 0, false, nil
+	}
+
+	return userID, true, nil
+}
+
+// ensureUsers registers the given ids in the users table if they aren't already
+func (repository SQLRepository) ensureUsers(tx *sql.Tx, ids ...uint) error {
+	for _, id := range ids {
+		_, err := tx.Exec(
+			"INSERT INTO users (id, created_at) VALUES ($1, $2) ON CONFLICT (id) DO NOTHING",
+			id, repository.Clock.Now().UTC(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation of SQLite or PostgreSQL
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+	}
+
+	var postgresErr *pq.Error
+	if errors.As(err, &postgresErr) {
+		return postgresErr.Code == "23505"
+	}
+
+	return false
+}
+
+// inTransaction runs fn inside a transaction, committing if it succeeds and rolling back otherwise
+func inTransaction(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
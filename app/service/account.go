@@ -0,0 +1,296 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/PatricioYegros/uala_challenge/app/models"
+	"github.com/PatricioYegros/uala_challenge/app/repository"
+)
+
+// DefaultHandleGracePeriod is how long the previous handle of a user redirects to it if HandleGracePeriod isn't set
+const DefaultHandleGracePeriod = 30 * 24 * time.Hour
+
+var (
+	ErrHandleTaken          = errors.New("handle already taken")
+	ErrInvalidPasswordReset = errors.New("invalid or expired password reset token")
+)
+
+// Register creates a user with handle and password and a profile with displayName and bio
+// Returns ErrHandleTaken if another user has the same handle, ignoring case, or the validation errors of models.NewUser
+// and models.NewProfile
+func (service TwitterService) Register(handle, password, displayName, bio string) (models.User, error) {
+	profile, err := models.NewProfile(displayName, bio)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user, err := models.NewUser(handle, password, service.Clock.Now())
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user.Profile = profile
+
+	user.ID, err = service.Repository.CreateUser(*user)
+	if errors.Is(err, repository.ErrHandleTaken) {
+		return models.User{}, ErrHandleTaken
+	} else if err != nil {
+		return models.User{}, err
+	}
+
+	return *user, nil
+}
+
+// GetUser returns the user userID
+// Returns ErrUserNotFound if it doesn't exist or ErrorGettingUser if an error occurred
+func (service TwitterService) GetUser(userID uint) (models.User, error) {
+	user, found, err := service.Repository.GetUser(userID)
+	if err != nil {
+		log.Println(err.Error())
+		return models.User{}, fmt.Errorf("%w %d", ErrorGettingUser, userID)
+	}
+
+	if !found {
+		return models.User{}, fmt.Errorf("%w %d", ErrUserNotFound, userID)
+	}
+
+	return user, nil
+}
+
+// UpdateProfile replaces the display name and bio of userID with the ones that aren't nil and returns the user
+// Returns ErrUserNotFound if it doesn't exist, the validation errors of models.NewProfile or
+// ErrorGettingUser or ErrUpdatingProfile if an error occurred
+func (service TwitterService) UpdateProfile(userID uint, displayName, bio *string) (models.User, error) {
+	user, err := service.GetUser(userID)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if displayName != nil {
+		user.DisplayName = *displayName
+	}
+
+	if bio != nil {
+		user.Bio = *bio
+	}
+
+	user.Profile, err = models.NewProfile(user.DisplayName, user.Bio)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	err = service.Repository.UpdateProfile(userID, user.Profile)
+	if err != nil {
+		log.Println(err.Error())
+		return models.User{}, fmt.Errorf("%w %d", ErrUpdatingProfile, userID)
+	}
+
+	return user, nil
+}
+
+// ResolveHandle returns the user with handle, ignoring case, and false, or the user that renamed it and true while the
+// previous handle still redirects to it.
+// Returns ErrUserNotFound if neither exists or ErrorGettingUser if an error occurred
+func (service TwitterService) ResolveHandle(handle string) (models.User, bool, error) {
+	user, found, err := service.Repository.GetUserByHandle(handle)
+	if err != nil {
+		log.Println(err.Error())
+		return models.User{}, false, fmt.Errorf("%w %s", ErrorGettingUser, handle)
+	}
+
+	if found {
+		return user, false, nil
+	}
+
+	userID, redirected, err := service.Repository.GetHandleRedirect(handle)
+	if err != nil {
+		log.Println(err.Error())
+		return models.User{}, false, fmt.Errorf("%w %s", ErrorGettingUser, handle)
+	}
+
+	if !redirected {
+		return models.User{}, false, fmt.Errorf("%w %s", ErrUserNotFound, handle)
+	}
+
+	user, err = service.GetUser(userID)
+	if err != nil {
+		return models.User{}, false, err
+	}
+
+	return user, true, nil
+}
+
+// RenameHandle changes the handle of userID and returns the user. The previous handle keeps redirecting to userID for
+// HandleGracePeriod, and nobody else can take it meanwhile.
+// Returns ErrUserNotFound if it doesn't exist, models.ErrInvalidHandle if handle isn't valid, ErrHandleTaken if
+// another user has it, ignoring case, or ErrorGettingUser or ErrRenamingHandle if an error occurred
+func (service TwitterService) RenameHandle(userID uint, handle string) (models.User, error) {
+	err := models.CheckHandle(handle)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user, err := service.GetUser(userID)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	err = service.Repository.RenameHandle(userID, handle, service.handleGracePeriod())
+	if errors.Is(err, repository.ErrHandleTaken) {
+		return models.User{}, ErrHandleTaken
+	} else if err != nil {
+		log.Println(err.Error())
+		return models.User{}, fmt.Errorf("%w %d", ErrRenamingHandle, userID)
+	}
+
+	user.Handle = handle
+
+	return user, nil
+}
+
+// handleGracePeriod returns HandleGracePeriod, or DefaultHandleGracePeriod if it isn't set
+func (service TwitterService) handleGracePeriod() time.Duration {
+	if service.HandleGracePeriod <= 0 {
+		return DefaultHandleGracePeriod
+	}
+
+	return service.HandleGracePeriod
+}
+
+// checkUsers returns ErrUserNotFound for the first of userIDs that doesn't exist
+// or ErrorGettingUser if an error occurred
+func (service TwitterService) checkUsers(userIDs ...uint) error {
+	for _, userID := range userIDs {
+		_, err := service.GetUser(userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ChangePassword replaces the password of userID if currentPassword is right, ending every other session of the user
+// Returns ErrInvalidCredentials if currentPassword is wrong or the validation errors of models.User.SetPassword
+func (service TwitterService) ChangePassword(userID uint, currentSessionID, currentPassword, newPassword string) error {
+	user, ok, err := service.Repository.GetUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if !ok || !user.CheckPassword(currentPassword) {
+		return ErrInvalidCredentials
+	}
+
+	err = service.setPassword(user, newPassword)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := service.Repository.GetSessions(userID)
+	if err != nil {
+		return err
+	}
+
+	sessionIDs := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		if session.ID != currentSessionID {
+			sessionIDs = append(sessionIDs, session.ID)
+		}
+	}
+
+	return service.Repository.DeleteSessions(userID, sessionIDs)
+}
+
+// RequestPasswordReset returns a single use token to reset the password of the user with handle,
+// valid for repository.PasswordResetTTL. The token must be delivered to the user out of band.
+// Returns ErrInvalidCredentials if there is no user with handle
+func (service TwitterService) RequestPasswordReset(handle string) (string, error) {
+	user, ok, err := service.Repository.GetUserByHandle(handle)
+	if err != nil {
+		return "", err
+	}
+
+	if !ok {
+		return "", ErrInvalidCredentials
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = service.Repository.CreatePasswordReset(hashToken(token), user.ID)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ResetPassword sets the password of the owner of a password reset token, ending all of its sessions and unlocking it
+// Returns ErrInvalidPasswordReset if the token doesn't exist, expired or was used, or the validation errors of models.User.SetPassword
+func (service TwitterService) ResetPassword(token, newPassword string) error {
+	if len(newPassword) < models.MinPasswordLength {
+		return models.ErrPasswordTooShort
+	}
+
+	userID, ok, err := service.Repository.ConsumePasswordReset(hashToken(token))
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return ErrInvalidPasswordReset
+	}
+
+	user, ok, err := service.Repository.GetUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return ErrInvalidPasswordReset
+	}
+
+	err = service.setPassword(user, newPassword)
+	if err != nil {
+		return err
+	}
+
+	err = service.Repository.ResetFailedLogins(userID)
+	if err != nil {
+		log.Println(err.Error())
+	}
+
+	return service.RevokeSessions(userID)
+}
+
+// findUser returns the user with handle reference or, if nobody has it, with id reference
+func (service TwitterService) findUser(reference string) (models.User, bool, error) {
+	user, found, err := service.Repository.GetUserByHandle(reference)
+	if err != nil || found {
+		return user, found, err
+	}
+
+	userID, err := strconv.ParseUint(reference, 10, 0)
+	if err != nil {
+		return models.User{}, false, nil
+	}
+
+	return service.Repository.GetUser(uint(userID))
+}
+
+// setPassword hashes and stores a new password of user
+func (service TwitterService) setPassword(user models.User, password string) error {
+	err := user.SetPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return service.Repository.UpdatePassword(user.ID, user.PasswordHash)
+}
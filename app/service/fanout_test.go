@@ -0,0 +1,97 @@
+package service_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/PatricioYegros/uala_challenge/app/models"
+	"github.com/PatricioYegros/uala_challenge/app/repository"
+	"github.com/PatricioYegros/uala_challenge/app/service"
+	"github.com/PatricioYegros/uala_challenge/app/utils"
+)
+
+const (
+	benchmarkFollowers = 100_000
+	benchmarkFollowing = 50
+)
+
+// newBenchmarkService returns a service over a MemoryRepository where user 1 has benchmarkFollowers followers
+// and user 2 follows user 1 and benchmarkFollowing other users with a tweet each
+func newBenchmarkService(b *testing.B, fanOutThreshold int64) service.TwitterService {
+	repo := repository.NewMemoryRepository(utils.Clock{})
+
+	//ids are given in order, from 1
+	for user := 1; user < benchmarkFollowers+2+benchmarkFollowing; user++ {
+		if _, err := repo.CreateUser(models.User{Handle: fmt.Sprintf("user%d", user)}); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	for follower := uint(2); follower < benchmarkFollowers+2; follower++ {
+		if err := repo.AddFollower(1, follower); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	twitterService := service.TwitterService{
+		Repository:      repo,
+		Clock:           utils.Clock{},
+		FanOutThreshold: fanOutThreshold,
+	}
+
+	for followed := uint(benchmarkFollowers + 2); followed < benchmarkFollowers+2+benchmarkFollowing; followed++ {
+		if err := repo.AddFollower(followed, 2); err != nil {
+			b.Fatal(err)
+		}
+
+		if _, err := twitterService.Tweet(followed, fmt.Sprintf("tweet of %d", followed)); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	return twitterService
+}
+
+func benchmarkTweet(b *testing.B, fanOutThreshold int64) {
+	twitterService := newBenchmarkService(b, fanOutThreshold)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := twitterService.Tweet(1, "uala_challenge"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkGetTimeLine(b *testing.B, fanOutThreshold int64) {
+	twitterService := newBenchmarkService(b, fanOutThreshold)
+
+	for i := 0; i < 10; i++ {
+		if _, err := twitterService.Tweet(1, "uala_challenge"); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := twitterService.GetTimeLine(2, "", 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTweetFanOutOnWrite(b *testing.B) {
+	benchmarkTweet(b, 0)
+}
+
+func BenchmarkTweetFanOutOnRead(b *testing.B) {
+	benchmarkTweet(b, benchmarkFollowers-1)
+}
+
+func BenchmarkGetTimeLineFanOutOnWrite(b *testing.B) {
+	benchmarkGetTimeLine(b, 0)
+}
+
+func BenchmarkGetTimeLineFanOutOnRead(b *testing.B) {
+	benchmarkGetTimeLine(b, benchmarkFollowers-1)
+}
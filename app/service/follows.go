@@ -0,0 +1,95 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/PatricioYegros/uala_challenge/app/models"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidLimit  = errors.New("invalid limit")
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// GetFollowers returns a page of up to limit followers of userID starting at cursor, with the total amount of followers.
+// An empty cursor starts from the first page and a limit of 0 uses DefaultPageSize.
+// Returns ErrInvalidCursor or ErrInvalidLimit if they can't be used or ErrUserNotFound if userID doesn't exist
+func (service TwitterService) GetFollowers(userID uint, cursor string, limit int) (models.UsersPage, error) {
+	return service.getUsersPage(userID, cursor, limit, service.Repository.ScanFollowers, service.Repository.CountFollowers)
+}
+
+// GetFollowing returns a page of the users followed by userID like GetFollowers
+func (service TwitterService) GetFollowing(userID uint, cursor string, limit int) (models.UsersPage, error) {
+	return service.getUsersPage(userID, cursor, limit, service.Repository.ScanFollowing, service.Repository.CountFollowing)
+}
+
+func (service TwitterService) getUsersPage(
+	userID uint,
+	cursor string,
+	limit int,
+	scan func(userID uint, cursor uint64, count int64) ([]uint, uint64, error),
+	count func(userID uint) (int64, error),
+) (models.UsersPage, error) {
+	var position uint64
+
+	if cursor != "" {
+		var err error
+
+		position, err = strconv.ParseUint(cursor, 10, 64)
+		if err != nil || position == 0 {
+			return models.UsersPage{}, ErrInvalidCursor
+		}
+	}
+
+	limit, err := pageLimit(limit)
+	if err != nil {
+		return models.UsersPage{}, err
+	}
+
+	err = service.checkUsers(userID)
+	if err != nil {
+		return models.UsersPage{}, err
+	}
+
+	userIDs, next, err := scan(userID, position, int64(limit))
+	if err != nil {
+		return models.UsersPage{}, err
+	}
+
+	total, err := count(userID)
+	if err != nil {
+		return models.UsersPage{}, err
+	}
+
+	page := models.UsersPage{
+		UserIDs: userIDs,
+		Count:   total,
+	}
+
+	if next != 0 {
+		page.NextCursor = strconv.FormatUint(next, 10)
+	}
+
+	return page, nil
+}
+
+// pageLimit returns the size of a page of up to limit items, DefaultPageSize for 0 and at most MaxPageSize
+// Returns ErrInvalidLimit if limit is negative
+func pageLimit(limit int) (int, error) {
+	switch {
+	case limit < 0:
+		return 0, ErrInvalidLimit
+	case limit == 0:
+		return DefaultPageSize, nil
+	case limit > MaxPageSize:
+		return MaxPageSize, nil
+	}
+
+	return limit, nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"cmp"
+	"log"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/PatricioYegros/uala_challenge/app/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultTrendsWindow is how far back the hashtags of tweets count for trends if TrendsWindow isn't set
+	DefaultTrendsWindow = 24 * time.Hour
+	// DefaultTrendsHalfLife is how long it takes for a tweet to count half for trends if TrendsHalfLife isn't set
+	DefaultTrendsHalfLife = 2 * time.Hour
+	// DefaultTrendsSize is the amount of trends returned by GetTrends with a limit of 0
+	DefaultTrendsSize = 10
+)
+
+// GetHashtagTweets returns a page of up to limit tweets with hashtag, newest first, skipping the ones that expired.
+// Cursors work like in GetTimeLine and LikedByMe and RetweetedBy are set for readerID.
+// Returns ErrInvalidHashtag, ErrInvalidCursor or ErrInvalidLimit if they can't be used or
+// ErrorGettingHashtag if an error ocurred
+func (service TwitterService) GetHashtagTweets(readerID uint, hashtag string, cursor string, limit int) (models.TimelinePage, error) {
+	hashtag, err := models.NormalizeHashtag(hashtag)
+	if err != nil {
+		return models.TimelinePage{}, err
+	}
+
+	return service.tweetsPage(readerID, cursor, limit, func() ([]uuid.UUID, error) {
+		return service.Repository.GetHashtagTweets(hashtag)
+	}, ErrorGettingHashtag)
+}
+
+// GetTrends returns up to limit hashtags used in the last TrendsWindow, with the highest score first.
+// Each tweet adds to the score of its hashtags and counts half every TrendsHalfLife, so old spikes fall off.
+// A limit of 0 uses DefaultTrendsSize.
+// Returns ErrInvalidLimit if it can't be used or ErrorGettingTrends if an error ocurred
+func (service TwitterService) GetTrends(limit int) ([]models.Trend, error) {
+	if limit == 0 {
+		limit = DefaultTrendsSize
+	}
+
+	limit, err := pageLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	window := service.trendsWindow()
+	halfLife := service.trendsHalfLife()
+	now := service.Clock.Now()
+
+	uses, err := service.Repository.GetHashtagUses(now.Add(-window))
+	if err != nil {
+		log.Println(err.Error())
+		return nil, ErrorGettingTrends
+	}
+
+	trends := make([]models.Trend, 0, len(uses))
+
+	for hashtag, timestamps := range uses {
+		trend := models.Trend{Hashtag: hashtag, TweetCount: len(timestamps)}
+
+		for _, timestamp := range timestamps {
+			age := max(now.Sub(timestamp), 0)
+			trend.Score += math.Exp2(-float64(age) / float64(halfLife))
+		}
+
+		trends = append(trends, trend)
+	}
+
+	slices.SortFunc(trends, func(a, b models.Trend) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.Hashtag, b.Hashtag))
+	})
+
+	return trends[:min(limit, len(trends))], nil
+}
+
+// trendsWindow returns TrendsWindow, or DefaultTrendsWindow if it isn't set
+func (service TwitterService) trendsWindow() time.Duration {
+	if service.TrendsWindow <= 0 {
+		return DefaultTrendsWindow
+	}
+
+	return service.TrendsWindow
+}
+
+// trendsHalfLife returns TrendsHalfLife, or DefaultTrendsHalfLife if it isn't set
+func (service TwitterService) trendsHalfLife() time.Duration {
+	if service.TrendsHalfLife <= 0 {
+		return DefaultTrendsHalfLife
+	}
+
+	return service.TrendsHalfLife
+}
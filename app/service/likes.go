@@ -0,0 +1,146 @@
+package service
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/PatricioYegros/uala_challenge/app/models"
+
+	"github.com/google/uuid"
+)
+
+// Like records that userID likes tweetID, of any user, and notifies its author.
+// Returns ErrTweetNotFound if tweetID doesn't exist, ErrAlreadyLiked if userID already likes it or
+// ErrorGettingTweet or ErrLiking if an error occurred
+func (service TwitterService) Like(userID uint, tweetID uuid.UUID) error {
+	tweet, err := service.findTweet(tweetID)
+	if err != nil {
+		return err
+	}
+
+	added, err := service.Repository.AddLike(userID, tweetID)
+	if err != nil {
+		log.Println(err.Error())
+		return fmt.Errorf("%w %s by user %d", ErrLiking, tweetID, userID)
+	}
+
+	if !added {
+		return ErrAlreadyLiked
+	}
+
+	service.notify(models.Notification{
+		Type:      models.NotificationLike,
+		UserID:    tweet.UserID,
+		ActorID:   userID,
+		TweetID:   tweetID,
+		Timestamp: service.Clock.Now(),
+	})
+
+	return nil
+}
+
+// Unlike removes the like of userID to tweetID.
+// Returns ErrTweetNotFound if tweetID doesn't exist, ErrNotLiked if userID doesn't like it or
+// ErrorGettingTweet or ErrUnliking if an error occurred
+func (service TwitterService) Unlike(userID uint, tweetID uuid.UUID) error {
+	_, err := service.findTweet(tweetID)
+	if err != nil {
+		return err
+	}
+
+	removed, err := service.Repository.RemoveLike(userID, tweetID)
+	if err != nil {
+		log.Println(err.Error())
+		return fmt.Errorf("%w %s by user %d", ErrUnliking, tweetID, userID)
+	}
+
+	if !removed {
+		return ErrNotLiked
+	}
+
+	return nil
+}
+
+// GetLikes returns a page of up to limit tweets liked by userID, newest like first, skipping the ones that expired.
+// Cursors work like in GetTimeLine and LikedByMe and RetweetedBy are set for readerID.
+// Returns ErrInvalidCursor or ErrInvalidLimit if they can't be used, ErrUserNotFound if userID doesn't exist or
+// ErrorGettingUser or ErrorGettingLikes if an error ocurred
+func (service TwitterService) GetLikes(readerID uint, userID uint, cursor string, limit int) (models.TimelinePage, error) {
+	err := service.checkUsers(userID)
+	if err != nil {
+		return models.TimelinePage{}, err
+	}
+
+	return service.tweetsPage(readerID, cursor, limit, func() ([]uuid.UUID, error) {
+		return service.Repository.GetUserLikes(userID)
+	}, ErrorGettingLikes)
+}
+
+// tweetsPage returns a page of up to limit tweets of the list of ids returned by listTweets, skipping the ones that
+// expired. Cursors work like in GetTimeLine and LikedByMe and RetweetedBy are set for readerID.
+// Returns ErrInvalidCursor or ErrInvalidLimit if they can't be used or errGetting if an error ocurred
+func (service TwitterService) tweetsPage(readerID uint, cursor string, limit int, listTweets func() ([]uuid.UUID, error), errGetting error) (models.TimelinePage, error) {
+	position, err := parseTimelineCursor(cursor)
+	if err != nil {
+		return models.TimelinePage{}, err
+	}
+
+	if limit == 0 {
+		limit = service.timelinePageSize()
+	}
+
+	limit, err = pageLimit(limit)
+	if err != nil {
+		return models.TimelinePage{}, err
+	}
+
+	tweetsIDs, err := listTweets()
+	if err != nil {
+		log.Println(err.Error())
+		return models.TimelinePage{}, errGetting
+	}
+
+	//one more tweet than the page tells if there is a next one
+	tweets, _, err := service.hydrateTimeline(tweetsIDs, position, limit+1)
+	if err != nil {
+		log.Println(err.Error())
+		return models.TimelinePage{}, errGetting
+	}
+
+	err = service.annotateRetweets(readerID, tweets[:min(limit, len(tweets))])
+	if err != nil {
+		log.Println(err.Error())
+		return models.TimelinePage{}, errGetting
+	}
+
+	err = service.annotateLikes(readerID, tweets[:min(limit, len(tweets))])
+	if err != nil {
+		log.Println(err.Error())
+		return models.TimelinePage{}, errGetting
+	}
+
+	return newTimelinePage(tweets, cursor, position, limit), nil
+}
+
+// annotateLikes sets the LikedByMe of tweets to whether userID likes them
+func (service TwitterService) annotateLikes(userID uint, tweets []models.Tweet) error {
+	if len(tweets) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(tweets))
+	for _, tweet := range tweets {
+		ids = append(ids, tweet.ID)
+	}
+
+	liked, err := service.Repository.GetLikedBy(userID, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range tweets {
+		tweets[i].LikedByMe = liked[i]
+	}
+
+	return nil
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/PatricioYegros/uala_challenge/app/models"
+	"github.com/PatricioYegros/uala_challenge/app/repository"
+
+	"github.com/google/uuid"
+)
+
+// Notifier delivers notifications to their users
+type Notifier interface {
+	Notify(notification models.Notification) error
+}
+
+// InboxNotifier delivers notifications to the inbox of their users in the repository
+type InboxNotifier struct {
+	Repository repository.IRepository
+}
+
+// Notify adds the notification, with a new id, to the inbox of its user
+func (notifier InboxNotifier) Notify(notification models.Notification) error {
+	notification.ID = uuid.New()
+
+	return notifier.Repository.AddNotification(notification)
+}
+
+// GetNotifications returns a page of up to limit notifications of the inbox of userID, newest first, with the amount
+// of unread ones. An empty cursor starts with the newest notifications and NextCursor of a page continues with
+// older ones. A limit of 0 uses DefaultPageSize.
+// Returns ErrInvalidCursor or ErrInvalidLimit if they can't be used or ErrorGettingNotifications if an error ocurred
+func (service TwitterService) GetNotifications(userID uint, cursor string, limit int) (models.NotificationsPage, error) {
+	var after uuid.UUID
+
+	if cursor != "" {
+		var err error
+
+		after, err = uuid.Parse(cursor)
+		if err != nil {
+			return models.NotificationsPage{}, ErrInvalidCursor
+		}
+	}
+
+	limit, err := pageLimit(limit)
+	if err != nil {
+		return models.NotificationsPage{}, err
+	}
+
+	notifications, err := service.readNotifications(userID)
+	if err != nil {
+		log.Println(err.Error())
+		return models.NotificationsPage{}, fmt.Errorf("%w %d", ErrorGettingNotifications, userID)
+	}
+
+	page := models.NotificationsPage{}
+
+	for _, notification := range notifications {
+		if !notification.Read {
+			page.UnreadCount++
+		}
+	}
+
+	start := 0
+	if cursor != "" {
+		//a cursor trimmed out of the inbox had nothing older left
+		start = len(notifications)
+		if i := slices.IndexFunc(notifications, func(notification models.Notification) bool { return notification.ID == after }); i >= 0 {
+			start = i + 1
+		}
+	}
+
+	end := min(start+limit, len(notifications))
+	page.Notifications = notifications[start:end]
+
+	if end < len(notifications) {
+		page.NextCursor = notifications[end-1].ID.String()
+	}
+
+	return page, nil
+}
+
+// MarkNotificationsRead marks notificationID of the inbox of userID and every older notification as read
+// Returns ErrNotificationNotFound if it isn't in the inbox or
+// ErrorGettingNotifications or ErrMarkingNotifications if an error occurred
+func (service TwitterService) MarkNotificationsRead(userID uint, notificationID uuid.UUID) error {
+	notifications, err := service.readNotifications(userID)
+	if err != nil {
+		log.Println(err.Error())
+		return fmt.Errorf("%w %d", ErrorGettingNotifications, userID)
+	}
+
+	i := slices.IndexFunc(notifications, func(notification models.Notification) bool { return notification.ID == notificationID })
+	if i < 0 {
+		return ErrNotificationNotFound
+	}
+
+	if notifications[i].Read {
+		//already read, keep the newer mark
+		return nil
+	}
+
+	err = service.Repository.SetNotificationsReadAt(userID, notifications[i].Timestamp)
+	if err != nil {
+		log.Println(err.Error())
+		return fmt.Errorf("%w %d", ErrMarkingNotifications, userID)
+	}
+
+	return nil
+}
+
+// readNotifications returns the notifications of the inbox of userID, newest first, with their Read set
+func (service TwitterService) readNotifications(userID uint) ([]models.Notification, error) {
+	notifications, err := service.Repository.GetNotifications(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	readAt, err := service.Repository.GetNotificationsReadAt(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range notifications {
+		notifications[i].Read = !notifications[i].Timestamp.After(readAt)
+	}
+
+	return notifications, nil
+}
+
+// notify delivers notification with the Notifier, unless the user acted on its own tweet
+// It's best effort, errors are only logged.
+func (service TwitterService) notify(notification models.Notification) {
+	if service.Notifier == nil || notification.UserID == notification.ActorID {
+		return
+	}
+
+	err := service.Notifier.Notify(notification)
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// notifyMentions notifies the users mentioned in tweet, except the ones in previous
+func (service TwitterService) notifyMentions(tweet models.Tweet, previous []uint) {
+	for _, userID := range tweet.Mentions {
+		if slices.Contains(previous, userID) {
+			continue
+		}
+
+		service.notify(models.Notification{
+			Type:      models.NotificationMention,
+			UserID:    userID,
+			ActorID:   tweet.UserID,
+			TweetID:   tweet.ID,
+			Timestamp: service.Clock.Now(),
+		})
+	}
+}
+
+// resolveMentions returns the sorted ids of the registered users mentioned in content by handle or by id.
+// A mention is a handle if a user has it, mentions of users that don't exist are ignored.
+func (service TwitterService) resolveMentions(content string) ([]uint, error) {
+	var mentions []uint
+
+	for _, mention := range models.ParseMentions(content) {
+		user, found, err := service.findUser(mention)
+		if err != nil {
+			return nil, err
+		}
+
+		if found && !slices.Contains(mentions, user.ID) {
+			mentions = append(mentions, user.ID)
+		}
+	}
+
+	slices.Sort(mentions)
+
+	return mentions, nil
+}
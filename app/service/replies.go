@@ -0,0 +1,102 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/PatricioYegros/uala_challenge/app/models"
+
+	"github.com/google/uuid"
+)
+
+// Reply creates a tweet of userID replying to tweetID, in its conversation, and notifies the author of tweetID
+// The reply is fanned out to the followers of userID like any other tweet.
+// Returns ErrTweetNotFound if tweetID doesn't exist, models.ErrMaxLengthExceeded if content len is bigger
+// than MaxTweetLength, ErrUserNotFound if userID doesn't exist or ErrorGettingTweet, ErrorGettingUser or
+// ErrCreatingTweet if an error occurred
+func (service TwitterService) Reply(userID uint, tweetID uuid.UUID, content string) (uuid.UUID, error) {
+	parent, err := service.findTweet(tweetID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	reply, err := models.NewReply(userID, service.Clock.Now(), content, service.MaxTweetLength, parent)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	replyID, err := service.createTweet(*reply)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	service.notify(models.Notification{
+		Type:      models.NotificationReply,
+		UserID:    parent.UserID,
+		ActorID:   userID,
+		TweetID:   replyID,
+		Timestamp: reply.Timestamp,
+	})
+
+	return service.publish(replyID, userID)
+}
+
+// GetThread returns the conversation of tweetID as trees of replies, oldest first.
+// The conversation starts at its first tweet, replies to tweets that don't exist anymore start their own tree.
+// Returns ErrTweetNotFound if tweetID doesn't exist or ErrorGettingTweet if an error occurred
+func (service TwitterService) GetThread(tweetID uuid.UUID) ([]models.Thread, error) {
+	tweet, err := service.findTweet(tweetID)
+	if err != nil {
+		return nil, err
+	}
+
+	conversationID := tweet.Conversation()
+
+	replies, err := service.Repository.GetConversation(conversationID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, fmt.Errorf("%w %s", ErrorGettingTweet, tweetID)
+	}
+
+	tweets, _, err := service.Repository.GetTweets(append([]uuid.UUID{conversationID}, replies...))
+	if err != nil {
+		log.Println(err.Error())
+		return nil, fmt.Errorf("%w %s", ErrorGettingTweet, tweetID)
+	}
+
+	slices.SortStableFunc(tweets, func(a, b models.Tweet) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+
+	found := make(map[uuid.UUID]bool, len(tweets))
+	for _, tweet := range tweets {
+		found[tweet.ID] = true
+	}
+
+	var roots []models.Tweet
+	children := make(map[uuid.UUID][]models.Tweet, len(tweets))
+	for _, tweet := range tweets {
+		if tweet.InReplyTo == nil || !found[*tweet.InReplyTo] {
+			roots = append(roots, tweet)
+			continue
+		}
+
+		children[*tweet.InReplyTo] = append(children[*tweet.InReplyTo], tweet)
+	}
+
+	return buildThreads(roots, children), nil
+}
+
+// buildThreads returns the threads of tweets with their replies from children, keeping their order
+func buildThreads(tweets []models.Tweet, children map[uuid.UUID][]models.Tweet) []models.Thread {
+	threads := make([]models.Thread, 0, len(tweets))
+	for _, tweet := range tweets {
+		threads = append(threads, models.Thread{
+			Tweet:   tweet,
+			Replies: buildThreads(children[tweet.ID], children),
+		})
+	}
+
+	return threads
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/PatricioYegros/uala_challenge/app/models"
+
+	"github.com/google/uuid"
+)
+
+// Retweet shares tweetID, of any user, with the followers of userID and notifies its author.
+// The tweet is pushed to the timelines of the followers even above FanOutThreshold, skipping the ones that already have it.
+// Returns ErrTweetNotFound if tweetID doesn't exist, ErrAlreadyRetweeted if userID already retweeted it or
+// ErrorGettingTweet, ErrRetweeting, ErrorGettingFollowersList or ErrorAddingToTimeline if an error occurred
+func (service TwitterService) Retweet(userID uint, tweetID uuid.UUID) error {
+	tweet, err := service.findTweet(tweetID)
+	if err != nil {
+		return err
+	}
+
+	added, err := service.Repository.AddRetweet(userID, tweetID)
+	if err != nil {
+		log.Println(err.Error())
+		return fmt.Errorf("%w %s by user %d", ErrRetweeting, tweetID, userID)
+	}
+
+	if !added {
+		return ErrAlreadyRetweeted
+	}
+
+	service.notify(models.Notification{
+		Type:      models.NotificationRetweet,
+		UserID:    tweet.UserID,
+		ActorID:   userID,
+		TweetID:   tweetID,
+		Timestamp: service.Clock.Now(),
+	})
+
+	return service.scheduleFanOut(tweetID, userID)
+}
+
+// Unretweet undoes the retweet of tweetID by userID, removing it from the timelines of the followers of userID
+// that only got it through the retweet.
+// Returns ErrTweetNotFound if tweetID doesn't exist, ErrNotRetweeted if userID didn't retweet it or
+// ErrorGettingTweet or ErrUndoingRetweet if an error occurred
+func (service TwitterService) Unretweet(userID uint, tweetID uuid.UUID) error {
+	tweet, err := service.findTweet(tweetID)
+	if err != nil {
+		return err
+	}
+
+	removed, err := service.Repository.RemoveRetweet(userID, tweetID)
+	if err != nil {
+		log.Println(err.Error())
+		return fmt.Errorf("%w %s by user %d", ErrUndoingRetweet, tweetID, userID)
+	}
+
+	if !removed {
+		return ErrNotRetweeted
+	}
+
+	err = service.retractRetweet(userID, tweet)
+	if err != nil {
+		log.Println(err.Error())
+		return fmt.Errorf("%w %s by user %d", ErrUndoingRetweet, tweetID, userID)
+	}
+
+	return nil
+}
+
+// Quote creates a tweet of userID with its own content quoting tweetID, of any user, and notifies its author
+// The quote is fanned out to the followers of userID like any other tweet.
+// Returns ErrTweetNotFound if tweetID doesn't exist, models.ErrMaxLengthExceeded if content len is bigger
+// than MaxTweetLength, ErrUserNotFound if userID doesn't exist or ErrorGettingTweet, ErrorGettingUser or
+// ErrCreatingTweet if an error occurred
+func (service TwitterService) Quote(userID uint, tweetID uuid.UUID, content string) (uuid.UUID, error) {
+	quoted, err := service.findTweet(tweetID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	quote, err := models.NewQuote(userID, service.Clock.Now(), content, service.MaxTweetLength, quoted)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	quoteID, err := service.createTweet(*quote)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	service.notify(models.Notification{
+		Type:      models.NotificationQuote,
+		UserID:    quoted.UserID,
+		ActorID:   userID,
+		TweetID:   quoteID,
+		Timestamp: quote.Timestamp,
+	})
+
+	return service.publish(quoteID, userID)
+}
+
+// retractRetweet removes tweet from the timelines of the followers of userID, except for the author of the tweet and
+// the followers of the author or of the other users that retweeted it
+func (service TwitterService) retractRetweet(userID uint, tweet models.Tweet) error {
+	followers, err := service.Repository.GetFollowers(userID)
+	if err != nil {
+		return err
+	}
+
+	retweeters, err := service.Repository.GetRetweeters([]uuid.UUID{tweet.ID})
+	if err != nil {
+		return err
+	}
+
+	kept := map[uint]bool{tweet.UserID: true}
+	for _, sharerID := range append([]uint{tweet.UserID}, retweeters[0]...) {
+		sharerFollowers, err := service.Repository.GetFollowers(sharerID)
+		if err != nil {
+			return err
+		}
+
+		for _, followerID := range sharerFollowers {
+			kept[followerID] = true
+		}
+	}
+
+	var retracted []uint
+	for _, followerID := range followers {
+		if !kept[followerID] {
+			retracted = append(retracted, followerID)
+		}
+	}
+
+	return service.Repository.RemoveTweetFromTimelines(tweet.ID, retracted)
+}
+
+// annotateRetweets sets the RetweetedBy of tweets to the users followed by userID that retweeted them
+func (service TwitterService) annotateRetweets(userID uint, tweets []models.Tweet) error {
+	if len(tweets) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(tweets))
+	for _, tweet := range tweets {
+		ids = append(ids, tweet.ID)
+	}
+
+	retweeters, err := service.Repository.GetRetweeters(ids)
+	if err != nil {
+		return err
+	}
+
+	var following map[uint]bool
+
+	for i := range tweets {
+		if len(retweeters[i]) == 0 {
+			continue
+		}
+
+		if following == nil {
+			followedIDs, err := service.Repository.GetFollowing(userID)
+			if err != nil {
+				return err
+			}
+
+			following = make(map[uint]bool, len(followedIDs))
+			for _, followedID := range followedIDs {
+				following[followedID] = true
+			}
+		}
+
+		for _, retweeterID := range retweeters[i] {
+			if following[retweeterID] {
+				tweets[i].RetweetedBy = append(tweets[i].RetweetedBy, retweeterID)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/PatricioYegros/uala_challenge/app/models"
+
+	"github.com/google/uuid"
+)
+
+// MaxSearchTerms is the max amount of words of a search
+const MaxSearchTerms = 10
+
+var (
+	ErrInvalidSearch = errors.New("search must have between 1 and 10 words or a user")
+	ErrInvalidSince  = errors.New("since must be a RFC 3339 time or a date")
+)
+
+// Search returns a page of up to limit tweets with every word of query, of the user from, created since since,
+// newest first, skipping the ones that expired. from is a handle or an id, with or without @, and is ignored if empty,
+// like a zero since.
+// Cursors work like in GetTimeLine and LikedByMe and RetweetedBy are set for readerID.
+// Returns ErrInvalidSearch if there is nothing to search, ErrUserNotFound if from doesn't exist,
+// ErrInvalidCursor or ErrInvalidLimit if they can't be used or ErrorSearching if an error ocurred
+func (service TwitterService) Search(readerID uint, query, from string, since time.Time, cursor string, limit int) (models.TimelinePage, error) {
+	terms := models.SearchTerms(query)
+	if len(terms) > MaxSearchTerms || (len(terms) == 0 && strings.TrimPrefix(from, "@") == "") {
+		return models.TimelinePage{}, ErrInvalidSearch
+	}
+
+	var userID uint
+
+	from = strings.TrimPrefix(from, "@")
+	if from != "" {
+		user, found, err := service.findUser(from)
+		if err != nil {
+			log.Println(err.Error())
+			return models.TimelinePage{}, ErrorSearching
+		}
+
+		if !found {
+			return models.TimelinePage{}, fmt.Errorf("%w %s", ErrUserNotFound, from)
+		}
+
+		userID = user.ID
+	}
+
+	return service.tweetsPage(readerID, cursor, limit, func() ([]uuid.UUID, error) {
+		return service.Repository.SearchTweets(terms, userID, since)
+	}, ErrorSearching)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"slices"
+	"time"
 
 	"github.com/PatricioYegros/uala_challenge/app/models"
 	"github.com/PatricioYegros/uala_challenge/app/repository"
@@ -16,6 +17,35 @@ import (
 type TwitterService struct {
 	Repository repository.IRepository
 	Clock      utils.IClock
+	// PruneTimelines removes the ids of expired tweets from a timeline when it's read
+	PruneTimelines bool
+	// BackfillTweets is the amount of latest tweets of a followed user merged into the timeline of the new follower.
+	// With 0, follows only receive future tweets.
+	BackfillTweets int
+	// FanOutThreshold is the amount of followers above which the tweets of a user aren't pushed to the followers'
+	// timelines but merged into them when they are read. With 0, tweets are always pushed.
+	FanOutThreshold int64
+	// FanOutQueue runs the fan-out of new tweets in the background. Without it, tweets are fanned out inline.
+	FanOutQueue FanOutQueue
+	// TimelinePageSize is the amount of tweets in a timeline page when no limit is requested, DefaultTimelinePageSize if not set
+	TimelinePageSize int
+	// MaxTweetLength is the max length of the content of a tweet, models.MaxLength if not set
+	MaxTweetLength int
+	// EditWindow is how long after being created a tweet can be edited, DefaultEditWindow if not set
+	EditWindow time.Duration
+	// Notifier delivers the notifications of interactions with tweets. Without it, nobody is notified.
+	Notifier Notifier
+	// TrendsWindow is how far back the hashtags of tweets count for trends, DefaultTrendsWindow if not set
+	TrendsWindow time.Duration
+	// TrendsHalfLife is how long it takes for a tweet to count half for trends, DefaultTrendsHalfLife if not set
+	TrendsHalfLife time.Duration
+	// HandleGracePeriod is how long the previous handle of a user redirects to it, DefaultHandleGracePeriod if not set
+	HandleGracePeriod time.Duration
+}
+
+// FanOutQueue schedules the fan-out of tweets to the timelines of the followers, handled by TwitterService.FanOut
+type FanOutQueue interface {
+	Enqueue(tweetID uuid.UUID, userID uint) error
 }
 
 var (
@@ -26,20 +56,56 @@ var (
 	ErrorGettingTimeline      = errors.New("error getting timeline of user")
 	ErrorGettingFollowersList = errors.New("error getting followers list")
 	ErrorFollowingAlready     = errors.New("error making an already existant follow")
+	ErrUnfollowing            = errors.New("error removing follow")
+	ErrNotFollowing           = errors.New("follow doesn't exist")
+	ErrInvalidPurge           = errors.New("purge must be true or false")
+	ErrBackfillingTimeline    = errors.New("error backfilling timeline")
+	ErrTweetNotFound          = errors.New("tweet doesn't exist")
+	ErrorGettingTweet         = errors.New("error getting tweet")
+	ErrDeletingTweet          = errors.New("error deleting tweet")
+	ErrEditingTweet           = errors.New("error editing tweet")
+	ErrEditWindowExpired      = errors.New("tweet can't be edited anymore")
+	ErrRetweeting             = errors.New("error retweeting")
+	ErrUndoingRetweet         = errors.New("error undoing retweet")
+	ErrAlreadyRetweeted       = errors.New("tweet is already retweeted")
+	ErrNotRetweeted           = errors.New("retweet doesn't exist")
+	ErrLiking                 = errors.New("error liking tweet")
+	ErrUnliking               = errors.New("error unliking tweet")
+	ErrAlreadyLiked           = errors.New("tweet is already liked")
+	ErrNotLiked               = errors.New("like doesn't exist")
+	ErrorGettingLikes         = errors.New("error getting liked tweets of user")
+	ErrorGettingNotifications = errors.New("error getting notifications of user")
+	ErrMarkingNotifications   = errors.New("error marking notifications as read")
+	ErrNotificationNotFound   = errors.New("notification doesn't exist")
+	ErrorGettingHashtag       = errors.New("error getting tweets of hashtag")
+	ErrorGettingTrends        = errors.New("error getting trends")
+	ErrUserNotFound           = errors.New("user doesn't exist")
+	ErrorGettingUser          = errors.New("error getting user")
+	ErrUpdatingProfile        = errors.New("error updating profile")
+	ErrorSearching            = errors.New("error searching tweets")
+	ErrRenamingHandle         = errors.New("error renaming handle")
 )
 
 const (
-	limitTimeLine = 10
+	// backfillAttempts bounds the retries of a backfill racing with new tweets
+	backfillAttempts = 3
+	// DefaultEditWindow is how long after being created a tweet can be edited by default
+	DefaultEditWindow = 30 * time.Minute
 )
 
 // Follow makes followerID to follow userID
-// Returns ErrEqualsIDs if followerID is the same as userID or
-// ErrFollowing if an error occurred
+// Returns ErrEqualsIDs if followerID is the same as userID, ErrUserNotFound if any of them doesn't exist or
+// ErrorGettingUser or ErrFollowing if an error occurred
 func (service TwitterService) Follow(followerID, userID uint) error {
 	if followerID == userID {
 		return ErrEqualsIDs
 	}
 
+	err := service.checkUsers(userID, followerID)
+	if err != nil {
+		return err
+	}
+
 	listOfFollows, err := service.Repository.GetFollowers(userID)
 	if err != nil {
 		log.Println(err.Error())
@@ -56,69 +122,389 @@ func (service TwitterService) Follow(followerID, userID uint) error {
 		return fmt.Errorf("%w from user %d to user %d", ErrFollowing, followerID, userID)
 	}
 
+	if service.BackfillTweets > 0 {
+		//best effort, the follow is already done
+		err = service.backfillTimeline(followerID, userID)
+		if err != nil {
+			log.Println(err.Error())
+		}
+	}
+
+	return nil
+}
+
+// backfillTimeline merges the latest BackfillTweets tweets of authorID into the timeline of userID by timestamp,
+// keeping the newest ones up to the size of the timeline. Expired tweets are dropped from the timeline.
+func (service TwitterService) backfillTimeline(userID, authorID uint) error {
+	latest, err := service.Repository.GetUserTweets(authorID, int64(service.BackfillTweets))
+	if err != nil {
+		return err
+	}
+
+	if len(latest) == 0 {
+		return nil
+	}
+
+	for attempt := 0; attempt < backfillAttempts; attempt++ {
+		current, err := service.Repository.GetTimeLine(userID)
+		if err != nil {
+			return err
+		}
+
+		ids := slices.Clone(current)
+		for _, id := range latest {
+			if !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+
+		tweets, _, err := service.Repository.GetTweets(ids)
+		if err != nil {
+			return err
+		}
+
+		slices.SortStableFunc(tweets, func(a, b models.Tweet) int {
+			return b.Timestamp.Compare(a.Timestamp)
+		})
+
+		merged := make([]uuid.UUID, 0, len(tweets))
+		for _, tweet := range tweets {
+			merged = append(merged, tweet.ID)
+		}
+
+		replaced, err := service.Repository.ReplaceTimeline(userID, current, merged)
+		if err != nil {
+			return err
+		}
+
+		if replaced {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w of user %d, it kept changing", ErrBackfillingTimeline, userID)
+}
+
+// Unfollow makes followerID stop following userID.
+// If purge is set the tweets of userID are also removed from the timeline of followerID.
+// Returns ErrNotFollowing if followerID doesn't follow userID or
+// ErrUnfollowing if an error occurred
+func (service TwitterService) Unfollow(followerID, userID uint, purge bool) error {
+	removed, err := service.Repository.RemoveFollower(userID, followerID)
+	if err != nil {
+		log.Println(err.Error())
+		return fmt.Errorf("%w from user %d to user %d", ErrUnfollowing, followerID, userID)
+	}
+
+	if !removed {
+		return ErrNotFollowing
+	}
+
+	if !purge {
+		return nil
+	}
+
+	err = service.purgeTimeline(followerID, userID)
+	if err != nil {
+		log.Println(err.Error())
+		return fmt.Errorf("%w from user %d to user %d", ErrUnfollowing, followerID, userID)
+	}
+
 	return nil
 }
 
-// Tweet creates a Tweet belonging of userID
-// Returns ErrTweetTooLong if content len is bigger than 150 characters or
-// ErrTweet if an error occurred
+// purgeTimeline removes the tweets of authorID from the timeline of userID
+func (service TwitterService) purgeTimeline(userID, authorID uint) error {
+	tweetsIDs, err := service.Repository.GetTimeLine(userID)
+	if err != nil {
+		return err
+	}
+
+	tweets, _, err := service.Repository.GetTweets(tweetsIDs)
+	if err != nil {
+		return err
+	}
+
+	var purged []uuid.UUID
+	for _, tweet := range tweets {
+		if tweet.UserID == authorID {
+			purged = append(purged, tweet.ID)
+		}
+	}
+
+	if len(purged) == 0 {
+		return nil
+	}
+
+	return service.Repository.RemoveFromTimeline(userID, purged)
+}
+
+// Tweet creates a Tweet belonging of userID and notifies the users mentioned in content
+// Returns models.ErrMaxLengthExceeded if content len is bigger than MaxTweetLength, ErrUserNotFound if userID doesn't
+// exist or ErrorGettingUser or ErrCreatingTweet if an error occurred
 func (service TwitterService) Tweet(userID uint, content string) (uuid.UUID, error) {
-	tweet, err := models.NewTweet(userID, service.Clock.Now(), content)
+	tweet, err := models.NewTweet(userID, service.Clock.Now(), content, service.MaxTweetLength)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	tweetID, err := service.createTweet(*tweet)
 	if err != nil {
 		return uuid.Nil, err
 	}
 
-	tweetID, err := service.Repository.CreateTweet(*tweet)
+	return service.publish(tweetID, userID)
+}
+
+// createTweet stores tweet of an existing user with the users mentioned in its body, and notifies them
+// Returns ErrUserNotFound if its user doesn't exist or ErrorGettingUser or ErrCreatingTweet if an error occurred
+func (service TwitterService) createTweet(tweet models.Tweet) (uuid.UUID, error) {
+	err := service.checkUsers(tweet.UserID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	mentions, err := service.resolveMentions(tweet.Body)
 	if err != nil {
 		log.Println(err.Error())
-		return uuid.Nil, fmt.Errorf("%w from user %d", ErrCreatingTweet, userID)
+		return uuid.Nil, fmt.Errorf("%w from user %d", ErrCreatingTweet, tweet.UserID)
 	}
+	tweet.Mentions = mentions
 
-	followers, err := service.Repository.GetFollowers(userID)
+	tweetID, err := service.Repository.CreateTweet(tweet)
 	if err != nil {
 		log.Println(err.Error())
-		return uuid.Nil, fmt.Errorf("%w from user %d", ErrorGettingFollowersList, userID)
+		return uuid.Nil, fmt.Errorf("%w from user %d", ErrCreatingTweet, tweet.UserID)
 	}
 
-	for _, follower := range followers {
-		err = service.Repository.AddTweetToTimeline(tweetID, follower)
+	tweet.ID = tweetID
+	service.notifyMentions(tweet, nil)
+
+	return tweetID, nil
+}
+
+// publish fans out the new tweetID of userID to the timelines of its followers, returning tweetID
+// Returns ErrorGettingFollowersList or ErrorAddingToTimeline if an error occurred
+func (service TwitterService) publish(tweetID uuid.UUID, userID uint) (uuid.UUID, error) {
+	if service.FanOutThreshold > 0 {
+		count, err := service.Repository.CountFollowers(userID)
 		if err != nil {
-			return uuid.Nil, ErrorAddingToTimeline
+			log.Println(err.Error())
+			return uuid.Nil, fmt.Errorf("%w from user %d", ErrorGettingFollowersList, userID)
+		}
+
+		if count > service.FanOutThreshold {
+			//followers pull it in GetTimeLine
+			return tweetID, nil
 		}
 	}
 
+	err := service.scheduleFanOut(tweetID, userID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
 	return tweetID, nil
 }
 
-// GetTimeline returns the list of tweets in user timeline
-// Returns ErrTimeline if an error ocurred
-func (service TwitterService) GetTimeLine(userID uint) ([]models.Tweet, error) {
-	tweetsIDs, err := service.Repository.GetTimeLine(userID)
+// scheduleFanOut enqueues the fan-out of tweetID to the followers of userID in FanOutQueue.
+// Without FanOutQueue, or if it can't be enqueued, the fan-out runs inline.
+// Returns ErrorGettingFollowersList or ErrorAddingToTimeline if an error occurred
+func (service TwitterService) scheduleFanOut(tweetID uuid.UUID, userID uint) error {
+	if service.FanOutQueue != nil {
+		err := service.FanOutQueue.Enqueue(tweetID, userID)
+		if err == nil {
+			return nil
+		}
+
+		//fall back to the inline fan-out, the tweet already exists
+		log.Println(err.Error())
+	}
+
+	return service.FanOut(tweetID, userID)
+}
+
+// DeleteTweet deletes the tweet of userID and removes it from the timelines of its followers and of the followers of
+// the users that retweeted it
+// Returns ErrTweetNotFound if userID has no tweet with tweetID or
+// ErrorGettingTweet, ErrorGettingFollowersList or ErrDeletingTweet if an error occurred
+func (service TwitterService) DeleteTweet(userID uint, tweetID uuid.UUID) error {
+	tweet, err := service.getTweet(userID, tweetID)
+	if err != nil {
+		return err
+	}
+
+	//the retweets are deleted with the tweet
+	retweeters, err := service.Repository.GetRetweeters([]uuid.UUID{tweetID})
+	if err != nil {
+		log.Println(err.Error())
+		return fmt.Errorf("%w %s", ErrDeletingTweet, tweetID)
+	}
+
+	followers, err := service.followersOf(append([]uint{userID}, retweeters[0]...))
+	if err != nil {
+		log.Println(err.Error())
+		return fmt.Errorf("%w from user %d", ErrorGettingFollowersList, userID)
+	}
+
+	deleted, err := service.Repository.DeleteTweet(tweet, followers)
+	if err != nil {
+		log.Println(err.Error())
+		return fmt.Errorf("%w %s", ErrDeletingTweet, tweetID)
+	}
+
+	if !deleted {
+		return ErrTweetNotFound
+	}
+
+	return nil
+}
+
+// followersOf returns the followers of any of userIDs, without repeating them
+func (service TwitterService) followersOf(userIDs []uint) ([]uint, error) {
+	var followers []uint
+	seen := make(map[uint]bool)
+
+	for _, userID := range userIDs {
+		userFollowers, err := service.Repository.GetFollowers(userID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, followerID := range userFollowers {
+			if !seen[followerID] {
+				seen[followerID] = true
+				followers = append(followers, followerID)
+			}
+		}
+	}
+
+	return followers, nil
+}
+
+// EditTweet replaces the content of a tweet of userID, keeping the previous one in its history.
+// The new content is validated like a new tweet, and only the users it newly mentions are notified.
+// Returns ErrTweetNotFound if userID has no tweet with tweetID, ErrEditWindowExpired if it's older than EditWindow,
+// models.ErrMaxLengthExceeded if content len is bigger than MaxTweetLength or
+// ErrorGettingTweet or ErrEditingTweet if an error occurred
+func (service TwitterService) EditTweet(userID uint, tweetID uuid.UUID, content string) (models.Tweet, error) {
+	tweet, err := service.getTweet(userID, tweetID)
+	if err != nil {
+		return models.Tweet{}, err
+	}
+
+	now := service.Clock.Now()
+	if now.Sub(tweet.Timestamp) > service.editWindow() {
+		return models.Tweet{}, ErrEditWindowExpired
+	}
+
+	edit, err := models.NewTweet(userID, tweet.Timestamp, content, service.MaxTweetLength)
+	if err != nil {
+		return models.Tweet{}, err
+	}
+
+	mentions, err := service.resolveMentions(content)
+	if err != nil {
+		log.Println(err.Error())
+		return models.Tweet{}, fmt.Errorf("%w %s", ErrEditingTweet, tweetID)
+	}
+
+	edit.ID = tweetID
+	edit.Mentions = mentions
+	edit.EditedAt = &now
+
+	edited, err := service.Repository.EditTweet(*edit)
+	if err != nil {
+		log.Println(err.Error())
+		return models.Tweet{}, fmt.Errorf("%w %s", ErrEditingTweet, tweetID)
+	}
+
+	if !edited {
+		return models.Tweet{}, ErrTweetNotFound
+	}
+
+	previous := tweet.Mentions
+	tweet.Body = content
+	tweet.Mentions = mentions
+	tweet.Hashtags = edit.Hashtags
+	tweet.EditedAt = &now
+
+	service.notifyMentions(tweet, previous)
+
+	return tweet, nil
+}
+
+// GetTweetHistory returns every revision of a tweet of userID, oldest first, ending with the current one
+// Returns ErrTweetNotFound if userID has no tweet with tweetID
+func (service TwitterService) GetTweetHistory(userID uint, tweetID uuid.UUID) ([]models.TweetRevision, error) {
+	tweet, err := service.getTweet(userID, tweetID)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := service.Repository.GetTweetHistory(tweetID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, fmt.Errorf("%w %s", ErrorGettingTweet, tweetID)
+	}
+
+	return append(history, tweet.Revision()), nil
+}
+
+// getTweet returns the tweet of userID with tweetID
+// Returns ErrTweetNotFound if userID has no tweet with tweetID
+func (service TwitterService) getTweet(userID uint, tweetID uuid.UUID) (models.Tweet, error) {
+	tweet, err := service.findTweet(tweetID)
 	if err != nil {
-		return nil, ErrorGettingTimeline
+		return models.Tweet{}, err
 	}
 
-	if len(tweetsIDs) <= limitTimeLine {
-		return service.Repository.GetTweets(tweetsIDs)
+	if tweet.UserID != userID {
+		return models.Tweet{}, ErrTweetNotFound
 	}
 
-	return service.Repository.GetTweets(tweetsIDs[0:limitTimeLine])
+	return tweet, nil
 }
 
-// Login logs the user in the app
-// Returns err if Redis return error
-func (service TwitterService) Login(userID uint) error {
-	return service.Repository.Login(userID)
+// findTweet returns the tweet with tweetID, of any user
+// Returns ErrTweetNotFound if it doesn't exist
+func (service TwitterService) findTweet(tweetID uuid.UUID) (models.Tweet, error) {
+	tweets, _, err := service.Repository.GetTweets([]uuid.UUID{tweetID})
+	if err != nil {
+		log.Println(err.Error())
+		return models.Tweet{}, fmt.Errorf("%w %s", ErrorGettingTweet, tweetID)
+	}
+
+	if len(tweets) == 0 {
+		return models.Tweet{}, ErrTweetNotFound
+	}
+
+	return tweets[0], nil
 }
 
-// CheckUserLogs checks if the user logged is the user who wants to make the action
-// Rerurns err if Redis return error
+// editWindow returns EditWindow, or DefaultEditWindow if it isn't set
+func (service TwitterService) editWindow() time.Duration {
+	if service.EditWindow <= 0 {
+		return DefaultEditWindow
+	}
+
+	return service.EditWindow
+}
+
+// FanOut adds tweetID, of userID or retweeted by userID, to the timelines of its followers that don't have it yet
+// Returns ErrorGettingFollowersList or ErrorAddingToTimeline if an error occurred
+func (service TwitterService) FanOut(tweetID uuid.UUID, userID uint) error {
+	followers, err := service.Repository.GetFollowers(userID)
+	if err != nil {
+		log.Println(err.Error())
+		return fmt.Errorf("%w from user %d", ErrorGettingFollowersList, userID)
+	}
 
-func (service TwitterService) CheckUserLog(userID uint) (bool, error) {
-	value, err := service.Repository.CheckUserLog(userID)
+	err = service.Repository.AddTweetToTimelines(tweetID, followers)
 	if err != nil {
-		return false, err
+		log.Println(err.Error())
+		return ErrorAddingToTimeline
 	}
 
-	return value, nil
+	return nil
 }
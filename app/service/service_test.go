@@ -3,17 +3,21 @@ package service_test
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/PatricioYegros/uala_challenge/app/models"
+	"github.com/PatricioYegros/uala_challenge/app/repository"
 	"github.com/PatricioYegros/uala_challenge/app/service"
 	"github.com/PatricioYegros/uala_challenge/app/utils"
 	"github.com/go-playground/assert/v2"
 	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
 
 	repositoryMocks "github.com/PatricioYegros/uala_challenge/mocks/repository"
+	serviceMocks "github.com/PatricioYegros/uala_challenge/mocks/service"
 	utilsMocks "github.com/PatricioYegros/uala_challenge/mocks/utils"
 )
 
@@ -25,6 +29,7 @@ func TestFollowReturnsErrorSameIDs(t *testing.T) {
 
 func TestFollowReturnsErrorGettingList(t *testing.T) {
 	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
 
 	followService := service.TwitterService{
 		Repository: mockRepository,
@@ -38,6 +43,7 @@ func TestFollowReturnsErrorGettingList(t *testing.T) {
 
 func TestFollowRepositoryReturnsError(t *testing.T) {
 	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
 	mockListFollowers := []uint{2, 3}
 
 	followService := service.TwitterService{
@@ -53,6 +59,7 @@ func TestFollowRepositoryReturnsError(t *testing.T) {
 
 func TestFollowRetunsErrorAlreadyFollowing(t *testing.T) {
 	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
 	mockListFollowers := []uint{2}
 
 	followService := service.TwitterService{
@@ -67,6 +74,7 @@ func TestFollowRetunsErrorAlreadyFollowing(t *testing.T) {
 
 func TestFollowSuccess(t *testing.T) {
 	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
 	mockListFollowers := []uint{2, 3}
 
 	followService := service.TwitterService{
@@ -79,6 +87,163 @@ func TestFollowSuccess(t *testing.T) {
 	assert.Equal(t, err, nil)
 }
 
+func TestFollowBackfillsTimelineByTimestamp(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
+
+	followService := service.TwitterService{
+		Repository:     mockRepository,
+		BackfillTweets: 2,
+	}
+
+	now := time.Now()
+	existing := models.Tweet{ID: uuid.New(), UserID: 3, Timestamp: now.Add(-time.Minute)}
+	newer := models.Tweet{ID: uuid.New(), UserID: 1, Timestamp: now}
+	older := models.Tweet{ID: uuid.New(), UserID: 1, Timestamp: now.Add(-time.Hour)}
+
+	mockRepository.On("GetFollowers", uint(1)).Return([]uint{}, nil)
+	mockRepository.On("AddFollower", uint(1), uint(2)).Return(nil)
+	mockRepository.On("GetUserTweets", uint(1), int64(2)).Return([]uuid.UUID{newer.ID, older.ID}, nil)
+	mockRepository.On("GetTimeLine", uint(2)).Return([]uuid.UUID{existing.ID}, nil)
+	mockRepository.On("GetTweets", []uuid.UUID{existing.ID, newer.ID, older.ID}).Return([]models.Tweet{existing, newer, older}, nil, nil)
+	mockRepository.On("ReplaceTimeline", uint(2), []uuid.UUID{existing.ID}, []uuid.UUID{newer.ID, existing.ID, older.ID}).Return(true, nil)
+
+	err := followService.Follow(2, 1)
+	assert.Equal(t, err, nil)
+}
+
+func TestFollowBackfillRetriesWhenTimelineChanges(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
+
+	followService := service.TwitterService{
+		Repository:     mockRepository,
+		BackfillTweets: 1,
+	}
+
+	tweet := models.Tweet{ID: uuid.New(), UserID: 1, Timestamp: time.Now()}
+
+	mockRepository.On("GetFollowers", uint(1)).Return([]uint{}, nil)
+	mockRepository.On("AddFollower", uint(1), uint(2)).Return(nil)
+	mockRepository.On("GetUserTweets", uint(1), int64(1)).Return([]uuid.UUID{tweet.ID}, nil)
+	mockRepository.On("GetTimeLine", uint(2)).Return([]uuid.UUID{}, nil)
+	mockRepository.On("GetTweets", []uuid.UUID{tweet.ID}).Return([]models.Tweet{tweet}, nil, nil)
+	mockRepository.On("ReplaceTimeline", uint(2), []uuid.UUID{}, []uuid.UUID{tweet.ID}).Return(false, nil).Once()
+	mockRepository.On("ReplaceTimeline", uint(2), []uuid.UUID{}, []uuid.UUID{tweet.ID}).Return(true, nil).Once()
+
+	err := followService.Follow(2, 1)
+	assert.Equal(t, err, nil)
+}
+
+func TestUnfollowNotFollowing(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	unfollowService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	mockRepository.On("RemoveFollower", uint(1), uint(2)).Return(false, nil)
+
+	err := unfollowService.Unfollow(2, 1, true)
+	assert.Equal(t, err, service.ErrNotFollowing)
+}
+
+func TestUnfollowRepositoryReturnsError(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	unfollowService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	mockRepository.On("RemoveFollower", uint(1), uint(2)).Return(false, errors.New("Error"))
+
+	err := unfollowService.Unfollow(2, 1, false)
+	assert.Equal(t, err, fmt.Errorf("%w from user %d to user %d", service.ErrUnfollowing, uint(2), uint(1)))
+}
+
+func TestUnfollowSuccess(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	unfollowService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	mockRepository.On("RemoveFollower", uint(1), uint(2)).Return(true, nil)
+
+	err := unfollowService.Unfollow(2, 1, false)
+	assert.Equal(t, err, nil)
+}
+
+func TestUnfollowPurgesTimeline(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	unfollowService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	unfollowed := models.Tweet{ID: uuid.New(), UserID: 1}
+	other := models.Tweet{ID: uuid.New(), UserID: 3}
+	ids := []uuid.UUID{unfollowed.ID, other.ID}
+
+	mockRepository.On("RemoveFollower", uint(1), uint(2)).Return(true, nil)
+	mockRepository.On("GetTimeLine", uint(2)).Return(ids, nil)
+	mockRepository.On("GetTweets", ids).Return([]models.Tweet{unfollowed, other}, nil, nil)
+	mockRepository.On("RemoveFromTimeline", uint(2), []uuid.UUID{unfollowed.ID}).Return(nil)
+
+	err := unfollowService.Unfollow(2, 1, true)
+	assert.Equal(t, err, nil)
+}
+
+func TestGetFollowersInvalidCursor(t *testing.T) {
+	followersService := service.TwitterService{
+		Repository: repositoryMocks.NewIRepository(t),
+	}
+
+	_, err := followersService.GetFollowers(1, "not-a-cursor", 0)
+	assert.Equal(t, err, service.ErrInvalidCursor)
+}
+
+func TestGetFollowersInvalidLimit(t *testing.T) {
+	followersService := service.TwitterService{
+		Repository: repositoryMocks.NewIRepository(t),
+	}
+
+	_, err := followersService.GetFollowers(1, "", -1)
+	assert.Equal(t, err, service.ErrInvalidLimit)
+}
+
+func TestGetFollowersDefaultPage(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
+
+	followersService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	mockRepository.On("ScanFollowers", uint(1), uint64(0), int64(service.DefaultPageSize)).Return([]uint{2, 3}, uint64(3), nil)
+	mockRepository.On("CountFollowers", uint(1)).Return(int64(30), nil)
+
+	page, err := followersService.GetFollowers(1, "", 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, page, models.UsersPage{UserIDs: []uint{2, 3}, NextCursor: "3", Count: 30})
+}
+
+func TestGetFollowingLastPage(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
+
+	followingService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	mockRepository.On("ScanFollowing", uint(1), uint64(3), int64(service.MaxPageSize)).Return([]uint{4}, uint64(0), nil)
+	mockRepository.On("CountFollowing", uint(1)).Return(int64(3), nil)
+
+	page, err := followingService.GetFollowing(1, "3", 1000)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, page, models.UsersPage{UserIDs: []uint{4}, Count: 3})
+}
+
 func TestTweetErrorMaxLengthExceeded(t *testing.T) {
 	mockRepository := repositoryMocks.NewIRepository(t)
 
@@ -88,11 +253,25 @@ func TestTweetErrorMaxLengthExceeded(t *testing.T) {
 	}
 
 	_, err := tweetService.Tweet(0, strings.Repeat("uala_challenge", 15))
-	assert.Equal(t, err, models.ErrMaxLengthExceeded)
+	assert.Equal(t, errors.Is(err, models.ErrMaxLengthExceeded), true)
+}
+
+func TestTweetErrorConfiguredMaxLengthExceeded(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	tweetService := service.TwitterService{
+		Repository:     mockRepository,
+		Clock:          utils.Clock{},
+		MaxTweetLength: 10,
+	}
+
+	_, err := tweetService.Tweet(0, "uala_challenge")
+	assert.Equal(t, errors.Is(err, models.ErrMaxLengthExceeded), true)
 }
 
 func TestTweetErrorCreatingTweet(t *testing.T) {
 	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
 	mockClock := utilsMocks.NewIClock(t)
 
 	tweetService := service.TwitterService{
@@ -117,6 +296,7 @@ func TestTweetErrorCreatingTweet(t *testing.T) {
 
 func TestTweetErrorGettingListOfFollowers(t *testing.T) {
 	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
 	mockClock := utilsMocks.NewIClock(t)
 
 	tweetService := service.TwitterService{
@@ -142,6 +322,7 @@ func TestTweetErrorGettingListOfFollowers(t *testing.T) {
 
 func TestTweetErrorAddToTimeline(t *testing.T) {
 	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
 	mockClock := utilsMocks.NewIClock(t)
 
 	tweetService := service.TwitterService{
@@ -162,7 +343,7 @@ func TestTweetErrorAddToTimeline(t *testing.T) {
 	mockClock.On("Now").Return(now)
 	mockRepository.On("CreateTweet", tweet).Return(id, nil)
 	mockRepository.On("GetFollowers", uint(1)).Return(listOfFollowers, nil)
-	mockRepository.On("AddTweetToTimeline", id, uint(2)).Return(errors.New("Error"))
+	mockRepository.On("AddTweetToTimelines", id, listOfFollowers).Return(errors.New("Error"))
 
 	_, err := tweetService.Tweet(1, "uala_challenge")
 	assert.Equal(t, err, service.ErrorAddingToTimeline)
@@ -170,6 +351,7 @@ func TestTweetErrorAddToTimeline(t *testing.T) {
 
 func TestTweetSuccess(t *testing.T) {
 	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
 	mockClock := utilsMocks.NewIClock(t)
 
 	tweetService := service.TwitterService{
@@ -190,15 +372,173 @@ func TestTweetSuccess(t *testing.T) {
 	mockClock.On("Now").Return(now)
 	mockRepository.On("CreateTweet", tweet).Return(id, nil)
 	mockRepository.On("GetFollowers", uint(1)).Return(listOfFollowers, nil)
-	mockRepository.On("AddTweetToTimeline", id, uint(2)).Return(nil)
+	mockRepository.On("AddTweetToTimelines", id, listOfFollowers).Return(nil)
 
 	tweetId, err := tweetService.Tweet(uint(1), "uala_challenge")
 	assert.Equal(t, tweetId, id)
 	assert.Equal(t, err, nil)
 }
 
+func TestTweetAboveFanOutThresholdIsNotPushed(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
+
+	tweetService := service.TwitterService{
+		Repository:      mockRepository,
+		Clock:           utils.Clock{},
+		FanOutThreshold: 100,
+	}
+
+	tweetID := uuid.New()
+
+	mockRepository.On("CreateTweet", mock.Anything).Return(tweetID, nil)
+	mockRepository.On("CountFollowers", uint(1)).Return(int64(101), nil)
+
+	id, err := tweetService.Tweet(1, "uala_challenge")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, id, tweetID)
+}
+
+func TestTweetBelowFanOutThresholdIsPushed(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
+
+	tweetService := service.TwitterService{
+		Repository:      mockRepository,
+		Clock:           utils.Clock{},
+		FanOutThreshold: 100,
+	}
+
+	tweetID := uuid.New()
+
+	mockRepository.On("CreateTweet", mock.Anything).Return(tweetID, nil)
+	mockRepository.On("CountFollowers", uint(1)).Return(int64(100), nil)
+	mockRepository.On("GetFollowers", uint(1)).Return([]uint{2}, nil)
+	mockRepository.On("AddTweetToTimelines", tweetID, []uint{2}).Return(nil)
+
+	_, err := tweetService.Tweet(1, "uala_challenge")
+	assert.Equal(t, err, nil)
+}
+
+func TestGetTimelineMergesPulledTweets(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
+
+	timelineService := service.TwitterService{
+		Repository:      mockRepository,
+		FanOutThreshold: 100,
+	}
+
+	now := time.Now()
+	pushed := models.Tweet{ID: uuid.New(), UserID: 2, Timestamp: now.Add(-time.Minute)}
+	pulled := models.Tweet{ID: uuid.New(), UserID: 3, Timestamp: now}
+
+	withoutAnnotations(mockRepository)
+	mockRepository.On("GetTimeLine", uint(1)).Return([]uuid.UUID{pushed.ID}, nil)
+	mockRepository.On("GetTweets", []uuid.UUID{pushed.ID}).Return([]models.Tweet{pushed}, nil, nil)
+	mockRepository.On("GetFollowing", uint(1)).Return([]uint{2, 3}, nil)
+	mockRepository.On("CountFollowersOf", []uint{2, 3}).Return([]int64{5, 500}, nil)
+	mockRepository.On("GetUserTweets", uint(3), int64(11)).Return([]uuid.UUID{pulled.ID}, nil)
+	mockRepository.On("GetTweets", []uuid.UUID{pulled.ID}).Return([]models.Tweet{pulled}, nil, nil)
+
+	page, err := timelineService.GetTimeLine(1, "", 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, page.Tweets, []models.Tweet{pulled, pushed})
+}
+
+func TestGetTimelinePullsAPageOfTweetsPerUser(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
+
+	timelineService := service.TwitterService{
+		Repository:      mockRepository,
+		FanOutThreshold: 100,
+	}
+
+	ids, tweets := newTimeline(30)
+
+	withoutAnnotations(mockRepository)
+	mockRepository.On("GetTimeLine", uint(1)).Return(nil, nil)
+	mockRepository.On("GetFollowing", uint(1)).Return([]uint{2}, nil)
+	mockRepository.On("CountFollowersOf", []uint{2}).Return([]int64{500}, nil)
+	//more than the default page size, which would leave pulled tweets out of the page
+	mockRepository.On("GetUserTweets", uint(2), int64(21)).Return(ids[:21], nil)
+	mockRepository.On("GetTweets", ids[:21]).Return(tweets[:21], nil, nil)
+
+	page, err := timelineService.GetTimeLine(1, "", 20)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, page.Tweets, tweets[:20])
+	assert.NotEqual(t, page.NextCursor, "")
+}
+
+func TestGetTimelineConfiguredPageSize(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
+
+	timelineService := service.TwitterService{
+		Repository:       mockRepository,
+		TimelinePageSize: 1,
+	}
+
+	now := time.Now()
+	newest := models.Tweet{ID: uuid.New(), UserID: 2, Timestamp: now}
+	oldest := models.Tweet{ID: uuid.New(), UserID: 2, Timestamp: now.Add(-time.Minute)}
+
+	withoutAnnotations(mockRepository)
+	mockRepository.On("GetTimeLine", uint(1)).Return([]uuid.UUID{newest.ID, oldest.ID, uuid.New()}, nil)
+	mockRepository.On("GetTweets", []uuid.UUID{newest.ID, oldest.ID}).Return([]models.Tweet{newest, oldest}, nil, nil)
+
+	page, err := timelineService.GetTimeLine(1, "", 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, page.Tweets, []models.Tweet{newest})
+	assert.NotEqual(t, page.NextCursor, "")
+}
+
+func TestTweetEnqueuesFanOut(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
+	mockQueue := serviceMocks.NewFanOutQueue(t)
+
+	tweetService := service.TwitterService{
+		Repository:  mockRepository,
+		Clock:       utils.Clock{},
+		FanOutQueue: mockQueue,
+	}
+
+	tweetID := uuid.New()
+
+	mockRepository.On("CreateTweet", mock.Anything).Return(tweetID, nil)
+	mockQueue.On("Enqueue", tweetID, uint(1)).Return(nil)
+
+	_, err := tweetService.Tweet(1, "uala_challenge")
+	assert.Equal(t, err, nil)
+}
+
+func TestTweetFansOutInlineIfEnqueueFails(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
+	mockQueue := serviceMocks.NewFanOutQueue(t)
+
+	tweetService := service.TwitterService{
+		Repository:  mockRepository,
+		Clock:       utils.Clock{},
+		FanOutQueue: mockQueue,
+	}
+
+	tweetID := uuid.New()
+
+	mockRepository.On("CreateTweet", mock.Anything).Return(tweetID, nil)
+	mockQueue.On("Enqueue", tweetID, uint(1)).Return(errors.New("Error"))
+	mockRepository.On("GetFollowers", uint(1)).Return([]uint{2}, nil)
+	mockRepository.On("AddTweetToTimelines", tweetID, []uint{2}).Return(nil)
+
+	_, err := tweetService.Tweet(1, "uala_challenge")
+	assert.Equal(t, err, nil)
+}
+
 func TestGetTimelineErrorRepository(t *testing.T) {
 	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
 
 	timelineService := service.TwitterService{
 		Repository: mockRepository,
@@ -206,12 +546,13 @@ func TestGetTimelineErrorRepository(t *testing.T) {
 
 	mockRepository.On("GetTimeLine", uint(1)).Return(nil, errors.New("Error"))
 
-	_, err := timelineService.GetTimeLine(1)
+	_, err := timelineService.GetTimeLine(1, "", 0)
 	assert.Equal(t, err, service.ErrorGettingTimeline)
 }
 
 func TestGetTimelineShorterThanLimit(t *testing.T) {
 	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
 	mockClock := utilsMocks.NewIClock(t)
 
 	timelineService := service.TwitterService{
@@ -229,17 +570,20 @@ func TestGetTimelineShorterThanLimit(t *testing.T) {
 	}
 	tweetArray := []models.Tweet{tweet}
 
+	withoutAnnotations(mockRepository)
 	mockRepository.On("GetTimeLine", uint(1)).Return(tweetsTimeLine, nil)
-	mockRepository.On("GetTweets", tweetsTimeLine).Return(tweetArray, nil)
+	mockRepository.On("GetTweets", tweetsTimeLine).Return(tweetArray, nil, nil)
 
-	tweets, err := timelineService.GetTimeLine(1)
-	assert.Equal(t, tweets, tweetArray)
+	page, err := timelineService.GetTimeLine(1, "", 0)
+	assert.Equal(t, page.Tweets, tweetArray)
+	assert.Equal(t, page.NextCursor, "")
 	assert.Equal(t, err, nil)
 
 }
 
 func TestGetTimelineLargerThanLimit(t *testing.T) {
 	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
 	mockClock := utilsMocks.NewIClock(t)
 
 	timelineService := service.TwitterService{
@@ -248,18 +592,1576 @@ func TestGetTimelineLargerThanLimit(t *testing.T) {
 	}
 
 	now := time.Now()
-	tweetsTimeLine := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()}
+	tweetsTimeLine := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()}
 	tweet := models.Tweet{
 		UserID:    1,
 		Timestamp: now,
 		Body:      "uala_challenge",
 	}
-	tweetArray := []models.Tweet{tweet, tweet, tweet, tweet, tweet, tweet, tweet, tweet, tweet, tweet}
+	tweetArray := []models.Tweet{tweet, tweet, tweet, tweet, tweet, tweet, tweet, tweet, tweet, tweet, tweet}
+
+	withoutAnnotations(mockRepository)
+	mockRepository.On("GetTimeLine", uint(1)).Return(tweetsTimeLine, nil)
+	mockRepository.On("GetTweets", tweetsTimeLine[0:11]).Return(tweetArray, nil, nil)
+
+	page, err := timelineService.GetTimeLine(1, "", 0)
+	assert.Equal(t, page.Tweets, tweetArray[0:10])
+	assert.NotEqual(t, page.NextCursor, "")
+	assert.Equal(t, err, nil)
+}
+
+func TestGetTimelineSkipsExpiredTweets(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
+
+	timelineService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	expired := uuid.New()
+	tweet := models.Tweet{
+		ID:        uuid.New(),
+		UserID:    1,
+		Timestamp: time.Now(),
+		Body:      "uala_challenge",
+	}
+	tweetsTimeLine := []uuid.UUID{expired, tweet.ID}
+
+	withoutAnnotations(mockRepository)
+	mockRepository.On("GetTimeLine", uint(1)).Return(tweetsTimeLine, nil)
+	mockRepository.On("GetTweets", tweetsTimeLine).Return([]models.Tweet{tweet}, []uuid.UUID{expired}, nil)
+
+	page, err := timelineService.GetTimeLine(1, "", 0)
+	assert.Equal(t, page.Tweets, []models.Tweet{tweet})
+	assert.Equal(t, err, nil)
+}
+
+func TestGetTimelinePrunesExpiredTweets(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
+
+	timelineService := service.TwitterService{
+		Repository:     mockRepository,
+		PruneTimelines: true,
+	}
+
+	expired := uuid.New()
+	tweetsTimeLine := []uuid.UUID{expired}
 
 	mockRepository.On("GetTimeLine", uint(1)).Return(tweetsTimeLine, nil)
-	mockRepository.On("GetTweets", tweetsTimeLine[0:10]).Return(tweetArray, nil)
+	mockRepository.On("GetTweets", tweetsTimeLine).Return([]models.Tweet{}, []uuid.UUID{expired}, nil)
+	mockRepository.On("RemoveFromTimeline", uint(1), []uuid.UUID{expired}).Return(errors.New("Error"))
+
+	page, err := timelineService.GetTimeLine(1, "", 0)
+	assert.Equal(t, page.Tweets, []models.Tweet{})
+	assert.Equal(t, err, nil)
+}
+
+// newTimeline returns the ids and tweets of a timeline of count tweets, newest first, a minute apart
+func newTimeline(count int) ([]uuid.UUID, []models.Tweet) {
+	now := time.Now()
+	ids := make([]uuid.UUID, 0, count)
+	tweets := make([]models.Tweet, 0, count)
+
+	for i := 0; i < count; i++ {
+		tweet := models.Tweet{ID: uuid.New(), UserID: 2, Timestamp: now.Add(-time.Duration(i) * time.Minute)}
+		ids = append(ids, tweet.ID)
+		tweets = append(tweets, tweet)
+	}
+
+	return ids, tweets
+}
+
+func TestGetTimelineNextCursorContinuesWithOlderTweets(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
+
+	timelineService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	ids, tweets := newTimeline(3)
+
+	withoutAnnotations(mockRepository)
+	mockRepository.On("GetTimeLine", uint(1)).Return(ids, nil)
+	mockRepository.On("GetTweets", ids).Return(tweets, nil, nil)
+	mockRepository.On("GetTweets", ids[2:]).Return(tweets[2:], nil, nil)
+
+	page, err := timelineService.GetTimeLine(1, "", 2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, page.Tweets, tweets[:2])
+
+	page, err = timelineService.GetTimeLine(1, page.NextCursor, 2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, page.Tweets, tweets[2:])
+	assert.Equal(t, page.NextCursor, "")
+}
+
+func TestGetTimelineCursorOfRemovedTweetUsesTimestamp(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
+
+	timelineService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	ids, tweets := newTimeline(4)
+
+	withoutAnnotations(mockRepository)
+	mockRepository.On("GetTimeLine", uint(1)).Return(ids, nil).Once()
+	mockRepository.On("GetTweets", ids[:3]).Return(tweets[:3], nil, nil)
+
+	page, err := timelineService.GetTimeLine(1, "", 2)
+	assert.Equal(t, err, nil)
+
+	//the last tweet of the page is removed from the timeline
+	remaining := []uuid.UUID{ids[0], ids[2], ids[3]}
+	mockRepository.On("GetTimeLine", uint(1)).Return(remaining, nil).Once()
+	mockRepository.On("GetTweets", remaining).Return([]models.Tweet{tweets[0], tweets[2], tweets[3]}, nil, nil)
+
+	page, err = timelineService.GetTimeLine(1, page.NextCursor, 2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, page.Tweets, tweets[2:])
+}
+
+func TestGetTimelinePreviousCursorReturnsNewerTweets(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
+
+	timelineService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	ids, tweets := newTimeline(3)
+
+	withoutAnnotations(mockRepository)
+	mockRepository.On("GetTimeLine", uint(1)).Return(ids[2:], nil).Once()
+	mockRepository.On("GetTweets", ids[2:]).Return(tweets[2:], nil, nil)
+
+	page, err := timelineService.GetTimeLine(1, "", 0)
+	assert.Equal(t, err, nil)
+	previous := page.PreviousCursor
+
+	//nothing new yet
+	mockRepository.On("GetTimeLine", uint(1)).Return(ids[2:], nil).Once()
 
-	tweets, err := timelineService.GetTimeLine(1)
-	assert.Equal(t, tweets, tweetArray)
+	page, err = timelineService.GetTimeLine(1, previous, 0)
 	assert.Equal(t, err, nil)
+	assert.Equal(t, len(page.Tweets), 0)
+	assert.Equal(t, page.PreviousCursor, previous)
+
+	mockRepository.On("GetTimeLine", uint(1)).Return(ids, nil).Once()
+	mockRepository.On("GetTweets", ids[:2]).Return(tweets[:2], nil, nil)
+
+	page, err = timelineService.GetTimeLine(1, previous, 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, page.Tweets, tweets[:2])
+}
+
+func TestGetTimelinePreviousCursorOfRemovedTweetKeepsNewerTweetsAfterOlderOnes(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
+
+	timelineService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	ids, tweets := newTimeline(4)
+
+	withoutAnnotations(mockRepository)
+	mockRepository.On("GetTimeLine", uint(1)).Return(ids[2:3], nil).Once()
+	mockRepository.On("GetTweets", ids[2:3]).Return(tweets[2:3], nil, nil)
+
+	page, err := timelineService.GetTimeLine(1, "", 0)
+	assert.Equal(t, err, nil)
+
+	//the cursor tweet is removed and an older tweet is retweeted between the newer ones
+	timeline := []uuid.UUID{ids[0], ids[3], ids[1]}
+	mockRepository.On("GetTimeLine", uint(1)).Return(timeline, nil).Once()
+	mockRepository.On("GetTweets", timeline).Return([]models.Tweet{tweets[0], tweets[3], tweets[1]}, nil, nil)
+
+	page, err = timelineService.GetTimeLine(1, page.PreviousCursor, 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, page.Tweets, tweets[:2])
+}
+
+func TestGetTimelineInvalidCursor(t *testing.T) {
+	timelineService := service.TwitterService{
+		Repository: repositoryMocks.NewIRepository(t),
+	}
+
+	_, err := timelineService.GetTimeLine(1, "not-a-cursor", 0)
+	assert.Equal(t, err, service.ErrInvalidCursor)
+}
+
+func newTestUser(t *testing.T, id uint, handle, password string) models.User {
+	user, err := models.NewUser(handle, password, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.ID = id
+
+	return *user
+}
+
+func TestLoginStoresHashedToken(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	mockClock := utilsMocks.NewIClock(t)
+
+	loginService := service.TwitterService{
+		Repository: mockRepository,
+		Clock:      mockClock,
+	}
+
+	now := time.Now()
+	var stored models.Session
+
+	mockClock.On("Now").Return(now)
+	mockRepository.On("GetUserByHandle", "uala").Return(newTestUser(t, 1, "uala", "password123"), true, nil)
+	mockRepository.On("AddFailedLogin", uint(1)).Return(int64(1), nil)
+	mockRepository.On("ResetFailedLogins", uint(1)).Return(nil)
+	mockRepository.On("CreateSession", mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(0).(models.Session)
+	}).Return(nil)
+
+	token, userID, err := loginService.Login("uala", "password123")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, userID, uint(1))
+	assert.NotEqual(t, token, "")
+	assert.Equal(t, stored, models.Session{ID: service.SessionID(token), UserID: 1, CreatedAt: now, LastUsedAt: now})
+}
+
+func TestLoginErrorCreatingSession(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	loginService := service.TwitterService{
+		Repository: mockRepository,
+		Clock:      utils.Clock{},
+	}
+
+	mockRepository.On("GetUserByHandle", "uala").Return(newTestUser(t, 1, "uala", "password123"), true, nil)
+	mockRepository.On("AddFailedLogin", uint(1)).Return(int64(1), nil)
+	mockRepository.On("ResetFailedLogins", uint(1)).Return(nil)
+	mockRepository.On("CreateSession", mock.Anything).Return(errors.New("Error"))
+
+	_, _, err := loginService.Login("uala", "password123")
+	assert.Equal(t, err, fmt.Errorf("%w for user %d", service.ErrCreatingSession, 1))
+}
+
+func TestLoginUnknownHandle(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	loginService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	mockRepository.On("GetUserByHandle", "uala").Return(models.User{}, false, nil)
+
+	_, _, err := loginService.Login("uala", "password123")
+	assert.Equal(t, err, service.ErrInvalidCredentials)
+}
+
+func TestLoginWrongPasswordCountsFailure(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	loginService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	mockRepository.On("GetUserByHandle", "uala").Return(newTestUser(t, 1, "uala", "password123"), true, nil)
+	mockRepository.On("AddFailedLogin", uint(1)).Return(int64(3), nil)
+
+	_, _, err := loginService.Login("uala", "wrong-password")
+	assert.Equal(t, err, service.ErrInvalidCredentials)
+}
+
+func TestLoginAccountLocked(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	loginService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	mockRepository.On("GetUserByHandle", "uala").Return(newTestUser(t, 1, "uala", "password123"), true, nil)
+	mockRepository.On("AddFailedLogin", uint(1)).Return(int64(service.MaxFailedLogins+1), nil)
+
+	_, _, err := loginService.Login("uala", "password123")
+	assert.Equal(t, err, service.ErrAccountLocked)
+}
+
+func TestLoginResetsFailedLogins(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	loginService := service.TwitterService{
+		Repository: mockRepository,
+		Clock:      utils.Clock{},
+	}
+
+	mockRepository.On("GetUserByHandle", "uala").Return(newTestUser(t, 1, "uala", "password123"), true, nil)
+	mockRepository.On("AddFailedLogin", uint(1)).Return(int64(service.MaxFailedLogins), nil)
+	mockRepository.On("ResetFailedLogins", uint(1)).Return(nil)
+	mockRepository.On("CreateSession", mock.Anything).Return(nil)
+
+	_, _, err := loginService.Login("uala", "password123")
+	assert.Equal(t, err, nil)
+}
+
+func TestLoginErrorCountingAttempt(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	loginService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	mockRepository.On("GetUserByHandle", "uala").Return(newTestUser(t, 1, "uala", "password123"), true, nil)
+	mockRepository.On("AddFailedLogin", uint(1)).Return(int64(0), errors.New("Error"))
+
+	_, _, err := loginService.Login("uala", "password123")
+	assert.Equal(t, err, errors.New("Error"))
+}
+
+func TestRegisterHandleTaken(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	registerService := service.TwitterService{
+		Repository: mockRepository,
+		Clock:      utils.Clock{},
+	}
+
+	mockRepository.On("CreateUser", mock.Anything).Return(uint(0), repository.ErrHandleTaken)
+
+	_, err := registerService.Register("uala", "password123", "", "")
+	assert.Equal(t, err, service.ErrHandleTaken)
+}
+
+func TestRegisterInvalidHandle(t *testing.T) {
+	registerService := service.TwitterService{
+		Clock: utils.Clock{},
+	}
+
+	_, err := registerService.Register("not a handle", "password123", "", "")
+	assert.Equal(t, err, models.ErrInvalidHandle)
+}
+
+func TestChangePasswordRevokesOtherSessions(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	accountService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	sessions := []models.Session{{ID: "current", UserID: 1}, {ID: "other", UserID: 1}}
+
+	mockRepository.On("GetUser", uint(1)).Return(newTestUser(t, 1, "uala", "password123"), true, nil)
+	mockRepository.On("UpdatePassword", uint(1), mock.Anything).Return(nil)
+	mockRepository.On("GetSessions", uint(1)).Return(sessions, nil)
+	mockRepository.On("DeleteSessions", uint(1), []string{"other"}).Return(nil)
+
+	err := accountService.ChangePassword(1, "current", "password123", "new-password")
+	assert.Equal(t, err, nil)
+}
+
+func TestChangePasswordWrongCurrentPassword(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	accountService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	mockRepository.On("GetUser", uint(1)).Return(newTestUser(t, 1, "uala", "password123"), true, nil)
+
+	err := accountService.ChangePassword(1, "current", "wrong-password", "new-password")
+	assert.Equal(t, err, service.ErrInvalidCredentials)
+}
+
+func TestRequestPasswordResetStoresHashedToken(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	accountService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	var stored string
+
+	mockRepository.On("GetUserByHandle", "uala").Return(newTestUser(t, 1, "uala", "password123"), true, nil)
+	mockRepository.On("CreatePasswordReset", mock.Anything, uint(1)).Run(func(args mock.Arguments) {
+		stored = args.String(0)
+	}).Return(nil)
+
+	token, err := accountService.RequestPasswordReset("uala")
+	assert.Equal(t, err, nil)
+	assert.NotEqual(t, token, "")
+	assert.Equal(t, stored, service.SessionID(token))
+}
+
+func TestResetPasswordInvalidToken(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	accountService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	mockRepository.On("ConsumePasswordReset", service.SessionID("token")).Return(uint(0), false, nil)
+
+	err := accountService.ResetPassword("token", "new-password")
+	assert.Equal(t, err, service.ErrInvalidPasswordReset)
+}
+
+func TestResetPasswordSuccess(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	accountService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	sessions := []models.Session{{ID: "session", UserID: 1}}
+
+	mockRepository.On("ConsumePasswordReset", service.SessionID("token")).Return(uint(1), true, nil)
+	mockRepository.On("GetUser", uint(1)).Return(newTestUser(t, 1, "uala", "password123"), true, nil)
+	mockRepository.On("UpdatePassword", uint(1), mock.Anything).Return(nil)
+	mockRepository.On("ResetFailedLogins", uint(1)).Return(nil)
+	mockRepository.On("GetSessions", uint(1)).Return(sessions, nil)
+	mockRepository.On("DeleteSessions", uint(1), []string{"session"}).Return(nil)
+
+	err := accountService.ResetPassword("token", "new-password")
+	assert.Equal(t, err, nil)
+}
+
+func TestAuthenticateInvalidSession(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	authService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	mockRepository.On("GetSession", service.SessionID("token")).Return(models.Session{}, false, nil)
+
+	_, err := authService.Authenticate("token")
+	assert.Equal(t, err, service.ErrInvalidSession)
+}
+
+func TestAuthenticateRefreshesSession(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	mockClock := utilsMocks.NewIClock(t)
+
+	authService := service.TwitterService{
+		Repository: mockRepository,
+		Clock:      mockClock,
+	}
+
+	createdAt := time.Now().Add(-time.Hour)
+	now := time.Now()
+	stored := models.Session{ID: service.SessionID("token"), UserID: 3, CreatedAt: createdAt, LastUsedAt: createdAt}
+	refreshed := models.Session{ID: service.SessionID("token"), UserID: 3, CreatedAt: createdAt, LastUsedAt: now}
+
+	mockClock.On("Now").Return(now)
+	mockRepository.On("GetSession", service.SessionID("token")).Return(stored, true, nil)
+	mockRepository.On("RefreshSession", refreshed).Return(nil)
+
+	session, err := authService.Authenticate("token")
+	assert.Equal(t, session, refreshed)
+	assert.Equal(t, err, nil)
+}
+
+func TestRevokeSessionOfAnotherUser(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	sessionService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	mockRepository.On("GetSession", "session").Return(models.Session{ID: "session", UserID: 2}, true, nil)
+
+	err := sessionService.RevokeSession(1, "session")
+	assert.Equal(t, err, service.ErrSessionNotFound)
+}
+
+func TestRevokeSessionSuccess(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	sessionService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	mockRepository.On("GetSession", "session").Return(models.Session{ID: "session", UserID: 1}, true, nil)
+	mockRepository.On("DeleteSessions", uint(1), []string{"session"}).Return(nil)
+
+	err := sessionService.RevokeSession(1, "session")
+	assert.Equal(t, err, nil)
+}
+
+func TestRevokeSessions(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	sessionService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	sessions := []models.Session{{ID: "first", UserID: 1}, {ID: "second", UserID: 1}}
+
+	mockRepository.On("GetSessions", uint(1)).Return(sessions, nil)
+	mockRepository.On("DeleteSessions", uint(1), []string{"first", "second"}).Return(nil)
+
+	err := sessionService.RevokeSessions(1)
+	assert.Equal(t, err, nil)
+}
+
+func TestDeleteTweet(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	tweetID := uuid.New()
+
+	mockRepository.On("GetTweets", []uuid.UUID{tweetID}).Return([]models.Tweet{{ID: tweetID, UserID: 1}}, nil, nil)
+	mockRepository.On("GetRetweeters", []uuid.UUID{tweetID}).Return([][]uint{nil}, nil)
+	mockRepository.On("GetFollowers", uint(1)).Return([]uint{2, 3}, nil)
+	mockRepository.On("DeleteTweet", models.Tweet{ID: tweetID, UserID: 1}, []uint{2, 3}).Return(true, nil)
+
+	err := tweetService.DeleteTweet(1, tweetID)
+	assert.Equal(t, err, nil)
+}
+
+func TestDeleteRetweetedTweet(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	tweetID := uuid.New()
+
+	mockRepository.On("GetTweets", []uuid.UUID{tweetID}).Return([]models.Tweet{{ID: tweetID, UserID: 1}}, nil, nil)
+	mockRepository.On("GetRetweeters", []uuid.UUID{tweetID}).Return([][]uint{{4}}, nil)
+	mockRepository.On("GetFollowers", uint(1)).Return([]uint{2, 3}, nil)
+	mockRepository.On("GetFollowers", uint(4)).Return([]uint{3, 5}, nil)
+	//the followers of the retweeter got it too
+	mockRepository.On("DeleteTweet", models.Tweet{ID: tweetID, UserID: 1}, []uint{2, 3, 5}).Return(true, nil)
+
+	err := tweetService.DeleteTweet(1, tweetID)
+	assert.Equal(t, err, nil)
+}
+
+func TestDeleteTweetErrorNotFound(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	tweetID := uuid.New()
+
+	mockRepository.On("GetTweets", []uuid.UUID{tweetID}).Return([]models.Tweet{}, []uuid.UUID{tweetID}, nil)
+
+	err := tweetService.DeleteTweet(1, tweetID)
+	assert.Equal(t, err, service.ErrTweetNotFound)
+}
+
+func TestDeleteTweetErrorOfOtherUser(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	tweetID := uuid.New()
+
+	mockRepository.On("GetTweets", []uuid.UUID{tweetID}).Return([]models.Tweet{{ID: tweetID, UserID: 2}}, nil, nil)
+
+	err := tweetService.DeleteTweet(1, tweetID)
+	assert.Equal(t, err, service.ErrTweetNotFound)
+}
+
+func TestDeleteTweetErrorDeleting(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	tweetID := uuid.New()
+
+	mockRepository.On("GetTweets", []uuid.UUID{tweetID}).Return([]models.Tweet{{ID: tweetID, UserID: 1}}, nil, nil)
+	mockRepository.On("GetRetweeters", []uuid.UUID{tweetID}).Return([][]uint{nil}, nil)
+	mockRepository.On("GetFollowers", uint(1)).Return([]uint{2}, nil)
+	mockRepository.On("DeleteTweet", models.Tweet{ID: tweetID, UserID: 1}, []uint{2}).Return(false, errors.New("Error"))
+
+	err := tweetService.DeleteTweet(1, tweetID)
+	assert.Equal(t, errors.Is(err, service.ErrDeletingTweet), true)
+}
+
+func TestEditTweet(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	mockClock := utilsMocks.NewIClock(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+		Clock:      mockClock,
+	}
+
+	now := time.Now()
+	tweet := models.Tweet{ID: uuid.New(), UserID: 1, Timestamp: now.Add(-time.Minute), Body: "frist"}
+
+	mockClock.On("Now").Return(now)
+	mockRepository.On("GetTweets", []uuid.UUID{tweet.ID}).Return([]models.Tweet{tweet}, nil, nil)
+	mockRepository.On("EditTweet", models.Tweet{
+		ID:        tweet.ID,
+		UserID:    1,
+		Timestamp: tweet.Timestamp,
+		Body:      "first #Go",
+		Hashtags:  []string{"go"},
+		EditedAt:  &now,
+	}).Return(true, nil)
+
+	edited, err := tweetService.EditTweet(1, tweet.ID, "first #Go")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, edited.Body, "first #Go")
+	assert.Equal(t, edited.Hashtags, []string{"go"})
+	assert.Equal(t, *edited.EditedAt, now)
+}
+
+func TestEditTweetNotifiesNewMentions(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	mockClock := utilsMocks.NewIClock(t)
+	mockNotifier := serviceMocks.NewNotifier(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+		Clock:      mockClock,
+		Notifier:   mockNotifier,
+	}
+
+	now := time.Now()
+	tweet := models.Tweet{ID: uuid.New(), UserID: 1, Timestamp: now.Add(-time.Minute), Body: "hi @ana", Mentions: []uint{2}}
+
+	mockClock.On("Now").Return(now)
+	mockRepository.On("GetTweets", []uuid.UUID{tweet.ID}).Return([]models.Tweet{tweet}, nil, nil)
+	mockRepository.On("GetUserByHandle", "ana").Return(models.User{ID: 2, Handle: "ana"}, true, nil)
+	mockRepository.On("GetUserByHandle", "beto").Return(models.User{ID: 3, Handle: "beto"}, true, nil)
+	mockRepository.On("EditTweet", models.Tweet{
+		ID:        tweet.ID,
+		UserID:    1,
+		Timestamp: tweet.Timestamp,
+		Body:      "hi @ana @beto",
+		Mentions:  []uint{2, 3},
+		EditedAt:  &now,
+	}).Return(true, nil)
+	//only the new mention is notified
+	mockNotifier.On("Notify", models.Notification{
+		Type:      models.NotificationMention,
+		UserID:    3,
+		ActorID:   1,
+		TweetID:   tweet.ID,
+		Timestamp: now,
+	}).Return(nil).Once()
+
+	edited, err := tweetService.EditTweet(1, tweet.ID, "hi @ana @beto")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, edited.Mentions, []uint{2, 3})
+}
+
+func TestEditTweetErrorEditWindowExpired(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	mockClock := utilsMocks.NewIClock(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+		Clock:      mockClock,
+		EditWindow: time.Hour,
+	}
+
+	now := time.Now()
+	tweet := models.Tweet{ID: uuid.New(), UserID: 1, Timestamp: now.Add(-2 * time.Hour), Body: "frist"}
+
+	mockClock.On("Now").Return(now)
+	mockRepository.On("GetTweets", []uuid.UUID{tweet.ID}).Return([]models.Tweet{tweet}, nil, nil)
+
+	_, err := tweetService.EditTweet(1, tweet.ID, "first")
+	assert.Equal(t, err, service.ErrEditWindowExpired)
+}
+
+func TestEditTweetErrorMaxLengthExceeded(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	mockClock := utilsMocks.NewIClock(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+		Clock:      mockClock,
+	}
+
+	now := time.Now()
+	tweet := models.Tweet{ID: uuid.New(), UserID: 1, Timestamp: now, Body: "frist"}
+
+	mockClock.On("Now").Return(now)
+	mockRepository.On("GetTweets", []uuid.UUID{tweet.ID}).Return([]models.Tweet{tweet}, nil, nil)
+
+	_, err := tweetService.EditTweet(1, tweet.ID, strings.Repeat("uala_challenge", 15))
+	assert.Equal(t, errors.Is(err, models.ErrMaxLengthExceeded), true)
+}
+
+func TestEditTweetErrorOfOtherUser(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	tweet := models.Tweet{ID: uuid.New(), UserID: 2, Timestamp: time.Now(), Body: "frist"}
+
+	mockRepository.On("GetTweets", []uuid.UUID{tweet.ID}).Return([]models.Tweet{tweet}, nil, nil)
+
+	_, err := tweetService.EditTweet(1, tweet.ID, "first")
+	assert.Equal(t, err, service.ErrTweetNotFound)
+}
+
+func TestGetTweetHistory(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	createdAt := time.Now().Add(-time.Minute)
+	editedAt := time.Now()
+	tweet := models.Tweet{ID: uuid.New(), UserID: 1, Timestamp: createdAt, Body: "first", EditedAt: &editedAt}
+	previous := models.TweetRevision{Body: "frist", Timestamp: createdAt}
+
+	mockRepository.On("GetTweets", []uuid.UUID{tweet.ID}).Return([]models.Tweet{tweet}, nil, nil)
+	mockRepository.On("GetTweetHistory", tweet.ID).Return([]models.TweetRevision{previous}, nil)
+
+	history, err := tweetService.GetTweetHistory(1, tweet.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, history, []models.TweetRevision{previous, {Body: "first", Timestamp: editedAt}})
+}
+
+func TestReply(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
+	mockClock := utilsMocks.NewIClock(t)
+	mockNotifier := serviceMocks.NewNotifier(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+		Clock:      mockClock,
+		Notifier:   mockNotifier,
+	}
+
+	now := time.Now()
+	parent := models.Tweet{ID: uuid.New(), UserID: 2, ConversationID: uuid.New()}
+	replyID := uuid.New()
+
+	mockClock.On("Now").Return(now)
+	mockRepository.On("GetTweets", []uuid.UUID{parent.ID}).Return([]models.Tweet{parent}, nil, nil)
+	mockRepository.On("CreateTweet", models.Tweet{
+		UserID:         1,
+		Timestamp:      now,
+		Body:           "reply",
+		InReplyTo:      &parent.ID,
+		ConversationID: parent.ConversationID,
+	}).Return(replyID, nil)
+	mockNotifier.On("Notify", models.Notification{
+		Type:      models.NotificationReply,
+		UserID:    2,
+		ActorID:   1,
+		TweetID:   replyID,
+		Timestamp: now,
+	}).Return(errors.New("Error"))
+	mockRepository.On("GetFollowers", uint(1)).Return([]uint{3}, nil)
+	mockRepository.On("AddTweetToTimelines", replyID, []uint{3}).Return(nil)
+
+	id, err := tweetService.Reply(1, parent.ID, "reply")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, id, replyID)
+}
+
+func TestReplyToOwnTweetIsNotNotified(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
+	mockClock := utilsMocks.NewIClock(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+		Clock:      mockClock,
+		Notifier:   serviceMocks.NewNotifier(t),
+	}
+
+	parent := models.Tweet{ID: uuid.New(), UserID: 1}
+	replyID := uuid.New()
+
+	mockClock.On("Now").Return(time.Now())
+	mockRepository.On("GetTweets", []uuid.UUID{parent.ID}).Return([]models.Tweet{parent}, nil, nil)
+	mockRepository.On("CreateTweet", mock.Anything).Return(replyID, nil)
+	mockRepository.On("GetFollowers", uint(1)).Return([]uint{}, nil)
+	mockRepository.On("AddTweetToTimelines", replyID, []uint{}).Return(nil)
+
+	_, err := tweetService.Reply(1, parent.ID, "reply")
+	assert.Equal(t, err, nil)
+}
+
+func TestReplyErrorNotFound(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	tweetID := uuid.New()
+
+	mockRepository.On("GetTweets", []uuid.UUID{tweetID}).Return([]models.Tweet{}, []uuid.UUID{tweetID}, nil)
+
+	_, err := tweetService.Reply(1, tweetID, "reply")
+	assert.Equal(t, err, service.ErrTweetNotFound)
+}
+
+func TestGetThread(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	now := time.Now()
+	root := models.Tweet{ID: uuid.New(), Timestamp: now}
+	reply := models.Tweet{ID: uuid.New(), Timestamp: now.Add(time.Second), InReplyTo: &root.ID, ConversationID: root.ID}
+	nested := models.Tweet{ID: uuid.New(), Timestamp: now.Add(2 * time.Second), InReplyTo: &reply.ID, ConversationID: root.ID}
+	second := models.Tweet{ID: uuid.New(), Timestamp: now.Add(3 * time.Second), InReplyTo: &root.ID, ConversationID: root.ID}
+	deleted := uuid.New()
+	orphan := models.Tweet{ID: uuid.New(), Timestamp: now.Add(4 * time.Second), InReplyTo: &deleted, ConversationID: root.ID}
+
+	ids := []uuid.UUID{root.ID, reply.ID, nested.ID, second.ID, orphan.ID}
+
+	mockRepository.On("GetTweets", []uuid.UUID{nested.ID}).Return([]models.Tweet{nested}, nil, nil)
+	mockRepository.On("GetConversation", root.ID).Return(ids[1:], nil)
+	mockRepository.On("GetTweets", ids).Return([]models.Tweet{root, second, reply, nested, orphan}, nil, nil)
+
+	thread, err := tweetService.GetThread(nested.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, thread, []models.Thread{
+		{Tweet: root, Replies: []models.Thread{
+			{Tweet: reply, Replies: []models.Thread{{Tweet: nested, Replies: []models.Thread{}}}},
+			{Tweet: second, Replies: []models.Thread{}},
+		}},
+		{Tweet: orphan, Replies: []models.Thread{}},
+	})
+}
+
+// withUsers makes every user exist in mockRepository
+func withUsers(mockRepository *repositoryMocks.IRepository) {
+	mockRepository.On("GetUser", mock.Anything).Return(func(userID uint) (models.User, bool, error) {
+		return models.User{ID: userID}, true, nil
+	})
+}
+
+// withoutAnnotations makes mockRepository return no retweeters nor likes for any tweet
+func withoutAnnotations(mockRepository *repositoryMocks.IRepository) {
+	mockRepository.On("GetRetweeters", mock.Anything).Return(func(tweetIDs []uuid.UUID) ([][]uint, error) {
+		return make([][]uint, len(tweetIDs)), nil
+	})
+	mockRepository.On("GetLikedBy", mock.Anything, mock.Anything).Return(func(userID uint, tweetIDs []uuid.UUID) ([]bool, error) {
+		return make([]bool, len(tweetIDs)), nil
+	})
+}
+
+func TestRetweet(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	mockClock := utilsMocks.NewIClock(t)
+	mockNotifier := serviceMocks.NewNotifier(t)
+
+	tweetService := service.TwitterService{
+		Repository:      mockRepository,
+		Clock:           mockClock,
+		Notifier:        mockNotifier,
+		FanOutThreshold: 1,
+	}
+
+	now := time.Now()
+	tweet := models.Tweet{ID: uuid.New(), UserID: 2}
+
+	mockClock.On("Now").Return(now)
+	mockRepository.On("GetTweets", []uuid.UUID{tweet.ID}).Return([]models.Tweet{tweet}, nil, nil)
+	mockRepository.On("AddRetweet", uint(1), tweet.ID).Return(true, nil)
+	mockNotifier.On("Notify", models.Notification{
+		Type:      models.NotificationRetweet,
+		UserID:    2,
+		ActorID:   1,
+		TweetID:   tweet.ID,
+		Timestamp: now,
+	}).Return(nil)
+	//pushed even above FanOutThreshold
+	mockRepository.On("GetFollowers", uint(1)).Return([]uint{3, 4}, nil)
+	mockRepository.On("AddTweetToTimelines", tweet.ID, []uint{3, 4}).Return(nil)
+
+	err := tweetService.Retweet(1, tweet.ID)
+	assert.Equal(t, err, nil)
+}
+
+func TestRetweetErrorAlreadyRetweeted(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	tweet := models.Tweet{ID: uuid.New(), UserID: 2}
+
+	mockRepository.On("GetTweets", []uuid.UUID{tweet.ID}).Return([]models.Tweet{tweet}, nil, nil)
+	mockRepository.On("AddRetweet", uint(1), tweet.ID).Return(false, nil)
+
+	err := tweetService.Retweet(1, tweet.ID)
+	assert.Equal(t, err, service.ErrAlreadyRetweeted)
+}
+
+func TestUnretweetKeepsTweetForOtherFollowers(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	tweet := models.Tweet{ID: uuid.New(), UserID: 2}
+
+	mockRepository.On("GetTweets", []uuid.UUID{tweet.ID}).Return([]models.Tweet{tweet}, nil, nil)
+	mockRepository.On("RemoveRetweet", uint(1), tweet.ID).Return(true, nil)
+	mockRepository.On("GetFollowers", uint(1)).Return([]uint{2, 3, 4, 5}, nil)
+	mockRepository.On("GetRetweeters", []uuid.UUID{tweet.ID}).Return([][]uint{{6}}, nil)
+	//3 follows the author and 4 another retweeter
+	mockRepository.On("GetFollowers", uint(2)).Return([]uint{3}, nil)
+	mockRepository.On("GetFollowers", uint(6)).Return([]uint{4}, nil)
+	mockRepository.On("RemoveTweetFromTimelines", tweet.ID, []uint{5}).Return(nil)
+
+	err := tweetService.Unretweet(1, tweet.ID)
+	assert.Equal(t, err, nil)
+}
+
+func TestUnretweetErrorNotRetweeted(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	tweet := models.Tweet{ID: uuid.New(), UserID: 2}
+
+	mockRepository.On("GetTweets", []uuid.UUID{tweet.ID}).Return([]models.Tweet{tweet}, nil, nil)
+	mockRepository.On("RemoveRetweet", uint(1), tweet.ID).Return(false, nil)
+
+	err := tweetService.Unretweet(1, tweet.ID)
+	assert.Equal(t, err, service.ErrNotRetweeted)
+}
+
+func TestGetTimelineAnnotatesRetweetsAndLikes(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
+
+	timelineService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	now := time.Now()
+	retweeted := models.Tweet{ID: uuid.New(), UserID: 5, Timestamp: now.Add(-time.Hour)}
+	tweet := models.Tweet{ID: uuid.New(), UserID: 2, Timestamp: now}
+
+	mockRepository.On("GetTimeLine", uint(1)).Return([]uuid.UUID{retweeted.ID, tweet.ID}, nil)
+	mockRepository.On("GetTweets", []uuid.UUID{retweeted.ID, tweet.ID}).Return([]models.Tweet{retweeted, tweet}, nil, nil)
+	mockRepository.On("GetRetweeters", []uuid.UUID{retweeted.ID, tweet.ID}).Return([][]uint{{2, 3, 4}, {}}, nil)
+	mockRepository.On("GetFollowing", uint(1)).Return([]uint{2, 4}, nil)
+	mockRepository.On("GetLikedBy", uint(1), []uuid.UUID{retweeted.ID, tweet.ID}).Return([]bool{false, true}, nil)
+
+	page, err := timelineService.GetTimeLine(1, "", 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, page.Tweets[0].RetweetedBy, []uint{2, 4})
+	assert.Equal(t, page.Tweets[1].RetweetedBy == nil, true)
+	assert.Equal(t, page.Tweets[0].LikedByMe, false)
+	assert.Equal(t, page.Tweets[1].LikedByMe, true)
+}
+
+func TestTweetMentions(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	mockClock := utilsMocks.NewIClock(t)
+	mockNotifier := serviceMocks.NewNotifier(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+		Clock:      mockClock,
+		Notifier:   mockNotifier,
+	}
+
+	now := time.Now()
+	tweetID := uuid.New()
+	content := "@beto @7 and @Ana, not me@ana nor @nobody or @1"
+
+	mockClock.On("Now").Return(now)
+	mockRepository.On("GetUserByHandle", "beto").Return(models.User{ID: 3, Handle: "beto"}, true, nil)
+	mockRepository.On("GetUserByHandle", "7").Return(models.User{}, false, nil)
+	mockRepository.On("GetUser", uint(7)).Return(models.User{ID: 7, Handle: "siete"}, true, nil)
+	mockRepository.On("GetUserByHandle", "Ana").Return(models.User{ID: 2, Handle: "ana"}, true, nil)
+	mockRepository.On("GetUserByHandle", "nobody").Return(models.User{}, false, nil)
+	mockRepository.On("GetUserByHandle", "1").Return(models.User{}, false, nil)
+	mockRepository.On("GetUser", uint(1)).Return(models.User{ID: 1, Handle: "uala"}, true, nil)
+	mockRepository.On("CreateTweet", models.Tweet{
+		UserID:    1,
+		Timestamp: now,
+		Body:      content,
+		Mentions:  []uint{1, 2, 3, 7},
+	}).Return(tweetID, nil)
+	//the author isn't notified of its own mention
+	for _, userID := range []uint{2, 3, 7} {
+		mockNotifier.On("Notify", models.Notification{
+			Type:      models.NotificationMention,
+			UserID:    userID,
+			ActorID:   1,
+			TweetID:   tweetID,
+			Timestamp: now,
+		}).Return(nil).Once()
+	}
+	mockRepository.On("GetFollowers", uint(1)).Return([]uint{}, nil)
+	mockRepository.On("AddTweetToTimelines", tweetID, []uint{}).Return(nil)
+
+	id, err := tweetService.Tweet(1, content)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, id, tweetID)
+}
+
+func TestInboxNotifier(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	notifier := service.InboxNotifier{Repository: mockRepository}
+	notification := models.Notification{Type: models.NotificationLike, UserID: 2, ActorID: 1, TweetID: uuid.New()}
+
+	mockRepository.On("AddNotification", mock.MatchedBy(func(added models.Notification) bool {
+		id := added.ID
+		added.ID = uuid.Nil
+
+		return id != uuid.Nil && added == notification
+	})).Return(nil)
+
+	err := notifier.Notify(notification)
+	assert.Equal(t, err, nil)
+}
+
+func TestGetNotifications(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	now := time.Now()
+	notifications := make([]models.Notification, 0, 3)
+	for i := range 3 {
+		notifications = append(notifications, models.Notification{ID: uuid.New(), UserID: 1, Timestamp: now.Add(-time.Duration(i) * time.Minute)})
+	}
+
+	mockRepository.On("GetNotifications", uint(1)).Return(func(uint) ([]models.Notification, error) {
+		return slices.Clone(notifications), nil
+	})
+	mockRepository.On("GetNotificationsReadAt", uint(1)).Return(notifications[1].Timestamp, nil)
+
+	page, err := tweetService.GetNotifications(1, "", 2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, page.UnreadCount, int64(1))
+	assert.Equal(t, len(page.Notifications), 2)
+	assert.Equal(t, page.Notifications[0].ID, notifications[0].ID)
+	assert.Equal(t, page.Notifications[0].Read, false)
+	assert.Equal(t, page.Notifications[1].Read, true)
+	assert.Equal(t, page.NextCursor, notifications[1].ID.String())
+
+	page, err = tweetService.GetNotifications(1, page.NextCursor, 2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(page.Notifications), 1)
+	assert.Equal(t, page.Notifications[0].ID, notifications[2].ID)
+	assert.Equal(t, page.NextCursor, "")
+
+	_, err = tweetService.GetNotifications(1, "nope", 2)
+	assert.Equal(t, err, service.ErrInvalidCursor)
+}
+
+func TestMarkNotificationsRead(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	now := time.Now()
+	newest := models.Notification{ID: uuid.New(), UserID: 1, Timestamp: now}
+	oldest := models.Notification{ID: uuid.New(), UserID: 1, Timestamp: now.Add(-time.Minute)}
+
+	mockRepository.On("GetNotifications", uint(1)).Return(func(uint) ([]models.Notification, error) {
+		return []models.Notification{newest, oldest}, nil
+	})
+	mockRepository.On("GetNotificationsReadAt", uint(1)).Return(oldest.Timestamp, nil)
+	mockRepository.On("SetNotificationsReadAt", uint(1), newest.Timestamp).Return(nil).Once()
+
+	err := tweetService.MarkNotificationsRead(1, newest.ID)
+	assert.Equal(t, err, nil)
+
+	//already read
+	err = tweetService.MarkNotificationsRead(1, oldest.ID)
+	assert.Equal(t, err, nil)
+
+	err = tweetService.MarkNotificationsRead(1, uuid.New())
+	assert.Equal(t, err, service.ErrNotificationNotFound)
+}
+
+func TestLike(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	mockClock := utilsMocks.NewIClock(t)
+	mockNotifier := serviceMocks.NewNotifier(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+		Clock:      mockClock,
+		Notifier:   mockNotifier,
+	}
+
+	now := time.Now()
+	tweet := models.Tweet{ID: uuid.New(), UserID: 2}
+
+	mockClock.On("Now").Return(now)
+	mockRepository.On("GetTweets", []uuid.UUID{tweet.ID}).Return([]models.Tweet{tweet}, nil, nil)
+	mockRepository.On("AddLike", uint(1), tweet.ID).Return(true, nil)
+	mockNotifier.On("Notify", models.Notification{
+		Type:      models.NotificationLike,
+		UserID:    2,
+		ActorID:   1,
+		TweetID:   tweet.ID,
+		Timestamp: now,
+	}).Return(nil)
+
+	err := tweetService.Like(1, tweet.ID)
+	assert.Equal(t, err, nil)
+}
+
+func TestLikeErrorAlreadyLiked(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	tweet := models.Tweet{ID: uuid.New(), UserID: 2}
+
+	mockRepository.On("GetTweets", []uuid.UUID{tweet.ID}).Return([]models.Tweet{tweet}, nil, nil)
+	mockRepository.On("AddLike", uint(1), tweet.ID).Return(false, nil)
+
+	err := tweetService.Like(1, tweet.ID)
+	assert.Equal(t, err, service.ErrAlreadyLiked)
+}
+
+func TestLikeErrorTweetNotFound(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	tweetID := uuid.New()
+
+	mockRepository.On("GetTweets", []uuid.UUID{tweetID}).Return(nil, []uuid.UUID{tweetID}, nil)
+
+	err := tweetService.Like(1, tweetID)
+	assert.Equal(t, err, service.ErrTweetNotFound)
+}
+
+func TestUnlikeErrorNotLiked(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	tweet := models.Tweet{ID: uuid.New(), UserID: 2}
+
+	mockRepository.On("GetTweets", []uuid.UUID{tweet.ID}).Return([]models.Tweet{tweet}, nil, nil)
+	mockRepository.On("RemoveLike", uint(1), tweet.ID).Return(false, nil)
+
+	err := tweetService.Unlike(1, tweet.ID)
+	assert.Equal(t, err, service.ErrNotLiked)
+}
+
+func TestGetLikes(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	ids, tweets := newTimeline(3)
+
+	//liked in a different order than tweeted
+	liked := []uuid.UUID{ids[2], ids[0], ids[1]}
+
+	mockRepository.On("GetUserLikes", uint(2)).Return(liked, nil)
+	mockRepository.On("GetTweets", liked[:3]).Return([]models.Tweet{tweets[2], tweets[0], tweets[1]}, nil, nil)
+	mockRepository.On("GetRetweeters", liked[:2]).Return([][]uint{{}, {}}, nil)
+	mockRepository.On("GetLikedBy", uint(1), liked[:2]).Return([]bool{true, false}, nil)
+
+	page, err := tweetService.GetLikes(1, 2, "", 2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(page.Tweets), 2)
+	assert.Equal(t, page.Tweets[0].ID, ids[2])
+	assert.Equal(t, page.Tweets[0].LikedByMe, true)
+	assert.Equal(t, page.Tweets[1].ID, ids[0])
+	assert.Equal(t, page.Tweets[1].LikedByMe, false)
+
+	mockRepository.On("GetTweets", liked[2:]).Return([]models.Tweet{tweets[1]}, nil, nil)
+	mockRepository.On("GetRetweeters", liked[2:]).Return([][]uint{{}}, nil)
+	mockRepository.On("GetLikedBy", uint(1), liked[2:]).Return([]bool{false}, nil)
+
+	page, err = tweetService.GetLikes(1, 2, page.NextCursor, 2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(page.Tweets), 1)
+	assert.Equal(t, page.Tweets[0].ID, ids[1])
+	assert.Equal(t, page.NextCursor, "")
+}
+
+func TestQuote(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	withUsers(mockRepository)
+	mockClock := utilsMocks.NewIClock(t)
+	mockNotifier := serviceMocks.NewNotifier(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+		Clock:      mockClock,
+		Notifier:   mockNotifier,
+	}
+
+	now := time.Now()
+	quoted := models.Tweet{ID: uuid.New(), UserID: 2}
+	quoteID := uuid.New()
+
+	mockClock.On("Now").Return(now)
+	mockRepository.On("GetTweets", []uuid.UUID{quoted.ID}).Return([]models.Tweet{quoted}, nil, nil)
+	mockRepository.On("CreateTweet", models.Tweet{
+		UserID:        1,
+		Timestamp:     now,
+		Body:          "look",
+		QuotedTweetID: &quoted.ID,
+	}).Return(quoteID, nil)
+	mockNotifier.On("Notify", models.Notification{
+		Type:      models.NotificationQuote,
+		UserID:    2,
+		ActorID:   1,
+		TweetID:   quoteID,
+		Timestamp: now,
+	}).Return(nil)
+	mockRepository.On("GetFollowers", uint(1)).Return([]uint{3}, nil)
+	mockRepository.On("AddTweetToTimelines", quoteID, []uint{3}).Return(nil)
+
+	id, err := tweetService.Quote(1, quoted.ID, "look")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, id, quoteID)
+}
+
+func TestQuoteErrorMaxLengthExceeded(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	mockClock := utilsMocks.NewIClock(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+		Clock:      mockClock,
+	}
+
+	quoted := models.Tweet{ID: uuid.New(), UserID: 2}
+
+	mockClock.On("Now").Return(time.Now())
+	mockRepository.On("GetTweets", []uuid.UUID{quoted.ID}).Return([]models.Tweet{quoted}, nil, nil)
+
+	_, err := tweetService.Quote(1, quoted.ID, strings.Repeat("uala_challenge", 15))
+	assert.Equal(t, errors.Is(err, models.ErrMaxLengthExceeded), true)
+}
+
+func TestGetHashtagTweets(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	ids, tweets := newTimeline(2)
+
+	mockRepository.On("GetHashtagTweets", "uala").Return(ids, nil)
+	mockRepository.On("GetTweets", ids).Return(tweets, nil, nil)
+	withoutAnnotations(mockRepository)
+
+	page, err := tweetService.GetHashtagTweets(1, "#Uala", "", 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(page.Tweets), 2)
+	assert.Equal(t, page.Tweets[0].ID, ids[0])
+	assert.Equal(t, page.NextCursor, "")
+}
+
+func TestGetHashtagTweetsErrorInvalidHashtag(t *testing.T) {
+	tweetService := service.TwitterService{
+		Repository: repositoryMocks.NewIRepository(t),
+	}
+
+	for _, hashtag := range []string{"", "#", "uala challenge", "2024"} {
+		_, err := tweetService.GetHashtagTweets(1, hashtag, "", 0)
+		assert.Equal(t, errors.Is(err, models.ErrInvalidHashtag), true)
+	}
+}
+
+func TestGetTrends(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	mockClock := utilsMocks.NewIClock(t)
+
+	tweetService := service.TwitterService{
+		Repository:     mockRepository,
+		Clock:          mockClock,
+		TrendsWindow:   24 * time.Hour,
+		TrendsHalfLife: time.Hour,
+	}
+
+	now := time.Now()
+
+	mockClock.On("Now").Return(now)
+	mockRepository.On("GetHashtagUses", now.Add(-24*time.Hour)).Return(map[string][]time.Time{
+		//an old spike counts less than a few recent tweets
+		"spike":  {now.Add(-10 * time.Hour), now.Add(-10 * time.Hour), now.Add(-10 * time.Hour), now.Add(-10 * time.Hour)},
+		"recent": {now, now.Add(-time.Hour)},
+		"once":   {now},
+		"tied":   {now},
+	}, nil)
+
+	trends, err := tweetService.GetTrends(3)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, trends, []models.Trend{
+		{Hashtag: "recent", Score: 1.5, TweetCount: 2},
+		{Hashtag: "once", Score: 1, TweetCount: 1},
+		{Hashtag: "tied", Score: 1, TweetCount: 1},
+	})
+}
+
+func TestGetTrendsNegativeSettingsUseDefaults(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+	mockClock := utilsMocks.NewIClock(t)
+
+	tweetService := service.TwitterService{
+		Repository:     mockRepository,
+		Clock:          mockClock,
+		TrendsWindow:   -time.Hour,
+		TrendsHalfLife: -time.Hour,
+	}
+
+	now := time.Now()
+
+	mockClock.On("Now").Return(now)
+	mockRepository.On("GetHashtagUses", now.Add(-service.DefaultTrendsWindow)).Return(map[string][]time.Time{
+		"uala": {now.Add(-service.DefaultTrendsHalfLife)},
+	}, nil)
+
+	trends, err := tweetService.GetTrends(0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, trends, []models.Trend{{Hashtag: "uala", Score: 0.5, TweetCount: 1}})
+}
+
+func TestGetTrendsErrorInvalidLimit(t *testing.T) {
+	tweetService := service.TwitterService{}
+
+	_, err := tweetService.GetTrends(-1)
+	assert.Equal(t, errors.Is(err, service.ErrInvalidLimit), true)
+}
+
+func TestSearch(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	ids, tweets := newTimeline(2)
+	since := time.Now().Add(-time.Hour)
+
+	mockRepository.On("GetUserByHandle", "ana").Return(models.User{ID: 2, Handle: "ana"}, true, nil)
+	mockRepository.On("SearchTweets", []string{"hola", "mundo"}, uint(2), since).Return(ids, nil)
+	mockRepository.On("GetTweets", ids).Return(tweets, nil, nil)
+	withoutAnnotations(mockRepository)
+
+	page, err := tweetService.Search(1, "Hola, #mundo!", "@ana", since, "", 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(page.Tweets), 2)
+	assert.Equal(t, page.Tweets[0].ID, ids[0])
+}
+
+func TestSearchErrorInvalidSearch(t *testing.T) {
+	tweetService := service.TwitterService{
+		Repository: repositoryMocks.NewIRepository(t),
+	}
+
+	for _, query := range []string{"", "#@!", "a b c d e f g h i j k"} {
+		_, err := tweetService.Search(1, query, "", time.Time{}, "", 0)
+		assert.Equal(t, errors.Is(err, service.ErrInvalidSearch), true)
+	}
+}
+
+func TestSearchErrorUserNotFound(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	tweetService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	mockRepository.On("GetUserByHandle", "nadie").Return(models.User{}, false, nil)
+
+	_, err := tweetService.Search(1, "hola", "nadie", time.Time{}, "", 0)
+	assert.Equal(t, errors.Is(err, service.ErrUserNotFound), true)
+}
+
+func TestFollowErrorUserNotFound(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	followService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	mockRepository.On("GetUser", uint(999999)).Return(models.User{}, false, nil)
+
+	err := followService.Follow(1, 999999)
+	assert.Equal(t, errors.Is(err, service.ErrUserNotFound), true)
+}
+
+func TestGetTimelineErrorUserNotFound(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	timelineService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	mockRepository.On("GetUser", uint(2)).Return(models.User{}, false, nil)
+
+	_, err := timelineService.GetTimeLine(2, "", 0)
+	assert.Equal(t, errors.Is(err, service.ErrUserNotFound), true)
+}
+
+func TestUpdateProfile(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	profileService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	user := models.User{ID: 1, Handle: "uala", Profile: models.Profile{DisplayName: "Ualá", Bio: "challenge"}}
+	bio := "  backend  "
+
+	mockRepository.On("GetUser", uint(1)).Return(user, true, nil)
+	mockRepository.On("UpdateProfile", uint(1), models.Profile{DisplayName: "Ualá", Bio: "backend"}).Return(nil)
+
+	//the display name isn't changed
+	updated, err := profileService.UpdateProfile(1, nil, &bio)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, updated.Handle, "uala")
+	assert.Equal(t, updated.Profile, models.Profile{DisplayName: "Ualá", Bio: "backend"})
+}
+
+func TestUpdateProfileErrorBioTooLong(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	profileService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	bio := strings.Repeat("á", models.MaxBioLength+1)
+
+	mockRepository.On("GetUser", uint(1)).Return(models.User{ID: 1, Handle: "uala"}, true, nil)
+
+	_, err := profileService.UpdateProfile(1, nil, &bio)
+	assert.Equal(t, err, models.ErrBioTooLong)
+}
+
+func TestRegisterErrorDisplayNameTooLong(t *testing.T) {
+	registerService := service.TwitterService{
+		Clock: utils.Clock{},
+	}
+
+	_, err := registerService.Register("uala", "password123", strings.Repeat("u", models.MaxDisplayNameLength+1), "")
+	assert.Equal(t, err, models.ErrDisplayNameTooLong)
+}
+
+func TestResolveHandle(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	handleService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	user := models.User{ID: 1, Handle: "renamed"}
+
+	mockRepository.On("GetUserByHandle", "renamed").Return(user, true, nil)
+	mockRepository.On("GetUserByHandle", "uala").Return(models.User{}, false, nil)
+	mockRepository.On("GetUserByHandle", "unknown").Return(models.User{}, false, nil)
+	mockRepository.On("GetHandleRedirect", "uala").Return(uint(1), true, nil)
+	mockRepository.On("GetHandleRedirect", "unknown").Return(uint(0), false, nil)
+	mockRepository.On("GetUser", uint(1)).Return(user, true, nil)
+
+	resolved, redirected, err := handleService.ResolveHandle("renamed")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, redirected, false)
+	assert.Equal(t, resolved, user)
+
+	resolved, redirected, err = handleService.ResolveHandle("uala")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, redirected, true)
+	assert.Equal(t, resolved, user)
+
+	_, _, err = handleService.ResolveHandle("unknown")
+	assert.Equal(t, errors.Is(err, service.ErrUserNotFound), true)
+}
+
+func TestRenameHandle(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	handleService := service.TwitterService{
+		Repository:        mockRepository,
+		HandleGracePeriod: time.Hour,
+	}
+
+	mockRepository.On("GetUser", uint(1)).Return(models.User{ID: 1, Handle: "uala"}, true, nil)
+	mockRepository.On("RenameHandle", uint(1), "renamed", time.Hour).Return(nil)
+
+	renamed, err := handleService.RenameHandle(1, "renamed")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, renamed.ID, uint(1))
+	assert.Equal(t, renamed.Handle, "renamed")
+}
+
+func TestRenameHandleErrorHandleTaken(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	handleService := service.TwitterService{
+		Repository: mockRepository,
+	}
+
+	mockRepository.On("GetUser", uint(1)).Return(models.User{ID: 1, Handle: "uala"}, true, nil)
+	mockRepository.On("RenameHandle", uint(1), "other", service.DefaultHandleGracePeriod).Return(repository.ErrHandleTaken)
+
+	_, err := handleService.RenameHandle(1, "other")
+	assert.Equal(t, err, service.ErrHandleTaken)
+}
+
+func TestRenameHandleNegativeGracePeriodUsesDefault(t *testing.T) {
+	mockRepository := repositoryMocks.NewIRepository(t)
+
+	handleService := service.TwitterService{
+		Repository:        mockRepository,
+		HandleGracePeriod: -time.Hour,
+	}
+
+	mockRepository.On("GetUser", uint(1)).Return(models.User{ID: 1, Handle: "uala"}, true, nil)
+	mockRepository.On("RenameHandle", uint(1), "renamed", service.DefaultHandleGracePeriod).Return(nil)
+
+	_, err := handleService.RenameHandle(1, "renamed")
+	assert.Equal(t, err, nil)
+}
+
+func TestRenameHandleErrorInvalidHandle(t *testing.T) {
+	handleService := service.TwitterService{}
+
+	_, err := handleService.RenameHandle(1, "not a handle")
+	assert.Equal(t, err, models.ErrInvalidHandle)
 }
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/PatricioYegros/uala_challenge/app/models"
+)
+
+var (
+	ErrCreatingSession    = errors.New("error creating session")
+	ErrInvalidSession     = errors.New("invalid or expired session")
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrInvalidCredentials = errors.New("invalid handle or password")
+	ErrAccountLocked      = errors.New("too many failed logins, try again later")
+)
+
+const (
+	tokenBytes      = 32
+	MaxFailedLogins = 5
+)
+
+// unknownUser is checked instead of a user that doesn't exist, so a login takes the same time whether the handle
+// exists or not. Its hash has the cost of models.User.SetPassword, and the result of the check is ignored
+var unknownUser = models.User{PasswordHash: []byte("$2a$10$hYV9ef1jzlgtyWD2/rOlIuCRGFR33miRrGaRRHeflXTgUyGpZUlVe")}
+
+// Login checks the credentials of the user with handle and starts a new session, returning its bearer token and the user id.
+// After MaxFailedLogins failed attempts the account is locked until repository.FailedLoginsTTL passes.
+// Returns ErrInvalidCredentials if handle or password are wrong, ErrAccountLocked if the account is locked or
+// ErrCreatingSession if an error occurred
+func (service TwitterService) Login(handle, password string) (string, uint, error) {
+	user, ok, err := service.Repository.GetUserByHandle(handle)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if !ok {
+		unknownUser.CheckPassword(password)
+		return "", 0, ErrInvalidCredentials
+	}
+
+	// The attempt is counted before checking the password so concurrent guesses can't go past MaxFailedLogins
+	attempts, err := service.Repository.AddFailedLogin(user.ID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if attempts > MaxFailedLogins {
+		return "", 0, ErrAccountLocked
+	}
+
+	if !user.CheckPassword(password) {
+		return "", 0, ErrInvalidCredentials
+	}
+
+	err = service.Repository.ResetFailedLogins(user.ID)
+	if err != nil {
+		log.Println(err.Error())
+	}
+
+	token, err := service.startSession(user.ID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return token, user.ID, nil
+}
+
+// startSession creates a new session for userID and returns its bearer token
+// Returns ErrCreatingSession if an error occurred
+func (service TwitterService) startSession(userID uint) (string, error) {
+	token, err := newToken()
+	if err != nil {
+		log.Println(err.Error())
+		return "", fmt.Errorf("%w for user %d", ErrCreatingSession, userID)
+	}
+
+	now := service.Clock.Now()
+	session := models.Session{
+		ID:         SessionID(token),
+		UserID:     userID,
+		CreatedAt:  now,
+		LastUsedAt: now,
+	}
+
+	err = service.Repository.CreateSession(session)
+	if err != nil {
+		log.Println(err.Error())
+		return "", fmt.Errorf("%w for user %d", ErrCreatingSession, userID)
+	}
+
+	return token, nil
+}
+
+// Authenticate returns the session of token, extending its expiration
+// Returns ErrInvalidSession if the session doesn't exist or expired
+func (service TwitterService) Authenticate(token string) (models.Session, error) {
+	session, ok, err := service.Repository.GetSession(SessionID(token))
+	if err != nil {
+		return models.Session{}, err
+	}
+
+	if !ok {
+		return models.Session{}, ErrInvalidSession
+	}
+
+	session.LastUsedAt = service.Clock.Now()
+
+	err = service.Repository.RefreshSession(session)
+	if err != nil {
+		return models.Session{}, err
+	}
+
+	return session, nil
+}
+
+// GetSessions returns the active sessions of userID, from the oldest to the newest
+func (service TwitterService) GetSessions(userID uint) ([]models.Session, error) {
+	return service.Repository.GetSessions(userID)
+}
+
+// RevokeSession ends the session of userID with sessionID
+// Returns ErrSessionNotFound if userID doesn't have an active session with that id
+func (service TwitterService) RevokeSession(userID uint, sessionID string) error {
+	session, ok, err := service.Repository.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if !ok || session.UserID != userID {
+		return ErrSessionNotFound
+	}
+
+	return service.Repository.DeleteSessions(userID, []string{sessionID})
+}
+
+// RevokeSessions ends every active session of userID
+func (service TwitterService) RevokeSessions(userID uint) error {
+	sessions, err := service.Repository.GetSessions(userID)
+	if err != nil {
+		return err
+	}
+
+	sessionIDs := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		sessionIDs = append(sessionIDs, session.ID)
+	}
+
+	return service.Repository.DeleteSessions(userID, sessionIDs)
+}
+
+// SessionID returns the id under which the session of token is stored
+func SessionID(token string) string {
+	return hashToken(token)
+}
+
+// hashToken returns the id under which a token is stored, so that tokens themselves are never stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// newToken returns a random opaque token
+func newToken() (string, error) {
+	token := make([]byte, tokenBytes)
+
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
@@ -0,0 +1,309 @@
+package service
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PatricioYegros/uala_challenge/app/models"
+	"github.com/PatricioYegros/uala_challenge/app/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultTimelinePageSize is the default amount of tweets in a timeline page
+	DefaultTimelinePageSize = 10
+)
+
+const (
+	cursorMaxID   = "max"
+	cursorSinceID = "since"
+)
+
+// timelineCursor points at a tweet of a timeline. With max_id semantics the page has the tweets older than it,
+// with since_id semantics the newest tweets newer than it.
+type timelineCursor struct {
+	kind      string
+	tweetID   uuid.UUID
+	timestamp time.Time
+}
+
+// GetTimeline returns a page of up to limit tweets of the user timeline, newest first, skipping the ones that expired.
+// An empty cursor returns the newest tweets, NextCursor of a page continues with older tweets and
+// PreviousCursor returns the tweets newer than the page. A limit of 0 uses TimelinePageSize.
+// The latest tweets of followed users above FanOutThreshold are merged by timestamp, and every tweet retweeted by
+// followed users is annotated with them. LikedByMe tells if userID likes every tweet.
+// Returns ErrInvalidCursor or ErrInvalidLimit if they can't be used, ErrUserNotFound if userID doesn't exist or
+// ErrorGettingUser or ErrTimeline if an error ocurred
+func (service TwitterService) GetTimeLine(userID uint, cursor string, limit int) (models.TimelinePage, error) {
+	position, err := parseTimelineCursor(cursor)
+	if err != nil {
+		return models.TimelinePage{}, err
+	}
+
+	if limit == 0 {
+		limit = service.timelinePageSize()
+	}
+
+	limit, err = pageLimit(limit)
+	if err != nil {
+		return models.TimelinePage{}, err
+	}
+
+	err = service.checkUsers(userID)
+	if err != nil {
+		return models.TimelinePage{}, err
+	}
+
+	tweetsIDs, err := service.Repository.GetTimeLine(userID)
+	if err != nil {
+		return models.TimelinePage{}, ErrorGettingTimeline
+	}
+
+	//one more tweet than the page tells if there is a next one
+	tweets, missing, err := service.hydrateTimeline(tweetsIDs, position, limit+1)
+	if err != nil {
+		return models.TimelinePage{}, err
+	}
+
+	if service.PruneTimelines && len(missing) > 0 {
+		//best effort, the missing ids are skipped anyway
+		err = service.Repository.RemoveFromTimeline(userID, missing)
+		if err != nil {
+			log.Println(err.Error())
+		}
+	}
+
+	if service.FanOutThreshold > 0 {
+		tweets, err = service.mergePulledTweets(userID, tweets, position, limit+1)
+		if err != nil {
+			log.Println(err.Error())
+			return models.TimelinePage{}, ErrorGettingTimeline
+		}
+	}
+
+	err = service.annotateRetweets(userID, tweets[:min(limit, len(tweets))])
+	if err != nil {
+		log.Println(err.Error())
+		return models.TimelinePage{}, ErrorGettingTimeline
+	}
+
+	err = service.annotateLikes(userID, tweets[:min(limit, len(tweets))])
+	if err != nil {
+		log.Println(err.Error())
+		return models.TimelinePage{}, ErrorGettingTimeline
+	}
+
+	return newTimelinePage(tweets, cursor, position, limit), nil
+}
+
+// newTimelinePage returns the page of the first limit tweets, read after position, with the cursors to
+// the older and newer ones
+func newTimelinePage(tweets []models.Tweet, cursor string, position timelineCursor, limit int) models.TimelinePage {
+	page := models.TimelinePage{
+		Tweets: tweets,
+	}
+
+	if position.kind == cursorSinceID {
+		//nothing newer yet, poll again with the same cursor
+		page.PreviousCursor = cursor
+	}
+
+	if len(tweets) > limit {
+		page.Tweets = tweets[:limit]
+		page.NextCursor = newTimelineCursor(cursorMaxID, tweets[limit-1]).String()
+	}
+
+	if len(tweets) > 0 {
+		page.PreviousCursor = newTimelineCursor(cursorSinceID, tweets[0]).String()
+	}
+
+	return page
+}
+
+// hydrateTimeline returns up to count tweets of the timeline tweetsIDs after position, and the ids that don't exist anymore.
+// Tweets are fetched in batches of the amount still needed. The ids aren't always in timestamp order, retweets and
+// backfills add older tweets and other lists are ordered by something else, so if the cursor tweet isn't in
+// tweetsIDs anymore every tweet is compared with it.
+func (service TwitterService) hydrateTimeline(tweetsIDs []uuid.UUID, position timelineCursor, count int) ([]models.Tweet, []uuid.UUID, error) {
+	candidates := tweetsIDs
+	includes := position.includes
+
+	if i := slices.Index(tweetsIDs, position.tweetID); position.kind != "" && i >= 0 {
+		//the cursor tweet is still in the timeline, no need to compare timestamps
+		includes = func(models.Tweet) bool { return true }
+
+		if position.kind == cursorMaxID {
+			candidates = tweetsIDs[i+1:]
+		} else {
+			candidates = tweetsIDs[:i]
+		}
+	}
+
+	tweets := make([]models.Tweet, 0, count)
+	var missing []uuid.UUID
+
+	for len(candidates) > 0 && len(tweets) < count {
+		batch := candidates[:min(count-len(tweets), len(candidates))]
+		candidates = candidates[len(batch):]
+
+		found, batchMissing, err := service.Repository.GetTweets(batch)
+		if err != nil {
+			return nil, nil, err
+		}
+		missing = append(missing, batchMissing...)
+
+		for _, tweet := range found {
+			if includes(tweet) {
+				tweets = append(tweets, tweet)
+			}
+		}
+	}
+
+	return tweets, missing, nil
+}
+
+// mergePulledTweets merges into tweets the latest tweets after position of the users followed by userID
+// above FanOutThreshold, which aren't pushed to the timeline, keeping the newest count
+func (service TwitterService) mergePulledTweets(userID uint, tweets []models.Tweet, position timelineCursor, count int) ([]models.Tweet, error) {
+	pulled, err := service.pullTweets(userID, position, count)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pulled) == 0 {
+		return tweets, nil
+	}
+
+	merged := slices.Clone(tweets)
+	for _, tweet := range pulled {
+		if position.includes(tweet) && !slices.ContainsFunc(merged, func(other models.Tweet) bool { return other.ID == tweet.ID }) {
+			merged = append(merged, tweet)
+		}
+	}
+
+	slices.SortStableFunc(merged, func(a, b models.Tweet) int {
+		return b.Timestamp.Compare(a.Timestamp)
+	})
+
+	if len(merged) > count {
+		merged = merged[:count]
+	}
+
+	return merged, nil
+}
+
+// pullTweets returns the latest count tweets of each user followed by userID above FanOutThreshold, which aren't
+// pushed to the timeline. Older pages look further back, up to repository.MaxUserTweets per user.
+func (service TwitterService) pullTweets(userID uint, position timelineCursor, count int) ([]models.Tweet, error) {
+	following, err := service.Repository.GetFollowing(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := service.Repository.CountFollowersOf(following)
+	if err != nil {
+		return nil, err
+	}
+
+	userCount := int64(count)
+	if position.kind == cursorMaxID {
+		userCount = repository.MaxUserTweets
+	}
+
+	var ids []uuid.UUID
+	for i, followedID := range following {
+		if counts[i] <= service.FanOutThreshold {
+			continue
+		}
+
+		userTweets, err := service.Repository.GetUserTweets(followedID, userCount)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, userTweets...)
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	tweets, _, err := service.Repository.GetTweets(ids)
+
+	return tweets, err
+}
+
+func newTimelineCursor(kind string, tweet models.Tweet) timelineCursor {
+	return timelineCursor{
+		kind:      kind,
+		tweetID:   tweet.ID,
+		timestamp: tweet.Timestamp,
+	}
+}
+
+// includes tells if tweet belongs to the pages after the cursor, comparing timestamps
+func (cursor timelineCursor) includes(tweet models.Tweet) bool {
+	switch cursor.kind {
+	case cursorMaxID:
+		return tweet.Timestamp.Before(cursor.timestamp)
+	case cursorSinceID:
+		return tweet.Timestamp.After(cursor.timestamp)
+	default:
+		return true
+	}
+}
+
+// String encodes the cursor as an opaque string
+func (cursor timelineCursor) String() string {
+	value := fmt.Sprintf("%s:%d:%s", cursor.kind, cursor.timestamp.UnixNano(), cursor.tweetID)
+
+	return base64.RawURLEncoding.EncodeToString([]byte(value))
+}
+
+// parseTimelineCursor decodes a cursor encoded by timelineCursor.String. An empty cursor is the zero timelineCursor.
+func parseTimelineCursor(cursor string) (timelineCursor, error) {
+	if cursor == "" {
+		return timelineCursor{}, nil
+	}
+
+	value, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return timelineCursor{}, ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(value), ":")
+	if len(parts) != 3 || (parts[0] != cursorMaxID && parts[0] != cursorSinceID) {
+		return timelineCursor{}, ErrInvalidCursor
+	}
+
+	nanos, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return timelineCursor{}, ErrInvalidCursor
+	}
+
+	tweetID, err := uuid.Parse(parts[2])
+	if err != nil {
+		return timelineCursor{}, ErrInvalidCursor
+	}
+
+	return timelineCursor{
+		kind:      parts[0],
+		tweetID:   tweetID,
+		timestamp: time.Unix(0, nanos),
+	}, nil
+}
+
+// timelinePageSize returns TimelinePageSize, or DefaultTimelinePageSize if it isn't set
+func (service TwitterService) timelinePageSize() int {
+	if service.TimelinePageSize <= 0 {
+		return DefaultTimelinePageSize
+	}
+
+	return service.TimelinePageSize
+}
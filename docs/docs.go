@@ -15,90 +15,1316 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/config": {
+            "get": {
+                "description": "Returns the config the server is running with, with its secrets redacted. Only enabled if an admin token is configured.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Effective Config",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/config.Config"
+                        }
+                    }
+                }
+            }
+        },
+        "/hashtag/{tag}": {
+            "get": {
+                "description": "Get the tweets with a hashtag, newest first, a page at a time",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Hashtag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "hashtag, with or without #",
+                        "name": "tag",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "nextCursor of the previous page for older tweets, or previousCursor for newer ones",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "max tweets in the page, 10 by default",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TimelinePage"
+                        }
+                    }
+                }
+            }
+        },
+        "/search": {
+            "get": {
+                "description": "Search the tweets with every word of q, newest first, a page at a time. Words are matched ignoring case and\npunctuation, so @ and # aren't needed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Search",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "words to find, up to 10. Required without from",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "handle or id of the author",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time or date of the oldest tweets",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "nextCursor of the previous page for older tweets, or previousCursor for newer ones",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "max tweets in the page, 10 by default",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TimelinePage"
+                        }
+                    }
+                }
+            }
+        },
+        "/trends": {
+            "get": {
+                "description": "Get the trending hashtags, highest score first. Recent tweets count more, so old spikes fall off.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Trends",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "max hashtags, 10 by default",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Trend"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tweet/{tweetID}/thread": {
+            "get": {
+                "description": "Returns the whole conversation of a tweet as trees of replies, oldest first. Replies to deleted tweets start their own tree.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Thread",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "tweetID",
+                        "name": "tweetID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Thread"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/login": {
+            "post": {
+                "description": "Checks the credentials of a user and returns the bearer token of a new session",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Login",
+                "parameters": [
+                    {
+                        "description": "credentials",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CredentialsRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.LoginResponseBody"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/logout": {
+            "post": {
+                "description": "Ends the session of the bearer token",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Logout",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/user/password/reset": {
+            "post": {
+                "description": "Issues a single use password reset token for handle. Tokens are only delivered through the server log when LOG_PASSWORD_RESETS is set, for development.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Request Password Reset",
+                "parameters": [
+                    {
+                        "description": "handle",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.PasswordResetRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted"
+                    }
+                }
+            }
+        },
+        "/user/password/reset/confirm": {
+            "post": {
+                "description": "Sets a new password using a password reset token, ending every session of the user",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Reset Password",
+                "parameters": [
+                    {
+                        "description": "token and new password",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ResetPasswordRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/user/{userID}": {
+            "get": {
+                "description": "Get the profile of a user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Replaces the display name and bio of userID, the ones missing in the body are kept",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Update Profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token of userID",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "new profile",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ProfileRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    }
+                }
+            }
+        },
         "/user/{userID}/follower/{followerID}": {
             "post": {
-                "description": "FollowerID start to follow UserID",
+                "description": "FollowerID start to follow UserID",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Follow User",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token of followerID",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of followerID",
+                        "name": "followerID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "delete": {
+                "description": "FollowerID stops following UserID",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Unfollow User",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token of followerID",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of followerID",
+                        "name": "followerID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "remove the tweets of userID from the timeline of followerID",
+                        "name": "purge",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/user/{userID}/followers": {
+            "get": {
+                "description": "Lists the followers of userID, a page at a time",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Followers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "nextCursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "max users in the page, 20 by default",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.UsersPage"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{userID}/following": {
+            "get": {
+                "description": "Lists the users followed by userID, a page at a time",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Following",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "nextCursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "max users in the page, 20 by default",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.UsersPage"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{userID}/handle": {
+            "put": {
+                "description": "Changes the handle of userID. The previous one keeps redirecting to the user for a grace period, and nobody else can take it meanwhile.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Rename Handle",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token of userID",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "new handle",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.HandleRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{userID}/likes": {
+            "get": {
+                "description": "Get the tweets liked by a user, newest like first, a page at a time. Any user can read them.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Likes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "nextCursor of the previous page for older likes, or previousCursor for newer ones",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "max tweets in the page, 10 by default",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TimelinePage"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{userID}/notifications": {
+            "get": {
+                "description": "Get the notifications inbox of userID, newest first, a page at a time, with the amount of unread notifications",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Notifications",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token of userID",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "nextCursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "max notifications in the page, 20 by default",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotificationsPage"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{userID}/notifications/{notificationID}/read": {
+            "post": {
+                "description": "Marks a notification of userID and every older one as read",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Read Notifications",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token of userID",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id of the newest read notification",
+                        "name": "notificationID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/user/{userID}/password": {
+            "put": {
+                "description": "Changes the password of userID, ending its other sessions",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Change Password",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token of userID",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "passwords",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ChangePasswordRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/user/{userID}/sessions": {
+            "get": {
+                "description": "Lists the active sessions of userID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Sessions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token of userID",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.SessionResponseBody"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Ends every session of userID, including the current one",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Revoke Sessions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token of userID",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/user/{userID}/sessions/{sessionID}": {
+            "delete": {
+                "description": "Ends one of the sessions of userID",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Revoke Session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token of userID",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "sessionID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/user/{userID}/timeline": {
+            "get": {
+                "description": "Get the timeline of certain user, a page at a time",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Timeline",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token of userID",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "nextCursor of the previous page for older tweets, or previousCursor for newer ones",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "max tweets in the page, 10 by default",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TimelinePage"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{userID}/tweet": {
+            "post": {
+                "description": "User makes a Tweet",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Tweet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token of userID",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    }
+                }
+            }
+        },
+        "/user/{userID}/tweet/{tweetID}": {
+            "delete": {
+                "description": "Deletes a tweet of userID, removing it from the timelines of its followers",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Delete Tweet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token of userID",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "tweetID",
+                        "name": "tweetID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            },
+            "patch": {
+                "description": "Replaces the content of a tweet of userID, keeping the previous one in its history. Tweets can only be edited for a while after being created.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Edit Tweet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token of userID",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "tweetID",
+                        "name": "tweetID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "new content",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.TweetRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Tweet"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{userID}/tweet/{tweetID}/history": {
+            "get": {
+                "description": "Lists every revision of a tweet of userID, oldest first, ending with the current one",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Tweet History",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "tweetID",
+                        "name": "tweetID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.TweetRevision"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{userID}/tweet/{tweetID}/like": {
+            "post": {
+                "description": "userID likes a tweet of any user",
                 "produces": [
                     "text/plain"
                 ],
                 "tags": [
                     "Twitter"
                 ],
-                "summary": "Follow User",
+                "summary": "Like",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "followerID",
-                        "name": "followerID",
+                        "type": "string",
+                        "description": "Bearer token of userID",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
+                        "name": "userID",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "userID",
+                        "type": "string",
+                        "description": "id of the liked tweet",
+                        "name": "tweetID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            },
+            "delete": {
+                "description": "Removes the like of userID to a tweet",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Unlike",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token of userID",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
                         "name": "userID",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id of the liked tweet",
+                        "name": "tweetID",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK"
+                    "204": {
+                        "description": "No Content"
                     }
                 }
             }
         },
-        "/user/{userID}/timeline": {
-            "get": {
-                "description": "Get the timeline of certain user",
+        "/user/{userID}/tweet/{tweetID}/quote": {
+            "post": {
+                "description": "userID tweets its own content quoting a tweet of any user, notifying its author. Quotes include the quoted tweet when read, or quoteUnavailable if it expired or was deleted.",
                 "produces": [
-                    "application/json"
+                    "text/plain"
                 ],
                 "tags": [
                     "Twitter"
                 ],
-                "summary": "Timeline",
+                "summary": "Quote",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "userID",
+                        "type": "string",
+                        "description": "Bearer token of userID",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
                         "name": "userID",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id of the quoted tweet",
+                        "name": "tweetID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "content",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.TweetRequestBody"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK"
+                    "201": {
+                        "description": "Created"
                     }
                 }
             }
         },
-        "/user/{userID}/tweet": {
+        "/user/{userID}/tweet/{tweetID}/reply": {
             "post": {
-                "description": "User makes a Tweet",
+                "description": "userID replies to a tweet of any user, notifying its author. The reply is a tweet of the conversation of the replied tweet.",
                 "produces": [
                     "text/plain"
                 ],
                 "tags": [
                     "Twitter"
                 ],
-                "summary": "Tweet",
+                "summary": "Reply",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "userID",
+                        "type": "string",
+                        "description": "Bearer token of userID",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
                         "name": "userID",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "body",
+                        "type": "string",
+                        "description": "id of the replied tweet",
+                        "name": "tweetID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "content",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.TweetRequestBody"
                         }
                     }
                 ],
@@ -108,6 +1334,641 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "/user/{userID}/tweet/{tweetID}/retweet": {
+            "post": {
+                "description": "userID shares a tweet of any user with its followers. Followers that already have the tweet in their timeline don't get it again.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Retweet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token of userID",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id of the retweeted tweet",
+                        "name": "tweetID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            },
+            "delete": {
+                "description": "Undoes a retweet of userID, removing the tweet from the timelines of the followers that only got it through the retweet",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Undo Retweet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token of userID",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id or @handle of userID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "id of the retweeted tweet",
+                        "name": "tweetID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/users": {
+            "post": {
+                "description": "Creates a user with a unique handle, a password and optionally a display name and a bio",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Twitter"
+                ],
+                "summary": "Register",
+                "parameters": [
+                    {
+                        "description": "credentials and profile",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.RegisterRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "config.Config": {
+            "type": "object",
+            "properties": {
+                "limits": {
+                    "$ref": "#/definitions/config.Limits"
+                },
+                "server": {
+                    "$ref": "#/definitions/config.Server"
+                },
+                "storage": {
+                    "$ref": "#/definitions/config.Storage"
+                },
+                "timelines": {
+                    "$ref": "#/definitions/config.Timelines"
+                },
+                "trends": {
+                    "$ref": "#/definitions/config.Trends"
+                }
+            }
+        },
+        "config.Limits": {
+            "type": "object",
+            "properties": {
+                "editWindow": {
+                    "type": "string",
+                    "example": "30m0s"
+                },
+                "handleGracePeriod": {
+                    "description": "HandleGracePeriod is how long the previous handle of a user redirects to it",
+                    "type": "string",
+                    "example": "720h0m0s"
+                },
+                "maxTweetLength": {
+                    "type": "integer"
+                },
+                "sessionTtl": {
+                    "type": "string",
+                    "example": "24h0m0s"
+                },
+                "timelinePageSize": {
+                    "type": "integer"
+                },
+                "timelineSize": {
+                    "type": "integer"
+                },
+                "tweetTtl": {
+                    "type": "string",
+                    "example": "24h0m0s"
+                }
+            }
+        },
+        "config.Server": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "description": "Address is the address the http api listens on",
+                    "type": "string"
+                },
+                "adminToken": {
+                    "description": "AdminToken enables the admin endpoints for the requests sending it. Secret.",
+                    "type": "string"
+                },
+                "logPasswordResets": {
+                    "description": "LogPasswordResets writes the password reset tokens to the log, for development without a mail service.\nWithout it, only the request is logged.",
+                    "type": "boolean"
+                },
+                "role": {
+                    "description": "Role is RoleAPI to serve the http api, RoleWorker to run the fan-out workers or RoleAll for both",
+                    "type": "string"
+                }
+            }
+        },
+        "config.Storage": {
+            "type": "object",
+            "properties": {
+                "backend": {
+                    "description": "Backend is StorageBackendRedis, StorageBackendMemory or StorageBackendSQL",
+                    "type": "string"
+                },
+                "cachePassword": {
+                    "description": "CachePassword is the password of Redis. Secret.",
+                    "type": "string"
+                },
+                "cacheUrl": {
+                    "type": "string"
+                },
+                "sqlDriver": {
+                    "type": "string"
+                },
+                "sqlDsn": {
+                    "description": "SQLDSN is the data source name of the sql database, it may have credentials. Secret.",
+                    "type": "string"
+                }
+            }
+        },
+        "config.Timelines": {
+            "type": "object",
+            "properties": {
+                "backfillTweets": {
+                    "type": "integer"
+                },
+                "fanOutThreshold": {
+                    "type": "integer"
+                },
+                "fanOutWorkers": {
+                    "type": "integer"
+                },
+                "pruneTimelines": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "config.Trends": {
+            "type": "object",
+            "properties": {
+                "halfLife": {
+                    "description": "HalfLife is how long it takes for a tweet to count half for trends",
+                    "type": "string",
+                    "example": "2h0m0s"
+                },
+                "window": {
+                    "description": "Window is how far back the hashtags of tweets count for trends",
+                    "type": "string",
+                    "example": "24h0m0s"
+                }
+            }
+        },
+        "main.ChangePasswordRequestBody": {
+            "type": "object",
+            "properties": {
+                "currentPassword": {
+                    "type": "string"
+                },
+                "newPassword": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.CredentialsRequestBody": {
+            "type": "object",
+            "properties": {
+                "handle": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.HandleRequestBody": {
+            "type": "object",
+            "properties": {
+                "handle": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.LoginResponseBody": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.PasswordResetRequestBody": {
+            "type": "object",
+            "properties": {
+                "handle": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.ProfileRequestBody": {
+            "type": "object",
+            "properties": {
+                "bio": {
+                    "type": "string"
+                },
+                "displayName": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.RegisterRequestBody": {
+            "type": "object",
+            "properties": {
+                "bio": {
+                    "type": "string"
+                },
+                "displayName": {
+                    "type": "string"
+                },
+                "handle": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.ResetPasswordRequestBody": {
+            "type": "object",
+            "properties": {
+                "newPassword": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.SessionResponseBody": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "current": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "lastUsedAt": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.TweetRequestBody": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Notification": {
+            "type": "object",
+            "properties": {
+                "actorId": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "read": {
+                    "description": "Read tells if UserID already read the notification, filled when it's read from the inbox",
+                    "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "tweetId": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.NotificationsPage": {
+            "type": "object",
+            "properties": {
+                "nextCursor": {
+                    "type": "string"
+                },
+                "notifications": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Notification"
+                    }
+                },
+                "unreadCount": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Thread": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string"
+                },
+                "conversationId": {
+                    "description": "ConversationID is the id of the tweet that started the conversation, the tweet itself if it isn't a reply",
+                    "type": "string"
+                },
+                "editedAt": {
+                    "description": "EditedAt is the time of the last edit, nil if the tweet wasn't edited",
+                    "type": "string"
+                },
+                "hashtags": {
+                    "description": "Hashtags are the hashtags of the body, lowercase and in order of appearance",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "inReplyTo": {
+                    "description": "InReplyTo is the id of the tweet this one replies to, nil if it isn't a reply",
+                    "type": "string"
+                },
+                "likeCount": {
+                    "type": "integer"
+                },
+                "likedByMe": {
+                    "description": "LikedByMe tells if the reader of a timeline likes the tweet, only set in timelines",
+                    "type": "boolean"
+                },
+                "mentions": {
+                    "description": "Mentions are the ids of the users mentioned in the body, sorted",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "quoteUnavailable": {
+                    "description": "QuoteUnavailable tells that the quoted tweet expired or was deleted, filled when it's read",
+                    "type": "boolean"
+                },
+                "quotedTweet": {
+                    "description": "QuotedTweet is the tweet quoted by this one, filled when it's read",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Tweet"
+                        }
+                    ]
+                },
+                "quotedTweetId": {
+                    "description": "QuotedTweetID is the id of the tweet quoted by this one, nil if it isn't a quote",
+                    "type": "string"
+                },
+                "replies": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Thread"
+                    }
+                },
+                "replyCount": {
+                    "type": "integer"
+                },
+                "retweetedBy": {
+                    "description": "RetweetedBy are the users followed by the reader of a timeline that retweeted the tweet, only set in timelines",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.TimelinePage": {
+            "type": "object",
+            "properties": {
+                "nextCursor": {
+                    "type": "string"
+                },
+                "previousCursor": {
+                    "type": "string"
+                },
+                "tweets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Tweet"
+                    }
+                }
+            }
+        },
+        "models.Trend": {
+            "type": "object",
+            "properties": {
+                "hashtag": {
+                    "type": "string"
+                },
+                "score": {
+                    "type": "number"
+                },
+                "tweetCount": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Tweet": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string"
+                },
+                "conversationId": {
+                    "description": "ConversationID is the id of the tweet that started the conversation, the tweet itself if it isn't a reply",
+                    "type": "string"
+                },
+                "editedAt": {
+                    "description": "EditedAt is the time of the last edit, nil if the tweet wasn't edited",
+                    "type": "string"
+                },
+                "hashtags": {
+                    "description": "Hashtags are the hashtags of the body, lowercase and in order of appearance",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "inReplyTo": {
+                    "description": "InReplyTo is the id of the tweet this one replies to, nil if it isn't a reply",
+                    "type": "string"
+                },
+                "likeCount": {
+                    "type": "integer"
+                },
+                "likedByMe": {
+                    "description": "LikedByMe tells if the reader of a timeline likes the tweet, only set in timelines",
+                    "type": "boolean"
+                },
+                "mentions": {
+                    "description": "Mentions are the ids of the users mentioned in the body, sorted",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "quoteUnavailable": {
+                    "description": "QuoteUnavailable tells that the quoted tweet expired or was deleted, filled when it's read",
+                    "type": "boolean"
+                },
+                "quotedTweet": {
+                    "description": "QuotedTweet is the tweet quoted by this one, filled when it's read",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Tweet"
+                        }
+                    ]
+                },
+                "quotedTweetId": {
+                    "description": "QuotedTweetID is the id of the tweet quoted by this one, nil if it isn't a quote",
+                    "type": "string"
+                },
+                "replyCount": {
+                    "type": "integer"
+                },
+                "retweetedBy": {
+                    "description": "RetweetedBy are the users followed by the reader of a timeline that retweeted the tweet, only set in timelines",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.TweetRevision": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.User": {
+            "type": "object",
+            "properties": {
+                "bio": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "displayName": {
+                    "type": "string"
+                },
+                "handle": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.UsersPage": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "nextCursor": {
+                    "type": "string"
+                },
+                "userIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
         }
     }
 }`
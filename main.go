@@ -18,83 +18,472 @@
 package main
 
 import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/PatricioYegros/uala_challenge/app"
+	"github.com/PatricioYegros/uala_challenge/app/config"
+	"github.com/PatricioYegros/uala_challenge/app/models"
+	"github.com/PatricioYegros/uala_challenge/app/queue"
 	"github.com/PatricioYegros/uala_challenge/app/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 )
 
 var ErrPermission = "User dont have permission to perform action"
 
+const (
+	userIDContextKey    = "userID"
+	sessionIDContextKey = "sessionID"
+)
+
+// adminTokenHeader carries the admin token of the admin endpoints
+const adminTokenHeader = "X-Admin-Token"
+
+// shutdownTimeout bounds the wait for the requests in flight when the server stops
+const shutdownTimeout = 10 * time.Second
+
 //go:generate swagger generate spec -o ./swagger.json
 var twitterService *service.TwitterService
 
-func init() {
+// fanOutQueue is nil when the storage backend doesn't use Redis, then tweets are fanned out inline
+var fanOutQueue *queue.StreamQueue
+
+// appConfig is the effective config of the server
+var appConfig config.Config
+
+func main() {
+	var redis *redis.Client
 	var err error
 
-	twitterService, _, err = app.NewService()
+	appConfig, err = config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	} else if err != nil {
+		log.Fatalln(err)
+	}
+
+	twitterService, redis, err = app.NewService(appConfig)
 	if err != nil {
 		log.Fatalln(err)
 	}
-}
 
-func main() {
+	if redis != nil {
+		streamQueue, err := app.NewFanOutQueue(appConfig, redis)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		fanOutQueue = &streamQueue
+		twitterService.FanOutQueue = streamQueue
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var workers sync.WaitGroup
+	var workersErr error
+
+	switch appConfig.Server.Role {
+	case config.RoleAll:
+		if fanOutQueue != nil {
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+
+				workersErr = consumeFanOutJobs(ctx)
+				if workersErr != nil {
+					//stop the api too
+					stop()
+				}
+			}()
+		}
+	case config.RoleWorker:
+		if fanOutQueue == nil {
+			log.Fatalln("the worker role needs Redis for the fan-out queue")
+		}
+
+		err = consumeFanOutJobs(ctx)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+
 	r := gin.Default()
 
-	r.POST("/user/login/:userID", login)
-	r.POST("/user/:userID/tweet", tweet)
-	r.POST("/user/:userID/follower/:followerID", follow)
-	r.GET("/user/:userID/timeline", timeline)
+	r.POST("/users", register)
+	r.POST("/user/login", login)
+	r.POST("/user/password/reset", requestPasswordReset)
+	r.POST("/user/password/reset/confirm", resetPassword)
+
+	authorized := r.Group("/", authenticate)
+	authorized.GET("/user/:userID", profile)
+	authorized.PATCH("/user/:userID", updateProfile)
+	authorized.PUT("/user/:userID/handle", renameHandle)
+	authorized.POST("/user/:userID/tweet", tweet)
+	authorized.PATCH("/user/:userID/tweet/:tweetID", editTweet)
+	authorized.DELETE("/user/:userID/tweet/:tweetID", deleteTweet)
+	authorized.GET("/user/:userID/tweet/:tweetID/history", tweetHistory)
+	authorized.POST("/user/:userID/tweet/:tweetID/reply", reply)
+	authorized.POST("/user/:userID/tweet/:tweetID/retweet", retweet)
+	authorized.POST("/user/:userID/tweet/:tweetID/quote", quote)
+	authorized.DELETE("/user/:userID/tweet/:tweetID/retweet", unretweet)
+	authorized.POST("/user/:userID/tweet/:tweetID/like", like)
+	authorized.DELETE("/user/:userID/tweet/:tweetID/like", unlike)
+	authorized.GET("/tweet/:tweetID/thread", thread)
+	authorized.POST("/user/:userID/follower/:followerID", follow)
+	authorized.DELETE("/user/:userID/follower/:followerID", unfollow)
+	authorized.GET("/user/:userID/followers", followers)
+	authorized.GET("/user/:userID/following", following)
+	authorized.GET("/user/:userID/timeline", timeline)
+	authorized.GET("/user/:userID/likes", likes)
+	authorized.GET("/hashtag/:tag", hashtag)
+	authorized.GET("/trends", trends)
+	authorized.GET("/search", search)
+	authorized.GET("/user/:userID/notifications", notifications)
+	authorized.POST("/user/:userID/notifications/:notificationID/read", readNotifications)
+	authorized.POST("/user/logout", logout)
+	authorized.GET("/user/:userID/sessions", sessions)
+	authorized.DELETE("/user/:userID/sessions", revokeSessions)
+	authorized.DELETE("/user/:userID/sessions/:sessionID", revokeSession)
+	authorized.PUT("/user/:userID/password", changePassword)
+
+	if appConfig.Server.AdminToken != "" {
+		admin := r.Group("/admin", authenticateAdmin)
+		admin.GET("/config", effectiveConfig)
+	}
+
+	server := &http.Server{
+		Addr:    appConfig.Server.Address,
+		Handler: r,
+	}
+
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+
+		//let the requests in flight finish
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		err := server.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+
+	err = server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+
+	//ListenAndServe returns as soon as the shutdown starts, the requests and jobs in flight are waited for here
+	stop()
+	<-shutdownDone
+	workers.Wait()
+
+	err = errors.Join(err, workersErr)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// consumeFanOutJobs runs the fan-out workers until ctx is done
+func consumeFanOutJobs(ctx context.Context) error {
+	log.Printf("running %d fan-out workers", fanOutQueue.Workers)
 
-	log.Fatalln(r.Run())
+	return fanOutQueue.Consume(ctx, twitterService.FanOut)
 }
 
 // @Summary Follow User
 // @Description FollowerID start to follow UserID
 // @Tags Twitter
-// @Param followerID path uint true "followerID"
-// @Param userID path uint true "userID"
+// @Param Authorization header string true "Bearer token of followerID"
+// @Param followerID path string true "id or @handle of followerID"
+// @Param userID path string true "id or @handle of userID"
 // @Produce text/plain
 // @Success 200
 // @Router /user/{userID}/follower/{followerID} [post]
 func follow(c *gin.Context) {
-	userID, err := strconv.Atoi(c.Param("userID"))
+	userID, ok := userIDParam(c, "userID")
+	if !ok {
+		return
+	}
+
+	followerID, ok := userIDParam(c, "followerID")
+	if !ok {
+		return
+	}
+
+	if !checkActingUser(c, followerID) {
+		return
+	}
+
+	err := twitterService.Follow(followerID, userID)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
+
+// @Summary Unfollow User
+// @Description FollowerID stops following UserID
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token of followerID"
+// @Param followerID path string true "id or @handle of followerID"
+// @Param userID path string true "id or @handle of userID"
+// @Param purge query bool false "remove the tweets of userID from the timeline of followerID"
+// @Produce text/plain
+// @Success 204
+// @Router /user/{userID}/follower/{followerID} [delete]
+func unfollow(c *gin.Context) {
+	userID, ok := userIDParam(c, "userID")
+	if !ok {
+		return
+	}
+
+	followerID, ok := userIDParam(c, "followerID")
+	if !ok {
+		return
+	}
+
+	if !checkActingUser(c, followerID) {
+		return
+	}
+
+	purge, err := queryPurge(c)
 	if err != nil {
 		returnError(c, err)
 		return
 	}
 
-	followerID, err := strconv.Atoi(c.Param("followerID"))
+	err = twitterService.Unfollow(followerID, userID, purge)
 	if err != nil {
 		returnError(c, err)
 		return
 	}
 
-	log, err := checkUserLog(uint(followerID))
+	c.String(http.StatusNoContent, "")
+}
+
+// @Summary Followers
+// @Description Lists the followers of userID, a page at a time
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token"
+// @Param userID path string true "id or @handle of userID"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Param limit query int false "max users in the page, 20 by default"
+// @Produce application/json
+// @Success 200 {object} models.UsersPage
+// @Router /user/{userID}/followers [get]
+func followers(c *gin.Context) {
+	usersPage(c, twitterService.GetFollowers)
+}
+
+// @Summary Following
+// @Description Lists the users followed by userID, a page at a time
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token"
+// @Param userID path string true "id or @handle of userID"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Param limit query int false "max users in the page, 20 by default"
+// @Produce application/json
+// @Success 200 {object} models.UsersPage
+// @Router /user/{userID}/following [get]
+func following(c *gin.Context) {
+	usersPage(c, twitterService.GetFollowing)
+}
+
+func usersPage(c *gin.Context, getPage func(userID uint, cursor string, limit int) (models.UsersPage, error)) {
+	userID, ok := userIDParam(c, "userID")
+	if !ok {
+		return
+	}
+
+	limit, err := queryLimit(c)
 	if err != nil {
 		returnError(c, err)
 		return
 	}
 
-	if log {
+	page, err := getPage(userID, c.Query("cursor"), limit)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
 
-		err = twitterService.Follow(uint(followerID), uint(userID))
-		if err != nil {
-			returnError(c, err)
-			return
-		}
+	c.IndentedJSON(http.StatusOK, page)
+}
 
-		c.String(http.StatusNoContent, "")
+// queryLimit returns the limit query parameter, or 0 if it's not set
+func queryLimit(c *gin.Context) (int, error) {
+	value := c.Query("limit")
+	if value == "" {
+		return 0, nil
+	}
 
-	} else {
+	limit, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, service.ErrInvalidLimit
+	}
+
+	return limit, nil
+}
+
+// queryPurge returns the purge query parameter, or false if it's not set
+func queryPurge(c *gin.Context) (bool, error) {
+	value := c.Query("purge")
+	if value == "" {
+		return false, nil
+	}
+
+	purge, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, service.ErrInvalidPurge
+	}
+
+	return purge, nil
+}
+
+// querySince returns the since query parameter, a RFC 3339 time or a date, or the zero time if it's not set
+func querySince(c *gin.Context) (time.Time, error) {
+	value := c.Query("since")
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	since, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return since, nil
+	}
+
+	since, err = time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, service.ErrInvalidSince
+	}
+
+	return since, nil
+}
+
+type ProfileRequestBody struct {
+	DisplayName *string `json:"displayName"`
+	Bio         *string `json:"bio"`
+}
+
+// @Summary Profile
+// @Description Get the profile of a user
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token"
+// @Param userID path string true "id or @handle of userID"
+// @Produce application/json
+// @Success 200 {object} models.User
+// @Router /user/{userID} [get]
+func profile(c *gin.Context) {
+	userID, ok := userIDParam(c, "userID")
+	if !ok {
+		return
+	}
+
+	user, err := twitterService.GetUser(userID)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, user)
+}
+
+// @Summary Update Profile
+// @Description Replaces the display name and bio of userID, the ones missing in the body are kept
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token of userID"
+// @Param userID path string true "id or @handle of userID"
+// @Param body body ProfileRequestBody true "new profile"
+// @Produce application/json
+// @Success 200 {object} models.User
+// @Router /user/{userID} [patch]
+func updateProfile(c *gin.Context) {
+	userID, ok := userIDParam(c, "userID")
+	if !ok {
+		return
+	}
+
+	if !checkActingUser(c, userID) {
+		return
+	}
+
+	var requestBody ProfileRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil {
+		returnError(c, err)
+		return
+	}
+
+	user, err := twitterService.UpdateProfile(userID, requestBody.DisplayName, requestBody.Bio)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, user)
+}
+
+type HandleRequestBody struct {
+	Handle string `json:"handle"`
+}
+
+// @Summary Rename Handle
+// @Description Changes the handle of userID. The previous one keeps redirecting to the user for a grace period, and nobody else can take it meanwhile.
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token of userID"
+// @Param userID path string true "id or @handle of userID"
+// @Param body body HandleRequestBody true "new handle"
+// @Produce application/json
+// @Success 200 {object} models.User
+// @Router /user/{userID}/handle [put]
+func renameHandle(c *gin.Context) {
+	userID, ok := userIDParam(c, "userID")
+	if !ok {
+		return
+	}
 
-		c.JSON(http.StatusUnauthorized, gin.H{"error": ErrPermission})
+	if !checkActingUser(c, userID) {
+		return
+	}
+
+	var requestBody HandleRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil {
+		returnError(c, err)
+		return
+	}
+
+	user, err := twitterService.RenameHandle(userID, requestBody.Handle)
+	if err != nil {
+		returnError(c, err)
+		return
 	}
+
+	c.IndentedJSON(http.StatusOK, user)
 }
 
 type TweetRequestBody struct {
@@ -104,113 +493,999 @@ type TweetRequestBody struct {
 // @Summary Tweet
 // @Description User makes a Tweet
 // @Tags Twitter
-// @Param userID path uint true "userID"
+// @Param Authorization header string true "Bearer token of userID"
+// @Param userID path string true "id or @handle of userID"
 // @Param body body string true "body"
 // @Produce text/plain
 // @Success 201
 // @Router /user/{userID}/tweet [post]
 func tweet(c *gin.Context) {
-	userID, err := strconv.Atoi(c.Param("userID"))
-	if err != nil {
+	userID, ok := userIDParam(c, "userID")
+	if !ok {
+		return
+	}
+
+	if !checkActingUser(c, userID) {
+		return
+	}
+
+	var requestBody TweetRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil {
 		returnError(c, err)
 		return
 	}
 
-	log, err := checkUserLog(uint(userID))
+	tweetID, err := twitterService.Tweet(userID, requestBody.Body)
 	if err != nil {
 		returnError(c, err)
 		return
 	}
 
-	if log {
+	c.String(http.StatusCreated, fmt.Sprintf("%d tweet %s created", userID, tweetID))
+}
 
-		var requestBody TweetRequestBody
+// @Summary Edit Tweet
+// @Description Replaces the content of a tweet of userID, keeping the previous one in its history. Tweets can only be edited for a while after being created.
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token of userID"
+// @Param userID path string true "id or @handle of userID"
+// @Param tweetID path string true "tweetID"
+// @Param body body TweetRequestBody true "new content"
+// @Produce application/json
+// @Success 200 {object} models.Tweet
+// @Router /user/{userID}/tweet/{tweetID} [patch]
+func editTweet(c *gin.Context) {
+	userID, tweetID, ok := tweetParams(c)
+	if !ok {
+		return
+	}
 
-		if err = c.BindJSON(&requestBody); err != nil {
-			returnError(c, err)
-			return
-		}
+	if !checkActingUser(c, userID) {
+		return
+	}
 
-		tweetID, err := twitterService.Tweet(uint(userID), requestBody.Body)
-		if err != nil {
-			returnError(c, err)
-			return
-		}
+	var requestBody TweetRequestBody
 
-		c.String(http.StatusCreated, fmt.Sprintf("%d tweet %s created", userID, tweetID))
+	if err := c.BindJSON(&requestBody); err != nil {
+		returnError(c, err)
+		return
+	}
 
-	} else {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": ErrPermission})
+	tweet, err := twitterService.EditTweet(userID, tweetID, requestBody.Body)
+	if err != nil {
+		returnError(c, err)
+		return
 	}
+
+	c.IndentedJSON(http.StatusOK, tweet)
 }
 
-// @Summary Timeline
-// @Description Get the timeline of certain user
+// @Summary Tweet History
+// @Description Lists every revision of a tweet of userID, oldest first, ending with the current one
 // @Tags Twitter
-// @Param userID path uint true "userID"
+// @Param Authorization header string true "Bearer token"
+// @Param userID path string true "id or @handle of userID"
+// @Param tweetID path string true "tweetID"
 // @Produce application/json
-// @Success 200
-// @Router /user/{userID}/timeline [get]
-func timeline(c *gin.Context) {
-	userID, err := strconv.Atoi(c.Param("userID"))
-	if err != nil {
-		returnError(c, err)
+// @Success 200 {array} models.TweetRevision
+// @Router /user/{userID}/tweet/{tweetID}/history [get]
+func tweetHistory(c *gin.Context) {
+	userID, tweetID, ok := tweetParams(c)
+	if !ok {
 		return
 	}
 
-	log, err := checkUserLog(uint(userID))
+	history, err := twitterService.GetTweetHistory(userID, tweetID)
 	if err != nil {
 		returnError(c, err)
 		return
 	}
 
-	if log {
+	c.IndentedJSON(http.StatusOK, history)
+}
+
+// tweetParams returns the userID and tweetID path params, responding with an error if they are invalid
+func tweetParams(c *gin.Context) (uint, uuid.UUID, bool) {
+	userID, ok := userIDParam(c, "userID")
+	if !ok {
+		return 0, uuid.Nil, false
+	}
+
+	tweetID, ok := tweetIDParam(c)
+	if !ok {
+		return 0, uuid.Nil, false
+	}
+
+	return userID, tweetID, true
+}
+
+// tweetIDParam returns the tweetID path param, responding with an error if it's invalid
+func tweetIDParam(c *gin.Context) (uuid.UUID, bool) {
+	tweetID, err := uuid.Parse(c.Param("tweetID"))
+	if err != nil {
+		//no tweet has an invalid id
+		returnError(c, service.ErrTweetNotFound)
+		return uuid.Nil, false
+	}
+
+	return tweetID, true
+}
 
-		timeline, err := twitterService.GetTimeLine(uint(userID))
+// userIDParam returns the user of the name path param, an id or an @handle, responding with an error if there is none.
+// Requests with the previous handle of a renamed user are redirected to its current one.
+func userIDParam(c *gin.Context, name string) (uint, bool) {
+	value := c.Param(name)
+
+	handle, isHandle := strings.CutPrefix(value, "@")
+	if !isHandle {
+		userID, err := strconv.ParseUint(value, 10, 0)
 		if err != nil {
-			returnError(c, err)
-			return
+			//no user has an invalid id
+			returnError(c, service.ErrUserNotFound)
+			return 0, false
 		}
 
-		c.IndentedJSON(http.StatusOK, timeline)
+		return uint(userID), true
+	}
 
-	} else {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": ErrPermission})
+	user, redirected, err := twitterService.ResolveHandle(handle)
+	if err != nil {
+		returnError(c, err)
+		return 0, false
 	}
-}
 
-func returnError(c *gin.Context, err error) {
-	c.JSON(http.StatusInternalServerError, err.Error())
+	if redirected {
+		//same route, with the current handle in place of the previous one
+		segments := strings.Split(c.FullPath(), "/")
+		for i, segment := range segments {
+			if param, ok := strings.CutPrefix(segment, ":"); ok {
+				segments[i] = c.Param(param)
+				if param == name {
+					segments[i] = "@" + user.Handle
+				}
+			}
+		}
+
+		location := url.URL{Path: strings.Join(segments, "/"), RawQuery: c.Request.URL.RawQuery}
+		c.Redirect(http.StatusPermanentRedirect, location.String())
+		return 0, false
+	}
+
+	return user.ID, true
 }
 
-// @Summary Login
-// @Description Logs
-// @Tags Twtter
-// @Param userID path uint true "userID"
-// @Produce application/json
-// @Success 200
-// @Router /user/login/:userID [post]
-func login(c *gin.Context) {
-	userID, err := strconv.Atoi(c.Param("userID"))
-	if err != nil {
+// @Summary Reply
+// @Description userID replies to a tweet of any user, notifying its author. The reply is a tweet of the conversation of the replied tweet.
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token of userID"
+// @Param userID path string true "id or @handle of userID"
+// @Param tweetID path string true "id of the replied tweet"
+// @Param body body TweetRequestBody true "content"
+// @Produce text/plain
+// @Success 201
+// @Router /user/{userID}/tweet/{tweetID}/reply [post]
+func reply(c *gin.Context) {
+	userID, tweetID, ok := tweetParams(c)
+	if !ok {
+		return
+	}
+
+	if !checkActingUser(c, userID) {
+		return
+	}
+
+	var requestBody TweetRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil {
 		returnError(c, err)
 		return
 	}
 
-	err = twitterService.Login(uint(userID))
+	replyID, err := twitterService.Reply(userID, tweetID, requestBody.Body)
 	if err != nil {
 		returnError(c, err)
 		return
 	}
 
-	c.String(http.StatusNoContent, "")
+	c.String(http.StatusCreated, fmt.Sprintf("%d tweet %s created", userID, replyID))
 }
 
-func checkUserLog(userID uint) (bool, error) {
-	value, err := twitterService.CheckUserLog(userID)
+// @Summary Thread
+// @Description Returns the whole conversation of a tweet as trees of replies, oldest first. Replies to deleted tweets start their own tree.
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token"
+// @Param tweetID path string true "tweetID"
+// @Produce application/json
+// @Success 200 {array} models.Thread
+// @Router /tweet/{tweetID}/thread [get]
+func thread(c *gin.Context) {
+	tweetID, ok := tweetIDParam(c)
+	if !ok {
+		return
+	}
+
+	threads, err := twitterService.GetThread(tweetID)
 	if err != nil {
-		return false, err
+		returnError(c, err)
+		return
 	}
 
-	return value, nil
+	c.IndentedJSON(http.StatusOK, threads)
+}
+
+// @Summary Quote
+// @Description userID tweets its own content quoting a tweet of any user, notifying its author. Quotes include the quoted tweet when read, or quoteUnavailable if it expired or was deleted.
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token of userID"
+// @Param userID path string true "id or @handle of userID"
+// @Param tweetID path string true "id of the quoted tweet"
+// @Param body body TweetRequestBody true "content"
+// @Produce text/plain
+// @Success 201
+// @Router /user/{userID}/tweet/{tweetID}/quote [post]
+func quote(c *gin.Context) {
+	userID, tweetID, ok := tweetParams(c)
+	if !ok {
+		return
+	}
+
+	if !checkActingUser(c, userID) {
+		return
+	}
+
+	var requestBody TweetRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil {
+		returnError(c, err)
+		return
+	}
+
+	quoteID, err := twitterService.Quote(userID, tweetID, requestBody.Body)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.String(http.StatusCreated, fmt.Sprintf("%d tweet %s created", userID, quoteID))
+}
+
+// @Summary Retweet
+// @Description userID shares a tweet of any user with its followers. Followers that already have the tweet in their timeline don't get it again.
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token of userID"
+// @Param userID path string true "id or @handle of userID"
+// @Param tweetID path string true "id of the retweeted tweet"
+// @Produce text/plain
+// @Success 204
+// @Router /user/{userID}/tweet/{tweetID}/retweet [post]
+func retweet(c *gin.Context) {
+	userID, tweetID, ok := tweetParams(c)
+	if !ok {
+		return
+	}
+
+	if !checkActingUser(c, userID) {
+		return
+	}
+
+	err := twitterService.Retweet(userID, tweetID)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
+
+// @Summary Undo Retweet
+// @Description Undoes a retweet of userID, removing the tweet from the timelines of the followers that only got it through the retweet
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token of userID"
+// @Param userID path string true "id or @handle of userID"
+// @Param tweetID path string true "id of the retweeted tweet"
+// @Produce text/plain
+// @Success 204
+// @Router /user/{userID}/tweet/{tweetID}/retweet [delete]
+func unretweet(c *gin.Context) {
+	userID, tweetID, ok := tweetParams(c)
+	if !ok {
+		return
+	}
+
+	if !checkActingUser(c, userID) {
+		return
+	}
+
+	err := twitterService.Unretweet(userID, tweetID)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
+
+// @Summary Like
+// @Description userID likes a tweet of any user
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token of userID"
+// @Param userID path string true "id or @handle of userID"
+// @Param tweetID path string true "id of the liked tweet"
+// @Produce text/plain
+// @Success 204
+// @Router /user/{userID}/tweet/{tweetID}/like [post]
+func like(c *gin.Context) {
+	userID, tweetID, ok := tweetParams(c)
+	if !ok {
+		return
+	}
+
+	if !checkActingUser(c, userID) {
+		return
+	}
+
+	err := twitterService.Like(userID, tweetID)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
+
+// @Summary Unlike
+// @Description Removes the like of userID to a tweet
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token of userID"
+// @Param userID path string true "id or @handle of userID"
+// @Param tweetID path string true "id of the liked tweet"
+// @Produce text/plain
+// @Success 204
+// @Router /user/{userID}/tweet/{tweetID}/like [delete]
+func unlike(c *gin.Context) {
+	userID, tweetID, ok := tweetParams(c)
+	if !ok {
+		return
+	}
+
+	if !checkActingUser(c, userID) {
+		return
+	}
+
+	err := twitterService.Unlike(userID, tweetID)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
+
+// @Summary Delete Tweet
+// @Description Deletes a tweet of userID, removing it from the timelines of its followers
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token of userID"
+// @Param userID path string true "id or @handle of userID"
+// @Param tweetID path string true "tweetID"
+// @Produce text/plain
+// @Success 204
+// @Router /user/{userID}/tweet/{tweetID} [delete]
+func deleteTweet(c *gin.Context) {
+	userID, tweetID, ok := tweetParams(c)
+	if !ok {
+		return
+	}
+
+	if !checkActingUser(c, userID) {
+		return
+	}
+
+	err := twitterService.DeleteTweet(userID, tweetID)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
+
+// @Summary Timeline
+// @Description Get the timeline of certain user, a page at a time
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token of userID"
+// @Param userID path string true "id or @handle of userID"
+// @Param cursor query string false "nextCursor of the previous page for older tweets, or previousCursor for newer ones"
+// @Param limit query int false "max tweets in the page, 10 by default"
+// @Produce application/json
+// @Success 200 {object} models.TimelinePage
+// @Router /user/{userID}/timeline [get]
+func timeline(c *gin.Context) {
+	userID, ok := userIDParam(c, "userID")
+	if !ok {
+		return
+	}
+
+	if !checkActingUser(c, userID) {
+		return
+	}
+
+	limit, err := queryLimit(c)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	timeline, err := twitterService.GetTimeLine(userID, c.Query("cursor"), limit)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, timeline)
+}
+
+// @Summary Likes
+// @Description Get the tweets liked by a user, newest like first, a page at a time. Any user can read them.
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token"
+// @Param userID path string true "id or @handle of userID"
+// @Param cursor query string false "nextCursor of the previous page for older likes, or previousCursor for newer ones"
+// @Param limit query int false "max tweets in the page, 10 by default"
+// @Produce application/json
+// @Success 200 {object} models.TimelinePage
+// @Router /user/{userID}/likes [get]
+func likes(c *gin.Context) {
+	userID, ok := userIDParam(c, "userID")
+	if !ok {
+		return
+	}
+
+	limit, err := queryLimit(c)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	likes, err := twitterService.GetLikes(c.GetUint(userIDContextKey), userID, c.Query("cursor"), limit)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, likes)
+}
+
+// @Summary Hashtag
+// @Description Get the tweets with a hashtag, newest first, a page at a time
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token"
+// @Param tag path string true "hashtag, with or without #"
+// @Param cursor query string false "nextCursor of the previous page for older tweets, or previousCursor for newer ones"
+// @Param limit query int false "max tweets in the page, 10 by default"
+// @Produce application/json
+// @Success 200 {object} models.TimelinePage
+// @Router /hashtag/{tag} [get]
+func hashtag(c *gin.Context) {
+	limit, err := queryLimit(c)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	tweets, err := twitterService.GetHashtagTweets(c.GetUint(userIDContextKey), c.Param("tag"), c.Query("cursor"), limit)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, tweets)
+}
+
+// @Summary Search
+// @Description Search the tweets with every word of q, newest first, a page at a time. Words are matched ignoring case and
+// @Description punctuation, so @ and # aren't needed.
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token"
+// @Param q query string false "words to find, up to 10. Required without from"
+// @Param from query string false "handle or id of the author"
+// @Param since query string false "RFC 3339 time or date of the oldest tweets"
+// @Param cursor query string false "nextCursor of the previous page for older tweets, or previousCursor for newer ones"
+// @Param limit query int false "max tweets in the page, 10 by default"
+// @Produce application/json
+// @Success 200 {object} models.TimelinePage
+// @Router /search [get]
+func search(c *gin.Context) {
+	limit, err := queryLimit(c)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	since, err := querySince(c)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	tweets, err := twitterService.Search(c.GetUint(userIDContextKey), c.Query("q"), c.Query("from"), since, c.Query("cursor"), limit)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, tweets)
+}
+
+// @Summary Trends
+// @Description Get the trending hashtags, highest score first. Recent tweets count more, so old spikes fall off.
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token"
+// @Param limit query int false "max hashtags, 10 by default"
+// @Produce application/json
+// @Success 200 {array} models.Trend
+// @Router /trends [get]
+func trends(c *gin.Context) {
+	limit, err := queryLimit(c)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	trends, err := twitterService.GetTrends(limit)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, trends)
+}
+
+// @Summary Notifications
+// @Description Get the notifications inbox of userID, newest first, a page at a time, with the amount of unread notifications
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token of userID"
+// @Param userID path string true "id or @handle of userID"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Param limit query int false "max notifications in the page, 20 by default"
+// @Produce application/json
+// @Success 200 {object} models.NotificationsPage
+// @Router /user/{userID}/notifications [get]
+func notifications(c *gin.Context) {
+	userID, ok := userIDParam(c, "userID")
+	if !ok {
+		return
+	}
+
+	if !checkActingUser(c, userID) {
+		return
+	}
+
+	limit, err := queryLimit(c)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	page, err := twitterService.GetNotifications(userID, c.Query("cursor"), limit)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, page)
+}
+
+// @Summary Read Notifications
+// @Description Marks a notification of userID and every older one as read
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token of userID"
+// @Param userID path string true "id or @handle of userID"
+// @Param notificationID path string true "id of the newest read notification"
+// @Produce text/plain
+// @Success 204
+// @Router /user/{userID}/notifications/{notificationID}/read [post]
+func readNotifications(c *gin.Context) {
+	userID, ok := userIDParam(c, "userID")
+	if !ok {
+		return
+	}
+
+	if !checkActingUser(c, userID) {
+		return
+	}
+
+	notificationID, err := uuid.Parse(c.Param("notificationID"))
+	if err != nil {
+		returnError(c, service.ErrNotificationNotFound)
+		return
+	}
+
+	err = twitterService.MarkNotificationsRead(userID, notificationID)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
+
+func returnError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+
+	switch {
+	case errors.Is(err, service.ErrSessionNotFound),
+		errors.Is(err, service.ErrNotFollowing),
+		errors.Is(err, service.ErrTweetNotFound),
+		errors.Is(err, service.ErrNotRetweeted),
+		errors.Is(err, service.ErrNotLiked),
+		errors.Is(err, service.ErrNotificationNotFound),
+		errors.Is(err, service.ErrUserNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrHandleTaken),
+		errors.Is(err, service.ErrAlreadyRetweeted),
+		errors.Is(err, service.ErrAlreadyLiked):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrInvalidCredentials):
+		status = http.StatusUnauthorized
+	case errors.Is(err, service.ErrAccountLocked):
+		status = http.StatusLocked
+	case errors.Is(err, service.ErrEditWindowExpired):
+		status = http.StatusForbidden
+	case errors.Is(err, models.ErrInvalidHandle),
+		errors.Is(err, models.ErrPasswordTooShort),
+		errors.Is(err, service.ErrInvalidPasswordReset),
+		errors.Is(err, service.ErrInvalidCursor),
+		errors.Is(err, service.ErrInvalidLimit),
+		errors.Is(err, service.ErrInvalidPurge),
+		errors.Is(err, models.ErrMaxLengthExceeded),
+		errors.Is(err, models.ErrInvalidHashtag),
+		errors.Is(err, service.ErrInvalidSearch),
+		errors.Is(err, service.ErrInvalidSince),
+		errors.Is(err, models.ErrDisplayNameTooLong),
+		errors.Is(err, models.ErrBioTooLong):
+		status = http.StatusBadRequest
+	}
+
+	c.JSON(status, err.Error())
+}
+
+type CredentialsRequestBody struct {
+	Handle   string `json:"handle"`
+	Password string `json:"password"`
+}
+
+type RegisterRequestBody struct {
+	Handle      string `json:"handle"`
+	Password    string `json:"password"`
+	DisplayName string `json:"displayName"`
+	Bio         string `json:"bio"`
+}
+
+// @Summary Register
+// @Description Creates a user with a unique handle, a password and optionally a display name and a bio
+// @Tags Twitter
+// @Param body body RegisterRequestBody true "credentials and profile"
+// @Produce application/json
+// @Success 201 {object} models.User
+// @Router /users [post]
+func register(c *gin.Context) {
+	var requestBody RegisterRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil {
+		returnError(c, err)
+		return
+	}
+
+	user, err := twitterService.Register(requestBody.Handle, requestBody.Password, requestBody.DisplayName, requestBody.Bio)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, user)
+}
+
+type LoginResponseBody struct {
+	Token  string `json:"token"`
+	UserID uint   `json:"userId"`
+}
+
+// @Summary Login
+// @Description Checks the credentials of a user and returns the bearer token of a new session
+// @Tags Twitter
+// @Param body body CredentialsRequestBody true "credentials"
+// @Produce application/json
+// @Success 200 {object} LoginResponseBody
+// @Router /user/login [post]
+func login(c *gin.Context) {
+	var requestBody CredentialsRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil {
+		returnError(c, err)
+		return
+	}
+
+	token, userID, err := twitterService.Login(requestBody.Handle, requestBody.Password)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, LoginResponseBody{Token: token, UserID: userID})
+}
+
+type ChangePasswordRequestBody struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+// @Summary Change Password
+// @Description Changes the password of userID, ending its other sessions
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token of userID"
+// @Param userID path string true "id or @handle of userID"
+// @Param body body ChangePasswordRequestBody true "passwords"
+// @Produce text/plain
+// @Success 204
+// @Router /user/{userID}/password [put]
+func changePassword(c *gin.Context) {
+	userID, ok := userIDParam(c, "userID")
+	if !ok {
+		return
+	}
+
+	if !checkActingUser(c, userID) {
+		return
+	}
+
+	var requestBody ChangePasswordRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil {
+		returnError(c, err)
+		return
+	}
+
+	err := twitterService.ChangePassword(userID, c.GetString(sessionIDContextKey), requestBody.CurrentPassword, requestBody.NewPassword)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
+
+type PasswordResetRequestBody struct {
+	Handle string `json:"handle"`
+}
+
+// @Summary Request Password Reset
+// @Description Issues a single use password reset token for handle. Tokens are only delivered through the server log when LOG_PASSWORD_RESETS is set, for development.
+// @Tags Twitter
+// @Param body body PasswordResetRequestBody true "handle"
+// @Produce text/plain
+// @Success 202
+// @Router /user/password/reset [post]
+func requestPasswordReset(c *gin.Context) {
+	var requestBody PasswordResetRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil {
+		returnError(c, err)
+		return
+	}
+
+	token, err := twitterService.RequestPasswordReset(requestBody.Handle)
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		//don't reveal which handles exist
+		c.String(http.StatusAccepted, "")
+		return
+	} else if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	//there is no mail service, tokens are only delivered locally on development
+	if appConfig.Server.LogPasswordResets {
+		log.Printf("password reset token for %s: %s", requestBody.Handle, token)
+	} else {
+		log.Printf("password reset requested for %s", requestBody.Handle)
+	}
+
+	c.String(http.StatusAccepted, "")
+}
+
+type ResetPasswordRequestBody struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"newPassword"`
+}
+
+// @Summary Reset Password
+// @Description Sets a new password using a password reset token, ending every session of the user
+// @Tags Twitter
+// @Param body body ResetPasswordRequestBody true "token and new password"
+// @Produce text/plain
+// @Success 204
+// @Router /user/password/reset/confirm [post]
+func resetPassword(c *gin.Context) {
+	var requestBody ResetPasswordRequestBody
+
+	if err := c.BindJSON(&requestBody); err != nil {
+		returnError(c, err)
+		return
+	}
+
+	err := twitterService.ResetPassword(requestBody.Token, requestBody.NewPassword)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
+
+// authenticate validates the bearer token of the request and sets the acting user in the context
+func authenticate(c *gin.Context) {
+	token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !found || token == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": service.ErrInvalidSession.Error()})
+		return
+	}
+
+	session, err := twitterService.Authenticate(token)
+	if errors.Is(err, service.ErrInvalidSession) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		returnError(c, err)
+		c.Abort()
+		return
+	}
+
+	c.Set(userIDContextKey, session.UserID)
+	c.Set(sessionIDContextKey, session.ID)
+	c.Next()
+}
+
+// checkActingUser checks that the authenticated user is userID, responding with forbidden if not
+func checkActingUser(c *gin.Context, userID uint) bool {
+	if c.GetUint(userIDContextKey) != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": ErrPermission})
+		return false
+	}
+
+	return true
+}
+
+// @Summary Logout
+// @Description Ends the session of the bearer token
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token"
+// @Produce text/plain
+// @Success 204
+// @Router /user/logout [post]
+func logout(c *gin.Context) {
+	err := twitterService.RevokeSession(c.GetUint(userIDContextKey), c.GetString(sessionIDContextKey))
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
+
+type SessionResponseBody struct {
+	models.Session
+	Current bool `json:"current"`
+}
+
+// @Summary Sessions
+// @Description Lists the active sessions of userID
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token of userID"
+// @Param userID path string true "id or @handle of userID"
+// @Produce application/json
+// @Success 200 {array} SessionResponseBody
+// @Router /user/{userID}/sessions [get]
+func sessions(c *gin.Context) {
+	userID, ok := userIDParam(c, "userID")
+	if !ok {
+		return
+	}
+
+	if !checkActingUser(c, userID) {
+		return
+	}
+
+	sessions, err := twitterService.GetSessions(userID)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	response := make([]SessionResponseBody, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, SessionResponseBody{
+			Session: session,
+			Current: session.ID == c.GetString(sessionIDContextKey),
+		})
+	}
+
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+// @Summary Revoke Session
+// @Description Ends one of the sessions of userID
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token of userID"
+// @Param userID path string true "id or @handle of userID"
+// @Param sessionID path string true "sessionID"
+// @Produce text/plain
+// @Success 204
+// @Router /user/{userID}/sessions/{sessionID} [delete]
+func revokeSession(c *gin.Context) {
+	userID, ok := userIDParam(c, "userID")
+	if !ok {
+		return
+	}
+
+	if !checkActingUser(c, userID) {
+		return
+	}
+
+	err := twitterService.RevokeSession(userID, c.Param("sessionID"))
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
+
+// @Summary Revoke Sessions
+// @Description Ends every session of userID, including the current one
+// @Tags Twitter
+// @Param Authorization header string true "Bearer token of userID"
+// @Param userID path string true "id or @handle of userID"
+// @Produce text/plain
+// @Success 204
+// @Router /user/{userID}/sessions [delete]
+func revokeSessions(c *gin.Context) {
+	userID, ok := userIDParam(c, "userID")
+	if !ok {
+		return
+	}
+
+	if !checkActingUser(c, userID) {
+		return
+	}
+
+	err := twitterService.RevokeSessions(userID)
+	if err != nil {
+		returnError(c, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
+
+// authenticateAdmin checks that the request sends the configured admin token
+func authenticateAdmin(c *gin.Context) {
+	token := c.GetHeader(adminTokenHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(appConfig.Server.AdminToken)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin token"})
+		return
+	}
+
+	c.Next()
+}
+
+// @Summary Effective Config
+// @Description Returns the config the server is running with, with its secrets redacted. Only enabled if an admin token is configured.
+// @Tags Admin
+// @Param X-Admin-Token header string true "admin token"
+// @Produce application/json
+// @Success 200 {object} config.Config
+// @Router /admin/config [get]
+func effectiveConfig(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, appConfig.Redacted())
 }
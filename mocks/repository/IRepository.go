@@ -7,6 +7,7 @@ import (
 	mock "github.com/stretchr/testify/mock"
 
 	uuid "github.com/google/uuid"
+	time "time"
 )
 
 // IRepository is an autogenerated mock type for the IRepository type
@@ -14,6 +15,34 @@ type IRepository struct {
 	mock.Mock
 }
 
+// AddFailedLogin provides a mock function with given fields: userID
+func (_m *IRepository) AddFailedLogin(userID uint) (int64, error) {
+	ret := _m.Called(userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddFailedLogin")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint) (int64, error)); ok {
+		return rf(userID)
+	}
+	if rf, ok := ret.Get(0).(func(uint) int64); ok {
+		r0 = rf(userID)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(uint) error); ok {
+		r1 = rf(userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // AddFollower provides a mock function with given fields: userID, newFollowerID
 func (_m *IRepository) AddFollower(userID uint, newFollowerID uint) error {
 	ret := _m.Called(userID, newFollowerID)
@@ -32,6 +61,80 @@ func (_m *IRepository) AddFollower(userID uint, newFollowerID uint) error {
 	return r0
 }
 
+// AddLike provides a mock function with given fields: userID, tweetID
+func (_m *IRepository) AddLike(userID uint, tweetID uuid.UUID) (bool, error) {
+	ret := _m.Called(userID, tweetID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddLike")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uuid.UUID) (bool, error)); ok {
+		return rf(userID, tweetID)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uuid.UUID) bool); ok {
+		r0 = rf(userID, tweetID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uuid.UUID) error); ok {
+		r1 = rf(userID, tweetID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AddNotification provides a mock function with given fields: notification
+func (_m *IRepository) AddNotification(notification models.Notification) error {
+	ret := _m.Called(notification)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddNotification")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(models.Notification) error); ok {
+		r0 = rf(notification)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AddRetweet provides a mock function with given fields: userID, tweetID
+func (_m *IRepository) AddRetweet(userID uint, tweetID uuid.UUID) (bool, error) {
+	ret := _m.Called(userID, tweetID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddRetweet")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uuid.UUID) (bool, error)); ok {
+		return rf(userID, tweetID)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uuid.UUID) bool); ok {
+		r0 = rf(userID, tweetID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uuid.UUID) error); ok {
+		r1 = rf(userID, tweetID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // AddTweetToTimeline provides a mock function with given fields: tweetID, userID
 func (_m *IRepository) AddTweetToTimeline(tweetID uuid.UUID, userID uint) error {
 	ret := _m.Called(tweetID, userID)
@@ -50,23 +153,134 @@ func (_m *IRepository) AddTweetToTimeline(tweetID uuid.UUID, userID uint) error
 	return r0
 }
 
-// CheckUserLog provides a mock function with given fields: userID
-func (_m *IRepository) CheckUserLog(userID uint) (bool, error) {
+// AddTweetToTimelines provides a mock function with given fields: tweetID, userIDs
+func (_m *IRepository) AddTweetToTimelines(tweetID uuid.UUID, userIDs []uint) error {
+	ret := _m.Called(tweetID, userIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddTweetToTimelines")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID, []uint) error); ok {
+		r0 = rf(tweetID, userIDs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ConsumePasswordReset provides a mock function with given fields: tokenID
+func (_m *IRepository) ConsumePasswordReset(tokenID string) (uint, bool, error) {
+	ret := _m.Called(tokenID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConsumePasswordReset")
+	}
+
+	var r0 uint
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(string) (uint, bool, error)); ok {
+		return rf(tokenID)
+	}
+	if rf, ok := ret.Get(0).(func(string) uint); ok {
+		r0 = rf(tokenID)
+	} else {
+		r0 = ret.Get(0).(uint)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) bool); ok {
+		r1 = rf(tokenID)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(string) error); ok {
+		r2 = rf(tokenID)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// CountFollowers provides a mock function with given fields: userID
+func (_m *IRepository) CountFollowers(userID uint) (int64, error) {
 	ret := _m.Called(userID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for CheckUserLog")
+		panic("no return value specified for CountFollowers")
 	}
 
-	var r0 bool
+	var r0 int64
 	var r1 error
-	if rf, ok := ret.Get(0).(func(uint) (bool, error)); ok {
+	if rf, ok := ret.Get(0).(func(uint) (int64, error)); ok {
 		return rf(userID)
 	}
-	if rf, ok := ret.Get(0).(func(uint) bool); ok {
+	if rf, ok := ret.Get(0).(func(uint) int64); ok {
 		r0 = rf(userID)
 	} else {
-		r0 = ret.Get(0).(bool)
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(uint) error); ok {
+		r1 = rf(userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountFollowersOf provides a mock function with given fields: userIDs
+func (_m *IRepository) CountFollowersOf(userIDs []uint) ([]int64, error) {
+	ret := _m.Called(userIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountFollowersOf")
+	}
+
+	var r0 []int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]uint) ([]int64, error)); ok {
+		return rf(userIDs)
+	}
+	if rf, ok := ret.Get(0).(func([]uint) []int64); ok {
+		r0 = rf(userIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]int64)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]uint) error); ok {
+		r1 = rf(userIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountFollowing provides a mock function with given fields: userID
+func (_m *IRepository) CountFollowing(userID uint) (int64, error) {
+	ret := _m.Called(userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountFollowing")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint) (int64, error)); ok {
+		return rf(userID)
+	}
+	if rf, ok := ret.Get(0).(func(uint) int64); ok {
+		r0 = rf(userID)
+	} else {
+		r0 = ret.Get(0).(int64)
 	}
 
 	if rf, ok := ret.Get(1).(func(uint) error); ok {
@@ -78,6 +292,42 @@ func (_m *IRepository) CheckUserLog(userID uint) (bool, error) {
 	return r0, r1
 }
 
+// CreatePasswordReset provides a mock function with given fields: tokenID, userID
+func (_m *IRepository) CreatePasswordReset(tokenID string, userID uint) error {
+	ret := _m.Called(tokenID, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreatePasswordReset")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, uint) error); ok {
+		r0 = rf(tokenID, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateSession provides a mock function with given fields: session
+func (_m *IRepository) CreateSession(session models.Session) error {
+	ret := _m.Called(session)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateSession")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(models.Session) error); ok {
+		r0 = rf(session)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // CreateTweet provides a mock function with given fields: tweet
 func (_m *IRepository) CreateTweet(tweet models.Tweet) (uuid.UUID, error) {
 	ret := _m.Called(tweet)
@@ -108,29 +358,27 @@ func (_m *IRepository) CreateTweet(tweet models.Tweet) (uuid.UUID, error) {
 	return r0, r1
 }
 
-// GetFollowers provides a mock function with given fields: userID
-func (_m *IRepository) GetFollowers(userID uint) ([]uint, error) {
-	ret := _m.Called(userID)
+// CreateUser provides a mock function with given fields: user
+func (_m *IRepository) CreateUser(user models.User) (uint, error) {
+	ret := _m.Called(user)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetFollowers")
+		panic("no return value specified for CreateUser")
 	}
 
-	var r0 []uint
+	var r0 uint
 	var r1 error
-	if rf, ok := ret.Get(0).(func(uint) ([]uint, error)); ok {
-		return rf(userID)
+	if rf, ok := ret.Get(0).(func(models.User) (uint, error)); ok {
+		return rf(user)
 	}
-	if rf, ok := ret.Get(0).(func(uint) []uint); ok {
-		r0 = rf(userID)
+	if rf, ok := ret.Get(0).(func(models.User) uint); ok {
+		r0 = rf(user)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]uint)
-		}
+		r0 = ret.Get(0).(uint)
 	}
 
-	if rf, ok := ret.Get(1).(func(uint) error); ok {
-		r1 = rf(userID)
+	if rf, ok := ret.Get(1).(func(models.User) error); ok {
+		r1 = rf(user)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -138,29 +386,103 @@ func (_m *IRepository) GetFollowers(userID uint) ([]uint, error) {
 	return r0, r1
 }
 
-// GetTimeLine provides a mock function with given fields: userID
-func (_m *IRepository) GetTimeLine(userID uint) ([]uuid.UUID, error) {
-	ret := _m.Called(userID)
+// DeleteSessions provides a mock function with given fields: userID, sessionIDs
+func (_m *IRepository) DeleteSessions(userID uint, sessionIDs []string) error {
+	ret := _m.Called(userID, sessionIDs)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetTimeLine")
+		panic("no return value specified for DeleteSessions")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uint, []string) error); ok {
+		r0 = rf(userID, sessionIDs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteTweet provides a mock function with given fields: tweet, timelineUserIDs
+func (_m *IRepository) DeleteTweet(tweet models.Tweet, timelineUserIDs []uint) (bool, error) {
+	ret := _m.Called(tweet, timelineUserIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteTweet")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(models.Tweet, []uint) (bool, error)); ok {
+		return rf(tweet, timelineUserIDs)
+	}
+	if rf, ok := ret.Get(0).(func(models.Tweet, []uint) bool); ok {
+		r0 = rf(tweet, timelineUserIDs)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(models.Tweet, []uint) error); ok {
+		r1 = rf(tweet, timelineUserIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// EditTweet provides a mock function with given fields: edited
+func (_m *IRepository) EditTweet(edited models.Tweet) (bool, error) {
+	ret := _m.Called(edited)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EditTweet")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(models.Tweet) (bool, error)); ok {
+		return rf(edited)
+	}
+	if rf, ok := ret.Get(0).(func(models.Tweet) bool); ok {
+		r0 = rf(edited)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(models.Tweet) error); ok {
+		r1 = rf(edited)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetConversation provides a mock function with given fields: conversationID
+func (_m *IRepository) GetConversation(conversationID uuid.UUID) ([]uuid.UUID, error) {
+	ret := _m.Called(conversationID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetConversation")
 	}
 
 	var r0 []uuid.UUID
 	var r1 error
-	if rf, ok := ret.Get(0).(func(uint) ([]uuid.UUID, error)); ok {
-		return rf(userID)
+	if rf, ok := ret.Get(0).(func(uuid.UUID) ([]uuid.UUID, error)); ok {
+		return rf(conversationID)
 	}
-	if rf, ok := ret.Get(0).(func(uint) []uuid.UUID); ok {
-		r0 = rf(userID)
+	if rf, ok := ret.Get(0).(func(uuid.UUID) []uuid.UUID); ok {
+		r0 = rf(conversationID)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]uuid.UUID)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(uint) error); ok {
-		r1 = rf(userID)
+	if rf, ok := ret.Get(1).(func(uuid.UUID) error); ok {
+		r1 = rf(conversationID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -168,29 +490,27 @@ func (_m *IRepository) GetTimeLine(userID uint) ([]uuid.UUID, error) {
 	return r0, r1
 }
 
-// GetTweets provides a mock function with given fields: ids
-func (_m *IRepository) GetTweets(ids []uuid.UUID) ([]models.Tweet, error) {
-	ret := _m.Called(ids)
+// GetFailedLogins provides a mock function with given fields: userID
+func (_m *IRepository) GetFailedLogins(userID uint) (int64, error) {
+	ret := _m.Called(userID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetTweets")
+		panic("no return value specified for GetFailedLogins")
 	}
 
-	var r0 []models.Tweet
+	var r0 int64
 	var r1 error
-	if rf, ok := ret.Get(0).(func([]uuid.UUID) ([]models.Tweet, error)); ok {
-		return rf(ids)
+	if rf, ok := ret.Get(0).(func(uint) (int64, error)); ok {
+		return rf(userID)
 	}
-	if rf, ok := ret.Get(0).(func([]uuid.UUID) []models.Tweet); ok {
-		r0 = rf(ids)
+	if rf, ok := ret.Get(0).(func(uint) int64); ok {
+		r0 = rf(userID)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]models.Tweet)
-		}
+		r0 = ret.Get(0).(int64)
 	}
 
-	if rf, ok := ret.Get(1).(func([]uuid.UUID) error); ok {
-		r1 = rf(ids)
+	if rf, ok := ret.Get(1).(func(uint) error); ok {
+		r1 = rf(userID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -198,15 +518,796 @@ func (_m *IRepository) GetTweets(ids []uuid.UUID) ([]models.Tweet, error) {
 	return r0, r1
 }
 
-// Login provides a mock function with given fields: userID
-func (_m *IRepository) Login(userID uint) error {
+// GetFollowers provides a mock function with given fields: userID
+func (_m *IRepository) GetFollowers(userID uint) ([]uint, error) {
 	ret := _m.Called(userID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Login")
+		panic("no return value specified for GetFollowers")
 	}
 
-	var r0 error
+	var r0 []uint
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint) ([]uint, error)); ok {
+		return rf(userID)
+	}
+	if rf, ok := ret.Get(0).(func(uint) []uint); ok {
+		r0 = rf(userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]uint)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint) error); ok {
+		r1 = rf(userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetFollowing provides a mock function with given fields: userID
+func (_m *IRepository) GetFollowing(userID uint) ([]uint, error) {
+	ret := _m.Called(userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFollowing")
+	}
+
+	var r0 []uint
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint) ([]uint, error)); ok {
+		return rf(userID)
+	}
+	if rf, ok := ret.Get(0).(func(uint) []uint); ok {
+		r0 = rf(userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]uint)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint) error); ok {
+		r1 = rf(userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetHandleRedirect provides a mock function with given fields: handle
+func (_m *IRepository) GetHandleRedirect(handle string) (uint, bool, error) {
+	ret := _m.Called(handle)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetHandleRedirect")
+	}
+
+	var r0 uint
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(string) (uint, bool, error)); ok {
+		return rf(handle)
+	}
+	if rf, ok := ret.Get(0).(func(string) uint); ok {
+		r0 = rf(handle)
+	} else {
+		r0 = ret.Get(0).(uint)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) bool); ok {
+		r1 = rf(handle)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(string) error); ok {
+		r2 = rf(handle)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetHashtagTweets provides a mock function with given fields: hashtag
+func (_m *IRepository) GetHashtagTweets(hashtag string) ([]uuid.UUID, error) {
+	ret := _m.Called(hashtag)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetHashtagTweets")
+	}
+
+	var r0 []uuid.UUID
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]uuid.UUID, error)); ok {
+		return rf(hashtag)
+	}
+	if rf, ok := ret.Get(0).(func(string) []uuid.UUID); ok {
+		r0 = rf(hashtag)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]uuid.UUID)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(hashtag)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetHashtagUses provides a mock function with given fields: since
+func (_m *IRepository) GetHashtagUses(since time.Time) (map[string][]time.Time, error) {
+	ret := _m.Called(since)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetHashtagUses")
+	}
+
+	var r0 map[string][]time.Time
+	var r1 error
+	if rf, ok := ret.Get(0).(func(time.Time) (map[string][]time.Time, error)); ok {
+		return rf(since)
+	}
+	if rf, ok := ret.Get(0).(func(time.Time) map[string][]time.Time); ok {
+		r0 = rf(since)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string][]time.Time)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(time.Time) error); ok {
+		r1 = rf(since)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLikedBy provides a mock function with given fields: userID, tweetIDs
+func (_m *IRepository) GetLikedBy(userID uint, tweetIDs []uuid.UUID) ([]bool, error) {
+	ret := _m.Called(userID, tweetIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLikedBy")
+	}
+
+	var r0 []bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, []uuid.UUID) ([]bool, error)); ok {
+		return rf(userID, tweetIDs)
+	}
+	if rf, ok := ret.Get(0).(func(uint, []uuid.UUID) []bool); ok {
+		r0 = rf(userID, tweetIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]bool)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, []uuid.UUID) error); ok {
+		r1 = rf(userID, tweetIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetNotifications provides a mock function with given fields: userID
+func (_m *IRepository) GetNotifications(userID uint) ([]models.Notification, error) {
+	ret := _m.Called(userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetNotifications")
+	}
+
+	var r0 []models.Notification
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint) ([]models.Notification, error)); ok {
+		return rf(userID)
+	}
+	if rf, ok := ret.Get(0).(func(uint) []models.Notification); ok {
+		r0 = rf(userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Notification)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint) error); ok {
+		r1 = rf(userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetNotificationsReadAt provides a mock function with given fields: userID
+func (_m *IRepository) GetNotificationsReadAt(userID uint) (time.Time, error) {
+	ret := _m.Called(userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetNotificationsReadAt")
+	}
+
+	var r0 time.Time
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint) (time.Time, error)); ok {
+		return rf(userID)
+	}
+	if rf, ok := ret.Get(0).(func(uint) time.Time); ok {
+		r0 = rf(userID)
+	} else {
+		r0 = ret.Get(0).(time.Time)
+	}
+
+	if rf, ok := ret.Get(1).(func(uint) error); ok {
+		r1 = rf(userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRetweeters provides a mock function with given fields: tweetIDs
+func (_m *IRepository) GetRetweeters(tweetIDs []uuid.UUID) ([][]uint, error) {
+	ret := _m.Called(tweetIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRetweeters")
+	}
+
+	var r0 [][]uint
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]uuid.UUID) ([][]uint, error)); ok {
+		return rf(tweetIDs)
+	}
+	if rf, ok := ret.Get(0).(func([]uuid.UUID) [][]uint); ok {
+		r0 = rf(tweetIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([][]uint)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]uuid.UUID) error); ok {
+		r1 = rf(tweetIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSession provides a mock function with given fields: sessionID
+func (_m *IRepository) GetSession(sessionID string) (models.Session, bool, error) {
+	ret := _m.Called(sessionID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSession")
+	}
+
+	var r0 models.Session
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(string) (models.Session, bool, error)); ok {
+		return rf(sessionID)
+	}
+	if rf, ok := ret.Get(0).(func(string) models.Session); ok {
+		r0 = rf(sessionID)
+	} else {
+		r0 = ret.Get(0).(models.Session)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) bool); ok {
+		r1 = rf(sessionID)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(string) error); ok {
+		r2 = rf(sessionID)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetSessions provides a mock function with given fields: userID
+func (_m *IRepository) GetSessions(userID uint) ([]models.Session, error) {
+	ret := _m.Called(userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSessions")
+	}
+
+	var r0 []models.Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint) ([]models.Session, error)); ok {
+		return rf(userID)
+	}
+	if rf, ok := ret.Get(0).(func(uint) []models.Session); ok {
+		r0 = rf(userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Session)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint) error); ok {
+		r1 = rf(userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTimeLine provides a mock function with given fields: userID
+func (_m *IRepository) GetTimeLine(userID uint) ([]uuid.UUID, error) {
+	ret := _m.Called(userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTimeLine")
+	}
+
+	var r0 []uuid.UUID
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint) ([]uuid.UUID, error)); ok {
+		return rf(userID)
+	}
+	if rf, ok := ret.Get(0).(func(uint) []uuid.UUID); ok {
+		r0 = rf(userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]uuid.UUID)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint) error); ok {
+		r1 = rf(userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTweetCounts provides a mock function with given fields: tweetIDs
+func (_m *IRepository) GetTweetCounts(tweetIDs []uuid.UUID) ([]models.TweetCounts, error) {
+	ret := _m.Called(tweetIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTweetCounts")
+	}
+
+	var r0 []models.TweetCounts
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]uuid.UUID) ([]models.TweetCounts, error)); ok {
+		return rf(tweetIDs)
+	}
+	if rf, ok := ret.Get(0).(func([]uuid.UUID) []models.TweetCounts); ok {
+		r0 = rf(tweetIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.TweetCounts)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]uuid.UUID) error); ok {
+		r1 = rf(tweetIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTweetHistory provides a mock function with given fields: tweetID
+func (_m *IRepository) GetTweetHistory(tweetID uuid.UUID) ([]models.TweetRevision, error) {
+	ret := _m.Called(tweetID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTweetHistory")
+	}
+
+	var r0 []models.TweetRevision
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID) ([]models.TweetRevision, error)); ok {
+		return rf(tweetID)
+	}
+	if rf, ok := ret.Get(0).(func(uuid.UUID) []models.TweetRevision); ok {
+		r0 = rf(tweetID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.TweetRevision)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uuid.UUID) error); ok {
+		r1 = rf(tweetID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTweets provides a mock function with given fields: ids
+func (_m *IRepository) GetTweets(ids []uuid.UUID) ([]models.Tweet, []uuid.UUID, error) {
+	ret := _m.Called(ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTweets")
+	}
+
+	var r0 []models.Tweet
+	var r1 []uuid.UUID
+	var r2 error
+	if rf, ok := ret.Get(0).(func([]uuid.UUID) ([]models.Tweet, []uuid.UUID, error)); ok {
+		return rf(ids)
+	}
+	if rf, ok := ret.Get(0).(func([]uuid.UUID) []models.Tweet); ok {
+		r0 = rf(ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Tweet)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]uuid.UUID) []uuid.UUID); ok {
+		r1 = rf(ids)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]uuid.UUID)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func([]uuid.UUID) error); ok {
+		r2 = rf(ids)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetUser provides a mock function with given fields: userID
+func (_m *IRepository) GetUser(userID uint) (models.User, bool, error) {
+	ret := _m.Called(userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUser")
+	}
+
+	var r0 models.User
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(uint) (models.User, bool, error)); ok {
+		return rf(userID)
+	}
+	if rf, ok := ret.Get(0).(func(uint) models.User); ok {
+		r0 = rf(userID)
+	} else {
+		r0 = ret.Get(0).(models.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(uint) bool); ok {
+		r1 = rf(userID)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(uint) error); ok {
+		r2 = rf(userID)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetUserByHandle provides a mock function with given fields: handle
+func (_m *IRepository) GetUserByHandle(handle string) (models.User, bool, error) {
+	ret := _m.Called(handle)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserByHandle")
+	}
+
+	var r0 models.User
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(string) (models.User, bool, error)); ok {
+		return rf(handle)
+	}
+	if rf, ok := ret.Get(0).(func(string) models.User); ok {
+		r0 = rf(handle)
+	} else {
+		r0 = ret.Get(0).(models.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) bool); ok {
+		r1 = rf(handle)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(string) error); ok {
+		r2 = rf(handle)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetUserLikes provides a mock function with given fields: userID
+func (_m *IRepository) GetUserLikes(userID uint) ([]uuid.UUID, error) {
+	ret := _m.Called(userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserLikes")
+	}
+
+	var r0 []uuid.UUID
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint) ([]uuid.UUID, error)); ok {
+		return rf(userID)
+	}
+	if rf, ok := ret.Get(0).(func(uint) []uuid.UUID); ok {
+		r0 = rf(userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]uuid.UUID)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint) error); ok {
+		r1 = rf(userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserTweets provides a mock function with given fields: userID, count
+func (_m *IRepository) GetUserTweets(userID uint, count int64) ([]uuid.UUID, error) {
+	ret := _m.Called(userID, count)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserTweets")
+	}
+
+	var r0 []uuid.UUID
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, int64) ([]uuid.UUID, error)); ok {
+		return rf(userID, count)
+	}
+	if rf, ok := ret.Get(0).(func(uint, int64) []uuid.UUID); ok {
+		r0 = rf(userID, count)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]uuid.UUID)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, int64) error); ok {
+		r1 = rf(userID, count)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RefreshSession provides a mock function with given fields: session
+func (_m *IRepository) RefreshSession(session models.Session) error {
+	ret := _m.Called(session)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RefreshSession")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(models.Session) error); ok {
+		r0 = rf(session)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RemoveFollower provides a mock function with given fields: userID, followerID
+func (_m *IRepository) RemoveFollower(userID uint, followerID uint) (bool, error) {
+	ret := _m.Called(userID, followerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveFollower")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint) (bool, error)); ok {
+		return rf(userID, followerID)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint) bool); ok {
+		r0 = rf(userID, followerID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint) error); ok {
+		r1 = rf(userID, followerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RemoveFromTimeline provides a mock function with given fields: userID, tweetIDs
+func (_m *IRepository) RemoveFromTimeline(userID uint, tweetIDs []uuid.UUID) error {
+	ret := _m.Called(userID, tweetIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveFromTimeline")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uint, []uuid.UUID) error); ok {
+		r0 = rf(userID, tweetIDs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RemoveLike provides a mock function with given fields: userID, tweetID
+func (_m *IRepository) RemoveLike(userID uint, tweetID uuid.UUID) (bool, error) {
+	ret := _m.Called(userID, tweetID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveLike")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uuid.UUID) (bool, error)); ok {
+		return rf(userID, tweetID)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uuid.UUID) bool); ok {
+		r0 = rf(userID, tweetID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uuid.UUID) error); ok {
+		r1 = rf(userID, tweetID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RemoveRetweet provides a mock function with given fields: userID, tweetID
+func (_m *IRepository) RemoveRetweet(userID uint, tweetID uuid.UUID) (bool, error) {
+	ret := _m.Called(userID, tweetID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveRetweet")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uuid.UUID) (bool, error)); ok {
+		return rf(userID, tweetID)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uuid.UUID) bool); ok {
+		r0 = rf(userID, tweetID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uuid.UUID) error); ok {
+		r1 = rf(userID, tweetID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RemoveTweetFromTimelines provides a mock function with given fields: tweetID, userIDs
+func (_m *IRepository) RemoveTweetFromTimelines(tweetID uuid.UUID, userIDs []uint) error {
+	ret := _m.Called(tweetID, userIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveTweetFromTimelines")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID, []uint) error); ok {
+		r0 = rf(tweetID, userIDs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RenameHandle provides a mock function with given fields: userID, handle, redirectFor
+func (_m *IRepository) RenameHandle(userID uint, handle string, redirectFor time.Duration) error {
+	ret := _m.Called(userID, handle, redirectFor)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RenameHandle")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uint, string, time.Duration) error); ok {
+		r0 = rf(userID, handle, redirectFor)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReplaceTimeline provides a mock function with given fields: userID, current, tweetIDs
+func (_m *IRepository) ReplaceTimeline(userID uint, current []uuid.UUID, tweetIDs []uuid.UUID) (bool, error) {
+	ret := _m.Called(userID, current, tweetIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReplaceTimeline")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, []uuid.UUID, []uuid.UUID) (bool, error)); ok {
+		return rf(userID, current, tweetIDs)
+	}
+	if rf, ok := ret.Get(0).(func(uint, []uuid.UUID, []uuid.UUID) bool); ok {
+		r0 = rf(userID, current, tweetIDs)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, []uuid.UUID, []uuid.UUID) error); ok {
+		r1 = rf(userID, current, tweetIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ResetFailedLogins provides a mock function with given fields: userID
+func (_m *IRepository) ResetFailedLogins(userID uint) error {
+	ret := _m.Called(userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResetFailedLogins")
+	}
+
+	var r0 error
 	if rf, ok := ret.Get(0).(func(uint) error); ok {
 		r0 = rf(userID)
 	} else {
@@ -216,6 +1317,164 @@ func (_m *IRepository) Login(userID uint) error {
 	return r0
 }
 
+// ScanFollowers provides a mock function with given fields: userID, cursor, count
+func (_m *IRepository) ScanFollowers(userID uint, cursor uint64, count int64) ([]uint, uint64, error) {
+	ret := _m.Called(userID, cursor, count)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ScanFollowers")
+	}
+
+	var r0 []uint
+	var r1 uint64
+	var r2 error
+	if rf, ok := ret.Get(0).(func(uint, uint64, int64) ([]uint, uint64, error)); ok {
+		return rf(userID, cursor, count)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint64, int64) []uint); ok {
+		r0 = rf(userID, cursor, count)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]uint)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint64, int64) uint64); ok {
+		r1 = rf(userID, cursor, count)
+	} else {
+		r1 = ret.Get(1).(uint64)
+	}
+
+	if rf, ok := ret.Get(2).(func(uint, uint64, int64) error); ok {
+		r2 = rf(userID, cursor, count)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ScanFollowing provides a mock function with given fields: userID, cursor, count
+func (_m *IRepository) ScanFollowing(userID uint, cursor uint64, count int64) ([]uint, uint64, error) {
+	ret := _m.Called(userID, cursor, count)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ScanFollowing")
+	}
+
+	var r0 []uint
+	var r1 uint64
+	var r2 error
+	if rf, ok := ret.Get(0).(func(uint, uint64, int64) ([]uint, uint64, error)); ok {
+		return rf(userID, cursor, count)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint64, int64) []uint); ok {
+		r0 = rf(userID, cursor, count)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]uint)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint64, int64) uint64); ok {
+		r1 = rf(userID, cursor, count)
+	} else {
+		r1 = ret.Get(1).(uint64)
+	}
+
+	if rf, ok := ret.Get(2).(func(uint, uint64, int64) error); ok {
+		r2 = rf(userID, cursor, count)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// SearchTweets provides a mock function with given fields: terms, userID, since
+func (_m *IRepository) SearchTweets(terms []string, userID uint, since time.Time) ([]uuid.UUID, error) {
+	ret := _m.Called(terms, userID, since)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SearchTweets")
+	}
+
+	var r0 []uuid.UUID
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]string, uint, time.Time) ([]uuid.UUID, error)); ok {
+		return rf(terms, userID, since)
+	}
+	if rf, ok := ret.Get(0).(func([]string, uint, time.Time) []uuid.UUID); ok {
+		r0 = rf(terms, userID, since)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]uuid.UUID)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]string, uint, time.Time) error); ok {
+		r1 = rf(terms, userID, since)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetNotificationsReadAt provides a mock function with given fields: userID, readAt
+func (_m *IRepository) SetNotificationsReadAt(userID uint, readAt time.Time) error {
+	ret := _m.Called(userID, readAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetNotificationsReadAt")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uint, time.Time) error); ok {
+		r0 = rf(userID, readAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdatePassword provides a mock function with given fields: userID, passwordHash
+func (_m *IRepository) UpdatePassword(userID uint, passwordHash []byte) error {
+	ret := _m.Called(userID, passwordHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdatePassword")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uint, []byte) error); ok {
+		r0 = rf(userID, passwordHash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateProfile provides a mock function with given fields: userID, profile
+func (_m *IRepository) UpdateProfile(userID uint, profile models.Profile) error {
+	ret := _m.Called(userID, profile)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateProfile")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uint, models.Profile) error); ok {
+		r0 = rf(userID, profile)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewIRepository creates a new instance of IRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewIRepository(t interface {